@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"archive/zip"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+const (
+	// maxRestoreSize caps the size of an uploaded backup archive
+	maxRestoreSize = 2 << 30
+	// restoreMemory is how much of an uploaded archive is kept in memory;
+	// the rest goes to a temporary file
+	restoreMemory = 32 << 20
+)
+
+// adminToken guards the admin endpoints
+var adminToken string
+
+// SetAdminToken sets the bearer token the admin endpoints require. With no
+// token they are disabled.
+func SetAdminToken(token string) {
+	adminToken = token
+}
+
+// authorizeAdmin checks the bearer token of an admin request, responding
+// when it is missing or wrong or when no token is configured
+func authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if adminToken == "" {
+		http.Error(w, "Admin endpoints are disabled; set BIOTREE_ADMIN_TOKEN to enable them", http.StatusForbidden)
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// BackupHandler streams an archive of the whole workspace
+func BackupHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	filename := fmt.Sprintf("biotree-backup-%s.zip", time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+	manifest, err := util.Backup(dataStore, w)
+	if err != nil {
+		// The response has started, all that is left is to log it
+		log.Println("Failed to back up workspace:", err)
+		return
+	}
+	log.Printf("Backed up %d objects and %d rule sets", len(manifest.Objects), len(manifest.Rules))
+}
+
+// RestoreHandler restores an uploaded backup archive ("archive"). The
+// "conflict" field says what happens to objects that exist with other
+// contents: skip (the default), overwrite or fail.
+func RestoreHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRestoreSize)
+	if err := r.ParseMultipartForm(restoreMemory); err != nil {
+		log.Println("Failed to parse restore form:", err)
+		util.RespondError(w, "Archive is too large or the form is invalid")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("archive")
+	if err != nil {
+		util.RespondError(w, "archive is required")
+		return
+	}
+	defer file.Close()
+
+	conflict := r.FormValue("conflict")
+	if conflict == "" {
+		conflict = util.ConflictSkip
+	}
+
+	zr, err := zip.NewReader(file, header.Size)
+	if err != nil {
+		util.RespondError(w, "Archive is not a zip file")
+		return
+	}
+
+	result, err := util.Restore(dataStore, zr, conflict)
+	if err != nil {
+		log.Println("Failed to restore archive:", err)
+		switch {
+		case errors.Is(err, util.ErrRestoreConflict):
+			responseWithCompression(w, r, map[string]interface{}{
+				"status":    "error",
+				"message":   "Archive conflicts with existing objects, nothing was restored",
+				"conflicts": result.Conflicts,
+			})
+		case errors.Is(err, util.ErrInvalidBackup):
+			util.RespondError(w, err.Error())
+		default:
+			util.RespondError(w, "Failed to restore archive")
+		}
+		return
+	}
+
+	// Charts may have been drawn from objects that were replaced
+	svgCache.Lock()
+	clear(svgCache.items)
+	svgCache.Unlock()
+
+	log.Printf("Restored %d objects from %s, %d unchanged, %d conflicts", result.Restored, header.Filename, result.Unchanged, len(result.Conflicts))
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status": "ok",
+		"result": result,
+	})
+}
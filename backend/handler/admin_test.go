@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+func TestAdminToken(t *testing.T) {
+	SetStore(store.NewMemory())
+	defer SetStore(store.NewLocal("."))
+	defer SetAdminToken("")
+
+	backup := func(authorization string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/backup", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		BackupHandler(rec, req)
+		return rec.Code
+	}
+
+	SetAdminToken("")
+	if code := backup("Bearer anything"); code != http.StatusForbidden {
+		t.Errorf("backup without a configured token = %d, want %d", code, http.StatusForbidden)
+	}
+
+	SetAdminToken("secret")
+	if code := backup(""); code != http.StatusUnauthorized {
+		t.Errorf("backup without a token = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := backup("Bearer wrong"); code != http.StatusUnauthorized {
+		t.Errorf("backup with a wrong token = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := backup("Bearer secret"); code != http.StatusOK {
+		t.Errorf("backup with the token = %d, want %d", code, http.StatusOK)
+	}
+}
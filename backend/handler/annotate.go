@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+// AnnotatedSourceHandler returns the original document of a dataset as sanitized
+// HTML with every stored data point highlighted and linked to its ID
+func AnnotatedSourceHandler(w http.ResponseWriter, r *http.Request) {
+	// Set CORS headers if needed
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	dataFile := r.URL.Query().Get("dataFile")
+	if dataFile == "" {
+		util.RespondError(w, "dataFile is required")
+		return
+	}
+
+	dataPoints, err := readDataPoints(dataFile)
+	if err != nil {
+		log.Println("Failed to read data file:", err)
+		util.RespondError(w, "Failed to read data file")
+		return
+	}
+
+	source, err := readSourceDocument(dataFile)
+	if err != nil {
+		log.Println("Failed to read source document:", err)
+		util.RespondError(w, "Source document not found for this data file")
+		return
+	}
+
+	annotation := util.Annotate(string(source), dataPoints)
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":    "ok",
+		"data_file": dataFile,
+		"html":      annotation.HTML,
+		"colors":    annotation.Colors,
+		"points":    annotation.Points,
+		"linked":    annotation.Linked,
+		"unlinked":  annotation.Unlinked,
+	})
+}
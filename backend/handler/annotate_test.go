@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+func TestAnnotatedSourceHandler(t *testing.T) {
+	st := store.NewMemory()
+	SetStore(st)
+	defer SetStore(store.NewLocal("."))
+
+	uploaded := uploadDocument(t, "survey.txt", "Rainfall was 5 mm <script>x</script> and 12 °C", nil)
+	if err := st.Put(store.Datasets, "orphan.json", []byte("[]")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		query      string
+		wantCode   int
+		wantError  string
+		wantLinked int
+	}{
+		{"stored dataset", http.MethodGet, "?dataFile=" + uploaded.DataFile, http.StatusOK, "", 2},
+		{"missing dataFile", http.MethodGet, "", http.StatusBadRequest, "dataFile is required", 0},
+		{"unknown dataset", http.MethodGet, "?dataFile=missing.json", http.StatusBadRequest, "Failed to read data file", 0},
+		{"no source document", http.MethodGet, "?dataFile=orphan.json", http.StatusBadRequest, "Source document not found", 0},
+		{"wrong method", http.MethodPost, "", http.StatusMethodNotAllowed, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			AnnotatedSourceHandler(rec, httptest.NewRequest(tt.method, "/api/annotated-source"+tt.query, nil))
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantError) {
+				t.Errorf("body = %s, want error %q", rec.Body, tt.wantError)
+			}
+			if tt.wantCode != http.StatusOK || tt.wantError != "" {
+				return
+			}
+
+			var annotated struct {
+				HTML   string                     `json:"html"`
+				Points map[string]json.RawMessage `json:"points"`
+				Linked int                        `json:"linked"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&annotated); err != nil {
+				t.Fatal(err)
+			}
+			if annotated.Linked != tt.wantLinked || len(annotated.Points) != tt.wantLinked {
+				t.Errorf("linked %d of %v, want %d", annotated.Linked, annotated.Points, tt.wantLinked)
+			}
+			for id := range annotated.Points {
+				if !strings.Contains(annotated.HTML, `data-point-id="`+id+`"`) {
+					t.Errorf("HTML has no highlight for %s: %s", id, annotated.HTML)
+				}
+			}
+			if strings.Contains(annotated.HTML, "<script>") {
+				t.Errorf("HTML = %s, document markup not escaped", annotated.HTML)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+// ComplianceHandler checks a stored dataset against the standards catalogue
+// and returns the exceedances per unit
+func ComplianceHandler(w http.ResponseWriter, r *http.Request) {
+	// Set CORS headers if needed
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	dataFile := r.URL.Query().Get("dataFile")
+	if dataFile == "" {
+		util.RespondError(w, "dataFile is required")
+		return
+	}
+	jurisdiction := r.URL.Query().Get("jurisdiction")
+
+	dataPoints, err := readDataPoints(dataFile)
+	if err != nil {
+		log.Println("Failed to read data file:", err)
+		util.RespondError(w, "Failed to read data file")
+		return
+	}
+
+	results := util.EvaluateCompliance(dataPoints, util.CurrentStandardsCatalogue(), jurisdiction)
+
+	exceedances := 0
+	for _, result := range results {
+		exceedances += len(result.Exceedances)
+	}
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":       "ok",
+		"data_file":    dataFile,
+		"jurisdiction": jurisdiction,
+		"units":        results,
+		"exceedances":  exceedances,
+	})
+}
+
+// StandardsHandler returns the standards catalogue in use
+func StandardsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":    "ok",
+		"standards": util.CurrentStandardsCatalogue().Standards,
+	})
+}
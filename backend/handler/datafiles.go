@@ -1,24 +1,45 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/Vinolia-E/BioTree/backend/store"
 	"github.com/Vinolia-E/BioTree/backend/util"
 )
 
-// FileInfo represents information about a data file
+const (
+	// defaultListLimit is the page size of a listing without a limit
+	defaultListLimit = 100
+	// maxListLimit caps the page size of a listing
+	maxListLimit = 1000
+)
+
+// FileInfo represents information about a data file. Size and Modified are
+// those of the data file; SourceSize is that of the uploaded document.
 type FileInfo struct {
-	Name     string    `json:"name"`
-	Size     int64     `json:"size"`
-	Modified time.Time `json:"modified"`
-	Units    []string  `json:"units"`
+	Name       string              `json:"name"`
+	Size       int64               `json:"size"`
+	SourceSize int64               `json:"source_size,omitempty"`
+	Modified   time.Time           `json:"modified"`
+	Units      []string            `json:"units"`
+	Categories []util.UnitGroup    `json:"categories"`
+	Quality    *util.QualityReport `json:"quality,omitempty"`
+	Metadata   *util.Metadata      `json:"metadata,omitempty"`
+	Projects   []string            `json:"projects,omitempty"`
 }
 
-// ListDataFilesHandler returns a list of all data files
+// ListDataFilesHandler returns a page of data files with their metadata, read
+// from the listing index. The query can filter by project, dataset, unit,
+// category, name and upload date range (from, to), sort by modified, size,
+// points or name in either order, and continue from the cursor of a previous page.
 func ListDataFilesHandler(w http.ResponseWriter, r *http.Request) {
 	// Set CORS headers if needed
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -36,48 +57,311 @@ func ListDataFilesHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	// Get all files in the data directory
-	files, err := os.ReadDir("data")
+	query, err := parseListQuery(r)
 	if err != nil {
-		log.Println("Failed to read data directory:", err)
-		util.RespondError(w, "Failed to read data directory")
+		util.RespondError(w, err.Error())
 		return
 	}
 
-	var fileInfos []FileInfo
+	projects, err := util.ListProjects(dataStore)
+	if err != nil {
+		log.Println("Failed to list projects:", err)
+		util.RespondError(w, "Failed to read projects")
+		return
+	}
+	membership := util.ProjectsOf(projects)
 
-	for _, file := range files {
-		// Skip directories and non-JSON files
-		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
-			continue
+	if id := r.URL.Query().Get("project"); id != "" {
+		project, ok := loadProject(w, id)
+		if !ok {
+			return
 		}
+		query.Only = make(map[string]bool)
+		for _, dataset := range project.Datasets {
+			query.Only[dataset] = true
+		}
+	}
 
-		// Get file info
-		filePath := filepath.Join("data", file.Name())
-		fileInfo, err := os.Stat(filePath)
+	index, err := loadIndex()
+	if err != nil {
+		log.Println("Failed to read listing index:", err)
+		util.RespondError(w, "Failed to read data directory")
+		return
+	}
+
+	page, err := util.ListIndex(index, query)
+	if err != nil {
+		util.RespondError(w, "Invalid cursor")
+		return
+	}
+
+	fileInfos := []FileInfo{}
+	for _, entry := range page.Entries {
+		meta, err := util.LoadMetadata(dataStore, entry.Dataset)
 		if err != nil {
+			log.Printf("Failed to get metadata for file %s: %v", entry.Dataset, err)
 			continue
 		}
 
-		// Get units from file
-		units, err := util.GetUnitsFromFile(filePath)
+		// Get the quality report stored for the dataset, if any
+		report, err := util.LoadQualityReport(dataStore, entry.Dataset)
 		if err != nil {
-			log.Printf("Failed to get units from file %s: %v", file.Name(), err)
-			units = []string{} // Empty array if units can't be read
+			report = nil
 		}
 
 		// Add file info to the list
 		fileInfos = append(fileInfos, FileInfo{
-			Name:     file.Name(),
-			Size:     fileInfo.Size(),
-			Modified: fileInfo.ModTime(),
-			Units:    units,
+			Name:       entry.Dataset,
+			Size:       entry.DataSize,
+			SourceSize: meta.Size,
+			Modified:   entry.Modified,
+			Units:      meta.Units,
+			Categories: util.GroupUnitsByCategory(meta.Units),
+			Quality:    report,
+			Metadata:   meta,
+			Projects:   membership[entry.Dataset],
 		})
 	}
 
-	// Return the list of files
+	// Return the page of files
 	responseWithCompression(w, r, map[string]interface{}{
-		"status": "ok",
-		"files":  fileInfos,
+		"status":      "ok",
+		"files":       fileInfos,
+		"total":       page.Total,
+		"next_cursor": page.NextCursor,
 	})
 }
+
+// parseListQuery reads the filters, order and page of a listing request
+func parseListQuery(r *http.Request) (util.ListQuery, error) {
+	params := r.URL.Query()
+	q := util.ListQuery{
+		Unit:     params.Get("unit"),
+		Category: params.Get("category"),
+		Name:     params.Get("name"),
+		Dataset:  params.Get("dataset"),
+		Sort:     params.Get("sort"),
+		Cursor:   params.Get("cursor"),
+		Limit:    defaultListLimit,
+	}
+
+	if q.Category != "" && !util.IsCategory(q.Category) {
+		return q, fmt.Errorf("invalid category: %s", q.Category)
+	}
+
+	switch q.Sort {
+	case "":
+		q.Sort = util.SortModified
+	case util.SortModified, util.SortSize, util.SortPoints, util.SortName:
+	default:
+		return q, fmt.Errorf("invalid sort: %s", q.Sort)
+	}
+
+	switch params.Get("order") {
+	case "":
+		q.Desc = q.Sort != util.SortName
+	case "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		return q, fmt.Errorf("invalid order: %s", params.Get("order"))
+	}
+
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxListLimit {
+			return q, fmt.Errorf("limit must be between 1 and %d", maxListLimit)
+		}
+		q.Limit = n
+	}
+
+	var err error
+	if q.From, err = parseListDate(params.Get("from"), false); err != nil {
+		return q, err
+	}
+	if q.To, err = parseListDate(params.Get("to"), true); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+// parseListDate parses a date (2006-01-02) or time (RFC 3339) of a listing
+// range. A date used as the end of a range includes the whole day.
+func parseListDate(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date: %s", s)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// indexSynced remembers the stores whose listing index has been checked
+// against the stored datasets
+var indexSynced sync.Map
+
+// loadIndex returns the listing index. The first listing of a store syncs it
+// with the stored datasets; after that the index is kept up to date by the
+// writes to datasets and their metadata.
+func loadIndex() (map[string]util.IndexEntry, error) {
+	if _, done := indexSynced.Load(dataStore); done {
+		return util.LoadIndex(dataStore)
+	}
+	index, err := syncIndex()
+	if err != nil {
+		return nil, err
+	}
+	indexSynced.Store(dataStore, true)
+	return index, nil
+}
+
+// syncIndex returns the listing index after adding the datasets it misses,
+// such as those stored before it existed, dropping those that are gone and
+// bringing the size and modification time of changed datasets up to date.
+// Only the list of stored datasets is read.
+func syncIndex() (map[string]util.IndexEntry, error) {
+	index, err := util.LoadIndex(dataStore)
+	if err != nil {
+		return nil, err
+	}
+	datasets, err := dataStore.List(store.Datasets)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]bool, len(datasets))
+	var changed []util.IndexEntry
+	for _, dataset := range datasets {
+		// Skip non-JSON files
+		if filepath.Ext(dataset.Name) != ".json" {
+			continue
+		}
+		stored[dataset.Name] = true
+
+		entry, ok := index[dataset.Name]
+		if !ok {
+			meta, err := loadOrBackfillMetadata(dataset)
+			if err != nil {
+				log.Printf("Failed to get metadata for file %s: %v", dataset.Name, err)
+				continue
+			}
+			entry = util.NewIndexEntry(*meta)
+			entry.Dataset = dataset.Name
+		} else if entry.DataSize == dataset.Size && entry.Modified.Equal(dataset.Modified) {
+			continue
+		}
+		entry.DataSize, entry.Modified = dataset.Size, dataset.Modified
+		changed = append(changed, entry)
+		index[dataset.Name] = entry
+	}
+	if len(changed) > 0 {
+		if err := util.UpdateIndex(dataStore, changed...); err != nil {
+			return nil, err
+		}
+	}
+
+	var gone []string
+	for name := range index {
+		if !stored[name] {
+			gone = append(gone, name)
+			delete(index, name)
+		}
+	}
+	if len(gone) > 0 {
+		if err := util.RemoveFromIndex(dataStore, gone...); err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+// DatasetFileHandler serves the raw JSON of a dataset. It is mounted below a
+// prefix that is stripped, so the request path is the dataset name.
+func DatasetFileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := dataStore.Get(store.Datasets, r.URL.Path)
+	if errors.Is(err, store.ErrNotFound) || errors.Is(err, store.ErrInvalidName) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Println("Failed to read dataset:", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// recordMetadata stores the metadata of a freshly processed upload and its
+// extraction as the first version. Failures are logged but do not fail the
+// upload.
+func recordMetadata(source, dataFile string, header *multipart.FileHeader) {
+	meta, err := util.NewMetadata(dataStore, source, dataFile, header.Filename, header.Header.Get("Content-Type"))
+	if err != nil {
+		log.Println("Failed to build metadata:", err)
+		return
+	}
+	if v, err := util.RecordVersion(dataStore, dataFile, meta.ExtractorVersion, meta.Rules, meta.UploadedAt); err != nil {
+		log.Println("Failed to record version:", err)
+	} else {
+		meta.Version = v.Version
+	}
+	if err := util.SaveMetadata(dataStore, dataFile, meta); err != nil {
+		log.Println("Failed to save metadata:", err)
+	}
+}
+
+// loadOrBackfillMetadata returns the metadata of a dataset. Datasets from
+// before metadata was recorded get it built from their files once, using the
+// modification time of the dataset as upload time. Their extractor version
+// is unknown, and their points may predate point IDs, so those are assigned.
+func loadOrBackfillMetadata(dataset store.Object) (*util.Metadata, error) {
+	if meta, err := util.LoadMetadata(dataStore, dataset.Name); err == nil {
+		return meta, nil
+	}
+	if err := util.BackfillPointIDs(dataStore, dataset.Name); err != nil {
+		log.Println("Failed to assign point IDs:", err)
+	}
+
+	source := util.SourceName(dataset.Name)
+	meta, err := util.NewMetadata(dataStore, source, dataset.Name, source, "")
+	if err != nil {
+		// Without its source only the dataset itself can be described
+		units, err := util.GetUnitsFromDataset(dataStore, dataset.Name)
+		if err != nil {
+			return nil, err
+		}
+		points, err := util.ReadDataset(dataStore, dataset.Name)
+		if err != nil {
+			return nil, err
+		}
+		meta = util.Metadata{
+			Dataset:       dataset.Name,
+			PointCount:    len(points),
+			Units:         units,
+			SourceRemoved: true,
+		}
+	}
+	meta.UploadedAt = dataset.Modified
+	meta.ExtractorVersion = "unknown"
+	meta.Rules = nil
+
+	if err := util.SaveMetadata(dataStore, dataset.Name, meta); err != nil {
+		log.Println("Failed to save metadata:", err)
+	}
+	return &meta, nil
+}
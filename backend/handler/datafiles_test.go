@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+// uploadResponse is the part of the upload response the tests look at
+type uploadResponse struct {
+	Status    string `json:"status"`
+	DataFile  string `json:"data_file"`
+	Duplicate bool   `json:"duplicate"`
+}
+
+// uploadDocument posts a document to ProcessAndGenerateHandler
+func uploadDocument(t *testing.T, name, content string, fields map[string]string) uploadResponse {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("document", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(content))
+	for k, v := range fields {
+		form.WriteField(k, v)
+	}
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/process-and-generate", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	ProcessAndGenerateHandler(rec, req)
+
+	var uploaded uploadResponse
+	if err := json.NewDecoder(rec.Body).Decode(&uploaded); err != nil || uploaded.Status != "ok" {
+		t.Fatalf("upload failed: %v %+v", err, uploaded)
+	}
+	return uploaded
+}
+
+func TestUploadAndListDataFiles(t *testing.T) {
+	SetStore(store.NewMemory())
+	defer SetStore(store.NewLocal("."))
+
+	uploaded := uploadDocument(t, "survey.txt", "Rainfall was 5 mm and the river reached 12 °C", nil)
+
+	rec := httptest.NewRecorder()
+	ListDataFilesHandler(rec, httptest.NewRequest(http.MethodGet, "/api/data-files", nil))
+
+	var listed struct {
+		Files []FileInfo `json:"files"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.Files) != 1 {
+		t.Fatalf("listed %d files, want 1", len(listed.Files))
+	}
+
+	file := listed.Files[0]
+	if file.Name != uploaded.DataFile || file.Metadata == nil || file.Metadata.OriginalName != "survey.txt" {
+		t.Errorf("listed %+v, want %s uploaded from survey.txt", file, uploaded.DataFile)
+	}
+	if len(file.Units) != 2 || file.Metadata.PointCount != 2 {
+		t.Errorf("listed units %v and %d points, want 2 of each", file.Units, file.Metadata.PointCount)
+	}
+
+	data, _ := dataStore.Get(store.Datasets, uploaded.DataFile)
+	if file.Size != int64(len(data)) || file.SourceSize != int64(len("Rainfall was 5 mm and the river reached 12 °C")) {
+		t.Errorf("listed size %d and source size %d, want %d and the document size", file.Size, file.SourceSize, len(data))
+	}
+}
+
+func TestListingAfterLaterUploads(t *testing.T) {
+	SetStore(store.NewMemory())
+	defer SetStore(store.NewLocal("."))
+
+	list := func() []FileInfo {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		ListDataFilesHandler(rec, httptest.NewRequest(http.MethodGet, "/api/data-files", nil))
+		var listed struct {
+			Files []FileInfo `json:"files"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil {
+			t.Fatal(err)
+		}
+		return listed.Files
+	}
+
+	uploadDocument(t, "rivers.txt", "Turbidity was 4 NTU", nil)
+	if files := list(); len(files) != 1 {
+		t.Fatalf("listed %d files, want 1", len(files))
+	}
+
+	// The index was synced by the first listing; later uploads add themselves
+	uploaded := uploadDocument(t, "air.txt", "PM2.5 was 12 µg/m³", nil)
+	files := list()
+	if len(files) != 2 {
+		t.Fatalf("listed %d files, want 2", len(files))
+	}
+	data, _ := dataStore.Get(store.Datasets, uploaded.DataFile)
+	for _, file := range files {
+		if file.Name == uploaded.DataFile && file.Size != int64(len(data)) {
+			t.Errorf("listed size %d, want %d", file.Size, len(data))
+		}
+	}
+}
+
+func TestUploadDeduplication(t *testing.T) {
+	st := store.NewMemory()
+	SetStore(st)
+	defer SetStore(store.NewLocal("."))
+
+	const report = "Turbidity was 4 NTU"
+	first := uploadDocument(t, "report.txt", report, nil)
+	again := uploadDocument(t, "report (copy).txt", report, nil)
+	forced := uploadDocument(t, "report.txt", report, map[string]string{"force": "true"})
+
+	if first.Duplicate || again.DataFile != first.DataFile || !again.Duplicate {
+		t.Errorf("second upload = %+v, want the dataset %s as a duplicate", again, first.DataFile)
+	}
+	if forced.Duplicate {
+		t.Errorf("forced upload = %+v, want a fresh extraction", forced)
+	}
+
+	documents, _ := st.List(store.Documents)
+	if len(documents) != 2 {
+		t.Errorf("stored %d documents, want 2", len(documents))
+	}
+
+	meta, err := util.LoadMetadata(st, first.DataFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(meta.Aliases) != 1 || meta.Aliases[0].OriginalName != "report (copy).txt" {
+		t.Errorf("aliases = %+v, want the copy", meta.Aliases)
+	}
+}
+
+func TestListDataFilesByProject(t *testing.T) {
+	st := store.NewMemory()
+	SetStore(st)
+	defer SetStore(store.NewLocal("."))
+
+	inProject := uploadDocument(t, "rivers.txt", "Turbidity was 4 NTU", nil)
+	uploadDocument(t, "air.txt", "PM2.5 was 12 µg/m³", nil)
+
+	project := util.Project{ID: "rivers", Name: "Rivers", Datasets: []string{inProject.DataFile}}
+	if err := util.SaveProject(st, &project); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	ListDataFilesHandler(rec, httptest.NewRequest(http.MethodGet, "/api/data-files?project=rivers", nil))
+
+	var listed struct {
+		Files []FileInfo `json:"files"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.Files) != 1 || listed.Files[0].Name != inProject.DataFile || len(listed.Files[0].Projects) != 1 {
+		t.Errorf("listed %+v, want only %s in project rivers", listed.Files, inProject.DataFile)
+	}
+}
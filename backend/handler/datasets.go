@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+// maxDisplayNameLength caps the length of a dataset's display name
+const maxDisplayNameLength = 200
+
+// RenameRequest represents the request payload for renaming a dataset
+type RenameRequest struct {
+	DisplayName string `json:"display_name"`
+}
+
+// DatasetHandler renames (PATCH) or deletes (DELETE) the dataset in the path
+func DatasetHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "PATCH, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	if r.Method != http.MethodPatch && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	id := r.PathValue("id")
+	if !store.ValidName(id) {
+		util.RespondError(w, "Invalid dataset ID")
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		deleteDataset(w, r, id)
+		return
+	}
+	renameDataset(w, r, id)
+}
+
+func deleteDataset(w http.ResponseWriter, r *http.Request, id string) {
+	if err := util.DeleteDataset(dataStore, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Dataset not found", http.StatusNotFound)
+			return
+		}
+		log.Println("Failed to delete dataset:", err)
+		util.RespondError(w, "Failed to delete dataset")
+		return
+	}
+	invalidateCharts(id)
+	log.Printf("Deleted dataset %s", id)
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":  "ok",
+		"deleted": id,
+	})
+}
+
+func renameDataset(w http.ResponseWriter, r *http.Request, id string) {
+	var req RenameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.RespondError(w, "Invalid request body")
+		return
+	}
+	if len(req.DisplayName) > maxDisplayNameLength {
+		util.RespondError(w, "Display name is too long")
+		return
+	}
+
+	dataset, err := dataStore.Stat(store.Datasets, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Dataset not found", http.StatusNotFound)
+			return
+		}
+		log.Println("Failed to read dataset:", err)
+		util.RespondError(w, "Failed to read dataset")
+		return
+	}
+
+	// Datasets from before metadata was recorded get theirs now
+	if _, err := loadOrBackfillMetadata(dataset); err != nil {
+		log.Println("Failed to load metadata:", err)
+		util.RespondError(w, "Failed to rename dataset")
+		return
+	}
+
+	meta, err := util.RenameDataset(dataStore, id, req.DisplayName)
+	if err != nil {
+		log.Println("Failed to rename dataset:", err)
+		util.RespondError(w, "Failed to rename dataset")
+		return
+	}
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":   "ok",
+		"metadata": meta,
+	})
+}
+
+// ReextractHandler runs the current extraction rules over the source document
+// of the dataset in the path again, keeping the earlier result as a version.
+// Datasets with manual corrections are only re-extracted with force=true.
+func ReextractHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	id := r.PathValue("id")
+	if !store.ValidName(id) {
+		util.RespondError(w, "Invalid dataset ID")
+		return
+	}
+
+	dataset, err := dataStore.Stat(store.Datasets, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Dataset not found", http.StatusNotFound)
+			return
+		}
+		log.Println("Failed to read dataset:", err)
+		util.RespondError(w, "Failed to read dataset")
+		return
+	}
+	if _, err := loadOrBackfillMetadata(dataset); err != nil {
+		log.Println("Failed to load metadata:", err)
+		util.RespondError(w, "Failed to re-extract dataset")
+		return
+	}
+
+	version, report, err := util.Reextract(dataStore, id, forceRequested(r))
+	if err != nil {
+		log.Println("Failed to re-extract dataset:", err)
+		if errors.Is(err, util.ErrSourceRemoved) {
+			util.RespondError(w, "Source document of this dataset was removed")
+			return
+		}
+		if errors.Is(err, util.ErrNotExtracted) {
+			util.RespondError(w, "Only datasets extracted from a document can be re-extracted")
+			return
+		}
+		if errors.Is(err, util.ErrHasCorrections) {
+			util.RespondError(w, "Re-extracting would discard the manual corrections of this dataset; repeat with force=true to discard them")
+			return
+		}
+		util.RespondError(w, "Failed to re-extract dataset")
+		return
+	}
+	invalidateCharts(id)
+	log.Printf("Re-extracted dataset %s as version %d", id, version.Version)
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":  "ok",
+		"version": version,
+		"quality": report,
+	})
+}
+
+// DatasetVersionsHandler lists the extraction versions of the dataset in the path
+func DatasetVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	id := r.PathValue("id")
+	if !store.ValidName(id) {
+		util.RespondError(w, "Invalid dataset ID")
+		return
+	}
+	if _, err := dataStore.Stat(store.Datasets, id); err != nil {
+		http.Error(w, "Dataset not found", http.StatusNotFound)
+		return
+	}
+
+	versions, err := util.ListVersions(dataStore, id)
+	if err != nil {
+		log.Println("Failed to list versions:", err)
+		util.RespondError(w, "Failed to list versions")
+		return
+	}
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":   "ok",
+		"versions": versions,
+	})
+}
+
+var retentionOnce sync.Once
+
+// StartRetention applies policy to the store now and then every interval,
+// until the process exits. Later calls do nothing.
+func StartRetention(policy util.RetentionPolicy, interval time.Duration) {
+	if !policy.Enabled() || interval <= 0 {
+		return
+	}
+	retentionOnce.Do(func() {
+		go func() {
+			for {
+				applyRetention(policy)
+				time.Sleep(interval)
+			}
+		}()
+	})
+}
+
+// applyRetention runs one retention pass and drops the charts of removed datasets
+func applyRetention(policy util.RetentionPolicy) util.RetentionResult {
+	result, err := util.ApplyRetention(dataStore, policy, time.Now())
+	if err != nil {
+		log.Println("Retention run failed:", err)
+	}
+	for _, dataset := range result.Datasets {
+		invalidateCharts(dataset)
+	}
+	if len(result.Uploads) > 0 || len(result.Datasets) > 0 {
+		log.Printf("Retention removed %d uploads and %d datasets", len(result.Uploads), len(result.Datasets))
+	}
+	return result
+}
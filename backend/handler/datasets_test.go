@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+func TestRenameAndDeleteDataset(t *testing.T) {
+	st := store.NewMemory()
+	SetStore(st)
+	defer SetStore(store.NewLocal("."))
+
+	uploaded := uploadDocument(t, "survey.txt", "Rainfall was 5 mm", nil)
+	svgCache.Lock()
+	svgCache.items[uploaded.DataFile+"-mm--"] = cacheItem{svg: "<svg/>"}
+	svgCache.Unlock()
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/datasets/"+uploaded.DataFile, strings.NewReader(`{"display_name":"River survey"}`))
+	req.SetPathValue("id", uploaded.DataFile)
+	rec := httptest.NewRecorder()
+	DatasetHandler(rec, req)
+	if meta, err := util.LoadMetadata(st, uploaded.DataFile); err != nil || meta.DisplayName != "River survey" {
+		t.Errorf("after rename metadata = %+v, %v (%s)", meta, err, rec.Body)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/datasets/"+uploaded.DataFile, nil)
+	req.SetPathValue("id", uploaded.DataFile)
+	rec = httptest.NewRecorder()
+	DatasetHandler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("delete = %d %s", rec.Code, rec.Body)
+	}
+	if _, err := st.Stat(store.Documents, util.SourceName(uploaded.DataFile)); err == nil {
+		t.Error("source document kept after delete")
+	}
+	svgCache.RLock()
+	cached := len(svgCache.items)
+	svgCache.RUnlock()
+	if cached != 0 {
+		t.Errorf("%d charts cached after delete, want 0", cached)
+	}
+
+	rec = httptest.NewRecorder()
+	DatasetHandler(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("second delete = %d, want 404", rec.Code)
+	}
+}
+
+func TestMergedAndUploadedNamesDoNotCollide(t *testing.T) {
+	SetStore(store.NewMemory())
+	defer SetStore(store.NewLocal("."))
+
+	// An upload called "merged" takes the name a merge would pick next
+	uploaded, release, err := generateUniqueFilename("merged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	if err := dataStore.Put(store.Documents, uploaded, []byte("Rainfall was 5 mm")); err != nil {
+		t.Fatal(err)
+	}
+	merged, release, err := uniqueDatasetName("merged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	if merged == uploaded+".json" {
+		t.Errorf("merged dataset named %s like the dataset of upload %s", merged, uploaded)
+	}
+
+	if err := dataStore.Put(store.Datasets, merged, []byte("[]")); err != nil {
+		t.Fatal(err)
+	}
+	if name, _, err := generateUniqueFilename("merged"); err != nil || name+".json" == merged || name == uploaded {
+		t.Errorf("generateUniqueFilename() = %s, %v, taken by %s or %s", name, err, uploaded, merged)
+	}
+}
+
+func TestReservedNamesAreNotHandedOutTwice(t *testing.T) {
+	SetStore(store.NewMemory())
+	defer SetStore(store.NewLocal("."))
+
+	// Nothing is written while the names are held
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		upload, release, err := generateUniqueFilename("merged")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer release()
+		merged, release, err := uniqueDatasetName("merged")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer release()
+
+		for _, name := range []string{upload, util.SourceName(merged)} {
+			if seen[name] {
+				t.Errorf("name %s handed out twice", name)
+			}
+			seen[name] = true
+		}
+	}
+}
+
+// memoryFile is an uploaded file held in memory
+type memoryFile struct{ *bytes.Reader }
+
+func (memoryFile) Close() error { return nil }
+
+func TestConcurrentDuplicateUploads(t *testing.T) {
+	SetStore(store.NewMemory())
+	defer SetStore(store.NewLocal("."))
+
+	content := []byte("Rainfall was 5 mm")
+	results := make([]ingestResult, 8)
+	errs := make([]error, len(results))
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			header := &multipart.FileHeader{Filename: "report.txt", Size: int64(len(content))}
+			results[i], errs[i] = ingestUpload(memoryFile{bytes.NewReader(content)}, header, fmt.Sprintf("report_%d.txt", i), false)
+		}(i)
+	}
+	wg.Wait()
+
+	extracted := 0
+	for i, result := range results {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if !result.Duplicate {
+			extracted++
+		}
+	}
+	if extracted != 1 {
+		t.Errorf("content extracted %d times, want once: %+v", extracted, results)
+	}
+}
+
+func TestCreateProjectWithUnknownDataset(t *testing.T) {
+	st := store.NewMemory()
+	SetStore(st)
+	defer SetStore(store.NewLocal("."))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/projects", strings.NewReader(`{"name":"Survey","datasets":["missing.json"]}`))
+	rec := httptest.NewRecorder()
+	ProjectsHandler(rec, req)
+	if !strings.Contains(rec.Body.String(), "missing.json not found") {
+		t.Errorf("response = %s, want dataset not found", rec.Body)
+	}
+	if projects, _ := st.List(store.Projects); len(projects) != 0 {
+		t.Errorf("projects = %v, want none saved", projects)
+	}
+}
+
+func TestExportFilename(t *testing.T) {
+	SetStore(store.NewMemory())
+	defer SetStore(store.NewLocal("."))
+
+	uploaded := uploadDocument(t, "relevé.txt", "Rainfall was 5 mm", nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/datasets/"+uploaded.DataFile+"/export?format=csv", nil)
+	req.SetPathValue("id", uploaded.DataFile)
+	rec := httptest.NewRecorder()
+	ExportHandler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export = %d %s", rec.Code, rec.Body)
+	}
+
+	_, params, err := mime.ParseMediaType(rec.Header().Get("Content-Disposition"))
+	if err != nil || params["filename"] != util.SourceName(uploaded.DataFile)+".csv" {
+		t.Errorf("Content-Disposition = %q (%v), want filename %s.csv", rec.Header().Get("Content-Disposition"), err, util.SourceName(uploaded.DataFile))
+	}
+}
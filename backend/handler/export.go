@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+// exportFlushEvery is how many points are written between flushes of the response
+const exportFlushEvery = 1000
+
+// ExportHandler streams the points of the dataset in the path as CSV, NDJSON
+// or XLSX (?format=), optionally only those with a unit (?unit=) or in a
+// section (?section=)
+func ExportHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.PathValue("id")
+	if !store.ValidName(id) {
+		w.Header().Set("Content-Type", "application/json")
+		util.RespondError(w, "Invalid dataset ID")
+		return
+	}
+
+	params := r.URL.Query()
+	formatName := params.Get("format")
+	if formatName == "" {
+		formatName = "csv"
+	}
+	format, ok := util.ExportFormats[formatName]
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		util.RespondError(w, fmt.Sprintf("Invalid format: %s. Valid formats are: csv, ndjson, xlsx", formatName))
+		return
+	}
+
+	if _, err := dataStore.Stat(store.Datasets, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Dataset not found", http.StatusNotFound)
+			return
+		}
+		log.Println("Failed to read dataset:", err)
+		w.Header().Set("Content-Type", "application/json")
+		util.RespondError(w, "Failed to read dataset")
+		return
+	}
+
+	unit, section := params.Get("unit"), params.Get("section")
+	filename := util.SourceName(id) + format.Extension
+
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+	export, _ := util.NewExportWriter(formatName, w)
+	flusher, _ := w.(http.Flusher)
+
+	written := 0
+	err := util.StreamDataset(dataStore, id, func(dp util.DataPoint) error {
+		if unit != "" && dp.Unit != unit {
+			return nil
+		}
+		if section != "" && !strings.EqualFold(dp.Section, section) {
+			return nil
+		}
+		if err := export.WritePoint(dp); err != nil {
+			return err
+		}
+		written++
+		if flusher != nil && written%exportFlushEvery == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = export.Close()
+	}
+	if err != nil {
+		// The response has started, all that is left is to log it
+		log.Printf("Failed to export dataset %s: %v", id, err)
+		return
+	}
+	log.Printf("Exported %d points of %s as %s", written, id, formatName)
+}
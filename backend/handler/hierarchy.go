@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/svgchart"
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+// generateHierarchyChart renders a tree or treemap of a hierarchy found in the
+// source document of req.DataFile
+func generateHierarchyChart(w http.ResponseWriter, r *http.Request, req ChartRequest, cacheKey string) {
+	if req.Hierarchy == "" {
+		req.Hierarchy = "taxonomy"
+	}
+
+	source, err := readSourceDocument(req.DataFile)
+	if err != nil {
+		log.Println("Failed to read source document:", err)
+		util.RespondError(w, "Source document not found for this data file")
+		return
+	}
+
+	var root *util.TreeNode
+	title := req.Title
+	switch req.Hierarchy {
+	case "taxonomy":
+		root = util.TaxonomyTree(util.FindSpecies(string(source), util.CurrentTaxonReference()))
+		if title == "" {
+			title = "Taxonomy"
+		}
+	case "outline":
+		dataPoints, err := util.ReadDatasetVersion(dataStore, req.DataFile, req.Version)
+		if err != nil {
+			log.Println("Failed to read data file:", err)
+			util.RespondError(w, "Failed to read data file")
+			return
+		}
+		root = util.OutlineTree(string(source), dataPoints)
+		if title == "" {
+			title = "Document outline"
+		}
+	}
+
+	if root == nil || (root.Count == 0 && len(root.Children) == 0) {
+		util.RespondError(w, fmt.Sprintf("No %s found in document", req.Hierarchy))
+		return
+	}
+
+	width := req.Width
+	if width == 0 {
+		width = defaultWidth
+	}
+	height := req.Height
+	if height == 0 {
+		height = defaultHeight
+	}
+
+	chart, err := svgchart.New(root, svgchart.ChartType(req.ChartType), svgchart.WithTitle(title), svgchart.WithDimensions(width, height))
+	if err != nil {
+		log.Println("Failed to create chart:", err)
+		util.RespondError(w, "Failed to create chart")
+		return
+	}
+
+	svgContent := chart.Generate()
+
+	// Cache the result
+	svgCache.Lock()
+	svgCache.items[cacheKey] = cacheItem{
+		svg:       svgContent,
+		createdAt: time.Now(),
+	}
+	svgCache.Unlock()
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":     "ok",
+		"svg":        svgContent,
+		"type":       req.ChartType,
+		"hierarchy":  req.Hierarchy,
+		"data_count": root.Count,
+		"cached":     false,
+	})
+}
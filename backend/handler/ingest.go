@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+// ingestResult is the outcome of storing and extracting an upload
+type ingestResult struct {
+	DataFile  string
+	Report    *util.QualityReport
+	Duplicate bool
+}
+
+// ingestUpload stores an uploaded document as filename and extracts its
+// dataset. When the same content was uploaded before, the existing dataset is
+// returned and the upload is recorded as an alias of it, unless force is set.
+func ingestUpload(file multipart.File, header *multipart.FileHeader, filename string, force bool) (ingestResult, error) {
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return ingestResult{}, fmt.Errorf("reading upload: %w", err)
+	}
+
+	hash := util.ContentHash(content)
+
+	// Uploads of the same content go one at a time, so the second finds the
+	// dataset of the first
+	unlock := lockContentHash(hash)
+	defer unlock()
+
+	if !force {
+		existing, found, err := util.FindDatasetByHash(dataStore, hash)
+		if err != nil {
+			log.Println("Failed to look up content hash:", err)
+		}
+		if found {
+			if err := util.AddAlias(dataStore, existing, header.Filename); err != nil {
+				log.Println("Failed to record alias:", err)
+			}
+			report, err := util.LoadQualityReport(dataStore, existing)
+			if err != nil {
+				report = nil
+			}
+			return ingestResult{DataFile: existing, Report: report, Duplicate: true}, nil
+		}
+	}
+
+	dataFile := filename + ".json"
+
+	// Save uploaded file
+	if err := dataStore.Put(store.Documents, filename, content); err != nil {
+		return ingestResult{}, fmt.Errorf("saving file: %w", err)
+	}
+
+	// Process file through ParseDocument
+	report, err := util.ParseDocument(dataStore, filename, dataFile)
+	if err != nil {
+		return ingestResult{}, fmt.Errorf("parsing document: %w", err)
+	}
+
+	if err := util.SaveQualityReport(dataStore, dataFile, report); err != nil {
+		log.Println("Failed to save quality report:", err)
+	}
+
+	recordMetadata(filename, dataFile, header)
+
+	if err := util.RecordContentHash(dataStore, hash, dataFile); err != nil {
+		log.Println("Failed to record content hash:", err)
+	}
+
+	return ingestResult{DataFile: dataFile, Report: &report}, nil
+}
+
+var (
+	// hashLocksMu guards hashLocks, the locks of the content hashes being
+	// uploaded
+	hashLocksMu sync.Mutex
+	hashLocks   = make(map[string]*hashLock)
+)
+
+// hashLock serialises the uploads of one content hash
+type hashLock struct {
+	sync.Mutex
+	waiting int
+}
+
+// lockContentHash locks hash against other uploads of the same content and
+// returns the function that unlocks it
+func lockContentHash(hash string) func() {
+	hashLocksMu.Lock()
+	l, ok := hashLocks[hash]
+	if !ok {
+		l = &hashLock{}
+		hashLocks[hash] = l
+	}
+	l.waiting++
+	hashLocksMu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		hashLocksMu.Lock()
+		l.waiting--
+		if l.waiting == 0 {
+			delete(hashLocks, hash)
+		}
+		hashLocksMu.Unlock()
+	}
+}
+
+// forceRequested reports whether the request asks to re-extract a document
+// that was uploaded before
+func forceRequested(r *http.Request) bool {
+	force, _ := strconv.ParseBool(r.FormValue("force"))
+	return force
+}
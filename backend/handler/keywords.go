@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/svgchart"
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+// defaultKeywords is the number of keywords returned or charted when no
+// limit is given
+const defaultKeywords = 50
+
+// KeywordsHandler returns the keywords of a dataset's source document ranked
+// by TF-IDF against every stored document
+func KeywordsHandler(w http.ResponseWriter, r *http.Request) {
+	// Set CORS headers if needed
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	dataFile := r.URL.Query().Get("dataFile")
+	if dataFile == "" {
+		util.RespondError(w, "dataFile is required")
+		return
+	}
+
+	limit := defaultKeywords
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxKeywords {
+			util.RespondError(w, fmt.Sprintf("limit must be between 1 and %d", maxKeywords))
+			return
+		}
+		limit = n
+	}
+
+	keywords, err := rankKeywords(dataFile, limit)
+	if err != nil {
+		log.Println("Failed to rank keywords:", err)
+		util.RespondError(w, "Failed to rank keywords")
+		return
+	}
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":    "ok",
+		"data_file": dataFile,
+		"keywords":  keywords,
+	})
+}
+
+// generateKeywordChart renders the top keywords of the source document of
+// req.DataFile as a word cloud or a ranked bar chart
+func generateKeywordChart(w http.ResponseWriter, r *http.Request, req ChartRequest, cacheKey string) {
+	if req.ChartType == "" {
+		req.ChartType = "wordcloud"
+	}
+	if req.ChartType != "wordcloud" && req.ChartType != "bar" {
+		util.RespondError(w, "Keywords can only be charted as a word cloud or a bar chart")
+		return
+	}
+
+	limit := req.Keywords
+	if limit == 0 {
+		limit = defaultKeywords
+	}
+
+	keywords, err := rankKeywords(req.DataFile, limit)
+	if err != nil {
+		log.Println("Failed to rank keywords:", err)
+		util.RespondError(w, "Failed to rank keywords")
+		return
+	}
+
+	if len(keywords) == 0 {
+		util.RespondError(w, "No keywords found in document")
+		return
+	}
+
+	// Scores are tiny fractions, chart them per thousand terms
+	data := make(svgchart.ChartData, len(keywords))
+	for i, k := range keywords {
+		data[i] = svgchart.DataPoint{Label: k.Term, Value: math.Round(k.Score*1e5) / 100}
+	}
+
+	width := req.Width
+	if width == 0 {
+		width = defaultWidth
+	}
+	height := req.Height
+	if height == 0 {
+		height = defaultHeight
+	}
+
+	title := req.Title
+	if title == "" {
+		title = "Keywords"
+	}
+
+	chart, err := svgchart.New(data, svgchart.ChartType(req.ChartType),
+		svgchart.WithTitle(title),
+		svgchart.WithDimensions(width, height),
+		svgchart.WithXLabel(req.XLabel),
+		svgchart.WithYLabel(req.YLabel),
+	)
+	if err != nil {
+		log.Println("Failed to create chart:", err)
+		util.RespondError(w, "Failed to create chart")
+		return
+	}
+
+	svgContent := chart.Generate()
+
+	// Cache the result
+	svgCache.Lock()
+	svgCache.items[cacheKey] = cacheItem{
+		svg:       svgContent,
+		createdAt: time.Now(),
+	}
+	svgCache.Unlock()
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":     "ok",
+		"svg":        svgContent,
+		"type":       req.ChartType,
+		"keywords":   keywords,
+		"data_count": len(keywords),
+		"cached":     false,
+	})
+}
+
+// rankKeywords ranks the terms of a data file's document against the corpus of
+// stored documents. Documents processed before term frequencies were stored
+// are counted from their source and saved on first use.
+func rankKeywords(dataFile string, limit int) ([]util.Keyword, error) {
+	tf, err := util.LoadTermFrequencies(dataStore, dataFile)
+	if err != nil {
+		source, err := readSourceDocument(dataFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading source document: %w", err)
+		}
+		counted := util.CountTerms(string(source), util.CurrentStopWords())
+		if err := util.SaveTermFrequencies(dataStore, dataFile, counted); err != nil {
+			log.Println("Failed to save term frequencies:", err)
+		}
+		tf = &counted
+	}
+
+	corpus, err := util.LoadCorpus(dataStore)
+	if err != nil {
+		return nil, err
+	}
+
+	return util.RankKeywords(*tf, corpus, limit), nil
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+// maxMergeSources caps how many datasets one merge combines
+const maxMergeSources = 100
+
+// MergeRequest represents the request payload for merging datasets
+type MergeRequest struct {
+	Datasets []string `json:"datasets"`
+	util.MergeOptions
+}
+
+// MergeHandler combines several datasets into a new one
+func MergeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req MergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.RespondError(w, "Invalid request body")
+		return
+	}
+	if len(req.Datasets) > maxMergeSources {
+		util.RespondError(w, fmt.Sprintf("At most %d datasets can be merged", maxMergeSources))
+		return
+	}
+	if len(req.Name) > maxDisplayNameLength {
+		util.RespondError(w, "Display name is too long")
+		return
+	}
+	for _, dataset := range req.Datasets {
+		if !store.ValidName(dataset) {
+			util.RespondError(w, "Invalid dataset ID")
+			return
+		}
+		if _, err := dataStore.Stat(store.Datasets, dataset); err != nil {
+			util.RespondError(w, fmt.Sprintf("Dataset %s not found", dataset))
+			return
+		}
+	}
+
+	dataFile, release, err := uniqueDatasetName("merged")
+	if err != nil {
+		log.Println("Failed to name merged dataset:", err)
+		util.RespondError(w, "Failed to merge datasets")
+		return
+	}
+	defer release()
+	meta, err := util.MergeDatasets(dataStore, dataFile, req.Datasets, req.MergeOptions)
+	if err != nil {
+		log.Println("Failed to merge datasets:", err)
+		if errors.Is(err, util.ErrNothingToMerge) {
+			util.RespondError(w, "Select at least two datasets to merge")
+			return
+		}
+		util.RespondError(w, "Failed to merge datasets")
+		return
+	}
+	log.Printf("Merged %d datasets into %s", len(req.Datasets), dataFile)
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":    "ok",
+		"data_file": dataFile,
+		"units":     meta.Units,
+		"metadata":  meta,
+	})
+}
+
+// uniqueDatasetName returns a dataset name of the form <base>_<unix>.json
+// that is not taken yet, neither by a dataset nor by an upload. The name is
+// reserved until release is called, which must happen after the dataset is
+// written.
+func uniqueDatasetName(base string) (name string, release func(), err error) {
+	timestamp := time.Now().Unix()
+
+	name, release, err = reserveSourceName(func(n int) string {
+		if n == 1 {
+			return fmt.Sprintf("%s_%d", base, timestamp)
+		}
+		return fmt.Sprintf("%s_%d_%d", base, timestamp, n)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return name + ".json", release, nil
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+// OutlineHandler returns the headings of the source document of a dataset and
+// the outline tree with the number of measurements in every section
+func OutlineHandler(w http.ResponseWriter, r *http.Request) {
+	// Set CORS headers if needed
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	dataFile := r.URL.Query().Get("dataFile")
+	if dataFile == "" {
+		util.RespondError(w, "dataFile is required")
+		return
+	}
+
+	dataPoints, err := readDataPoints(dataFile)
+	if err != nil {
+		log.Println("Failed to read data file:", err)
+		util.RespondError(w, "Failed to read data file")
+		return
+	}
+
+	source, err := readSourceDocument(dataFile)
+	if err != nil {
+		log.Println("Failed to read source document:", err)
+		util.RespondError(w, "Source document not found for this data file")
+		return
+	}
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":    "ok",
+		"data_file": dataFile,
+		"headings":  util.FindHeadings(string(source)),
+		"tree":      util.OutlineTree(string(source), dataPoints),
+	})
+}
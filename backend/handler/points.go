@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+// anonymousUser is recorded in the audit trail when a request names no user
+const anonymousUser = "anonymous"
+
+// requestUser returns who made a request, as named by its X-User header
+func requestUser(r *http.Request) string {
+	if user := strings.TrimSpace(r.Header.Get("X-User")); user != "" {
+		return user
+	}
+	return anonymousUser
+}
+
+// PointsHandler adds a data point (POST) to the dataset in the path
+func PointsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-User")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	id := r.PathValue("id")
+	if !store.ValidName(id) {
+		util.RespondError(w, "Invalid dataset ID")
+		return
+	}
+
+	var point util.DataPoint
+	if err := json.NewDecoder(r.Body).Decode(&point); err != nil {
+		util.RespondError(w, "Invalid request body")
+		return
+	}
+
+	added, err := util.AddPoint(dataStore, id, point, requestUser(r))
+	if err != nil {
+		respondCorrectionError(w, err)
+		return
+	}
+	invalidateCharts(id)
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status": "ok",
+		"point":  added,
+	})
+}
+
+// PointHandler edits (PATCH) or deletes (DELETE) a data point of the dataset in the path
+func PointHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "PATCH, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-User")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	if r.Method != http.MethodPatch && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	id := r.PathValue("id")
+	if !store.ValidName(id) {
+		util.RespondError(w, "Invalid dataset ID")
+		return
+	}
+	pointID := r.PathValue("point")
+
+	if r.Method == http.MethodDelete {
+		if err := util.DeletePoint(dataStore, id, pointID, requestUser(r)); err != nil {
+			respondCorrectionError(w, err)
+			return
+		}
+		invalidateCharts(id)
+
+		responseWithCompression(w, r, map[string]interface{}{
+			"status":  "ok",
+			"deleted": pointID,
+		})
+		return
+	}
+
+	var edit util.PointEdit
+	if err := json.NewDecoder(r.Body).Decode(&edit); err != nil {
+		util.RespondError(w, "Invalid request body")
+		return
+	}
+
+	updated, err := util.EditPoint(dataStore, id, pointID, edit, requestUser(r))
+	if err != nil {
+		respondCorrectionError(w, err)
+		return
+	}
+	invalidateCharts(id)
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status": "ok",
+		"point":  updated,
+	})
+}
+
+// AuditHandler returns the trail of manual corrections of the dataset in the path
+func AuditHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	id := r.PathValue("id")
+	if !store.ValidName(id) {
+		util.RespondError(w, "Invalid dataset ID")
+		return
+	}
+	if _, err := dataStore.Stat(store.Datasets, id); err != nil {
+		http.Error(w, "Dataset not found", http.StatusNotFound)
+		return
+	}
+
+	trail, err := util.LoadAuditTrail(dataStore, id)
+	if err != nil {
+		log.Println("Failed to load audit trail:", err)
+		util.RespondError(w, "Failed to load audit trail")
+		return
+	}
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status": "ok",
+		"audit":  trail,
+	})
+}
+
+// respondCorrectionError reports why a correction could not be made
+func respondCorrectionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "Dataset not found", http.StatusNotFound)
+	case errors.Is(err, util.ErrPointNotFound):
+		http.Error(w, "Data point not found", http.StatusNotFound)
+	case errors.Is(err, util.ErrInvalidPoint):
+		util.RespondError(w, err.Error())
+	default:
+		log.Println("Failed to correct data point:", err)
+		util.RespondError(w, "Failed to correct data point")
+	}
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+// PreviewRequest represents a JSON payload for an extraction preview
+type PreviewRequest struct {
+	Text string `json:"text"`
+}
+
+// ExtractPreviewHandler runs the extraction pipeline on an uploaded document or
+// pasted text and returns the result without persisting anything
+func ExtractPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	// Set CORS headers if needed
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	text, err := readPreviewText(w, r)
+	if err != nil {
+		log.Println("Failed to read preview input:", err)
+		util.RespondError(w, "Failed to read document or text")
+		return
+	}
+
+	if strings.TrimSpace(text) == "" {
+		util.RespondError(w, "document or text is required")
+		return
+	}
+
+	extraction := util.Extract(text)
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":          "ok",
+		"points":          extraction.Points,
+		"discarded":       extraction.Discarded,
+		"unit_counts":     extraction.UnitCounts,
+		"derived":         extraction.Derived,
+		"point_count":     len(extraction.Points),
+		"discarded_count": len(extraction.Discarded),
+	})
+}
+
+// readPreviewText returns the text to preview from either a JSON body, a
+// "text" form field or an uploaded "document" file
+func readPreviewText(w http.ResponseWriter, r *http.Request) (string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxFileSize)
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var req PreviewRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return "", err
+		}
+		return req.Text, nil
+	}
+
+	if err := r.ParseMultipartForm(maxFileSize); err != nil {
+		return "", err
+	}
+
+	if text := r.FormValue("text"); text != "" {
+		return text, nil
+	}
+
+	file, _, err := r.FormFile("document")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+// multipartBody builds a form with the given fields and, if document is not
+// empty, a "document" file
+func multipartBody(t *testing.T, fields map[string]string, document string) (*bytes.Buffer, string) {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for k, v := range fields {
+		form.WriteField(k, v)
+	}
+	if document != "" {
+		part, err := form.CreateFormFile("document", "report.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(document))
+	}
+	form.Close()
+	return &body, form.FormDataContentType()
+}
+
+func TestExtractPreviewHandler(t *testing.T) {
+	const text = "Rainfall was 5 mm and the river reached 12 °C. Page 3 of 4."
+
+	jsonBody, _ := json.Marshal(PreviewRequest{Text: text})
+	textForm, textType := multipartBody(t, map[string]string{"text": text}, "")
+	fileForm, fileType := multipartBody(t, nil, text)
+	emptyForm, emptyType := multipartBody(t, map[string]string{"text": "   "}, "")
+
+	tests := []struct {
+		name        string
+		method      string
+		body        *bytes.Buffer
+		contentType string
+		wantCode    int
+		wantPoints  int
+		wantError   string
+	}{
+		{"json text", http.MethodPost, bytes.NewBuffer(jsonBody), "application/json", http.StatusOK, 2, ""},
+		{"form text", http.MethodPost, textForm, textType, http.StatusOK, 2, ""},
+		{"uploaded document", http.MethodPost, fileForm, fileType, http.StatusOK, 2, ""},
+		{"blank text", http.MethodPost, emptyForm, emptyType, http.StatusBadRequest, 0, "document or text is required"},
+		{"malformed json", http.MethodPost, bytes.NewBufferString("{"), "application/json", http.StatusBadRequest, 0, "Failed to read document or text"},
+		{"wrong method", http.MethodGet, &bytes.Buffer{}, "", http.StatusMethodNotAllowed, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/extract/preview", tt.body)
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			ExtractPreviewHandler(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantError) {
+				t.Errorf("body = %s, want error %q", rec.Body, tt.wantError)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var preview struct {
+				Status         string           `json:"status"`
+				Points         []util.DataPoint `json:"points"`
+				PointCount     int              `json:"point_count"`
+				DiscardedCount int              `json:"discarded_count"`
+				UnitCounts     map[string]int   `json:"unit_counts"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&preview); err != nil {
+				t.Fatal(err)
+			}
+			if preview.Status != "ok" || preview.PointCount != tt.wantPoints || len(preview.Points) != tt.wantPoints {
+				t.Fatalf("preview = %+v, want %d points", preview, tt.wantPoints)
+			}
+			if preview.DiscardedCount == 0 {
+				t.Errorf("preview discarded nothing, want the page numbers")
+			}
+			if preview.UnitCounts["mm"] != 1 || preview.UnitCounts["°C"] != 1 {
+				t.Errorf("unit counts = %v, want one mm and one °C", preview.UnitCounts)
+			}
+			for _, dp := range preview.Points {
+				if dp.Source == nil || text[dp.Source.Offset:dp.Source.Offset+dp.Source.Length] == "" {
+					t.Errorf("point %+v has no provenance", dp)
+				}
+			}
+		})
+	}
+}
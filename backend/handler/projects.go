@@ -0,0 +1,312 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+	"github.com/Vinolia-E/BioTree/backend/util"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxProjectDescriptionLength caps the length of a project description
+	maxProjectDescriptionLength = 2000
+	// maxProjectTags caps the number of tags of a project
+	maxProjectTags = 50
+)
+
+// ProjectRequest represents the request payload for creating or updating a
+// project. Fields left out of an update are kept.
+type ProjectRequest struct {
+	Name        *string  `json:"name,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Datasets    []string `json:"datasets,omitempty"`
+}
+
+// validate checks the lengths of the fields of a project request
+func (r *ProjectRequest) validate() error {
+	if r.Name != nil && len(*r.Name) > maxDisplayNameLength {
+		return errors.New("name is too long")
+	}
+	if r.Description != nil && len(*r.Description) > maxProjectDescriptionLength {
+		return errors.New("description is too long")
+	}
+	if len(r.Tags) > maxProjectTags {
+		return errors.New("too many tags")
+	}
+	for _, dataset := range r.Datasets {
+		if !store.ValidName(dataset) {
+			return errors.New("invalid dataset ID")
+		}
+	}
+	return nil
+}
+
+// apply copies the fields set in the request onto p
+func (r *ProjectRequest) apply(p *util.Project) {
+	if r.Name != nil {
+		p.Name = *r.Name
+	}
+	if r.Description != nil {
+		p.Description = strings.TrimSpace(*r.Description)
+	}
+	if r.Tags != nil {
+		p.Tags = r.Tags
+	}
+}
+
+// ProjectsHandler lists projects (GET), optionally only those with a tag, or
+// creates one (POST)
+func ProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == http.MethodGet {
+		projects, err := util.ListProjects(dataStore)
+		if err != nil {
+			log.Println("Failed to list projects:", err)
+			util.RespondError(w, "Failed to list projects")
+			return
+		}
+		if tag := r.URL.Query().Get("tag"); tag != "" {
+			tagged := []util.Project{}
+			for _, p := range projects {
+				if p.HasTag(tag) {
+					tagged = append(tagged, p)
+				}
+			}
+			projects = tagged
+		}
+
+		responseWithCompression(w, r, map[string]interface{}{
+			"status":   "ok",
+			"projects": projects,
+		})
+		return
+	}
+
+	var req ProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.RespondError(w, "Invalid request body")
+		return
+	}
+	if err := req.validate(); err != nil {
+		util.RespondError(w, err.Error())
+		return
+	}
+
+	// Check the datasets first so an unknown one does not leave the project
+	// behind without them
+	if err := util.CheckDatasets(dataStore, req.Datasets); err != nil {
+		respondProjectError(w, err)
+		return
+	}
+
+	project := util.Project{ID: uuid.New().String()}
+	req.apply(&project)
+	for _, dataset := range req.Datasets {
+		if !project.Contains(dataset) {
+			project.Datasets = append(project.Datasets, dataset)
+		}
+	}
+	if err := util.SaveProject(dataStore, &project); err != nil {
+		respondProjectError(w, err)
+		return
+	}
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":  "ok",
+		"project": &project,
+	})
+}
+
+// ProjectHandler returns (GET), updates (PATCH) or deletes (DELETE) the
+// project in the path. Deleting a project keeps its datasets.
+func ProjectHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PATCH, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPatch && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	project, ok := loadProject(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodDelete:
+		if err := util.DeleteProject(dataStore, project.ID); err != nil {
+			log.Println("Failed to delete project:", err)
+			util.RespondError(w, "Failed to delete project")
+			return
+		}
+		invalidateCharts(projectChartPrefix + project.ID)
+		responseWithCompression(w, r, map[string]interface{}{
+			"status":  "ok",
+			"deleted": project.ID,
+		})
+		return
+
+	case http.MethodPatch:
+		var req ProjectRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			util.RespondError(w, "Invalid request body")
+			return
+		}
+		if err := req.validate(); err != nil {
+			util.RespondError(w, err.Error())
+			return
+		}
+		var err error
+		if project, err = util.UpdateProject(dataStore, project.ID, req.apply); err != nil {
+			respondProjectError(w, err)
+			return
+		}
+	}
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":  "ok",
+		"project": project,
+	})
+}
+
+// ProjectDatasetsHandler assigns datasets (POST) to the project in the path
+func ProjectDatasetsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	project, ok := loadProject(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	var req ProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.RespondError(w, "Invalid request body")
+		return
+	}
+	if err := req.validate(); err != nil {
+		util.RespondError(w, err.Error())
+		return
+	}
+
+	project, err := util.AssignDatasets(dataStore, project.ID, req.Datasets)
+	if err != nil {
+		respondProjectError(w, err)
+		return
+	}
+	invalidateCharts(projectChartPrefix + project.ID)
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":  "ok",
+		"project": project,
+	})
+}
+
+// ProjectDatasetHandler removes (DELETE) a dataset from the project in the path
+func ProjectDatasetHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	project, ok := loadProject(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	project, err := util.UnassignDataset(dataStore, project.ID, r.PathValue("dataset"))
+	if err != nil {
+		respondProjectError(w, err)
+		return
+	}
+	invalidateCharts(projectChartPrefix + project.ID)
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":  "ok",
+		"project": project,
+	})
+}
+
+// loadProject reads the project with id, responding with an error when it
+// cannot be read
+func loadProject(w http.ResponseWriter, id string) (*util.Project, bool) {
+	if !store.ValidName(id) {
+		util.RespondError(w, "Invalid project ID")
+		return nil, false
+	}
+	project, err := util.LoadProject(dataStore, id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		log.Println("Failed to load project:", err)
+		util.RespondError(w, "Failed to load project")
+		return nil, false
+	}
+	return project, true
+}
+
+// respondProjectError reports why a project could not be changed
+func respondProjectError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, util.ErrInvalidProject):
+		util.RespondError(w, err.Error())
+	case errors.Is(err, store.ErrNotFound):
+		// The project was deleted while it was being changed
+		http.Error(w, "Project not found", http.StatusNotFound)
+	default:
+		log.Println("Failed to save project:", err)
+		util.RespondError(w, "Failed to save project")
+	}
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+const (
+	// defaultQueryResults is the page size of a query without a limit
+	defaultQueryResults = 100
+	// maxQueryResults caps the page size of a query
+	maxQueryResults = 1000
+)
+
+// QueryHandler returns the data points of every dataset matching ?filter=,
+// a page at a time. See util.ParseQuery for the filter language.
+func QueryHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.URL.Query()
+	filter := params.Get("filter")
+	if len(filter) > maxQueryLength {
+		util.RespondError(w, "Filter is too long")
+		return
+	}
+
+	query, err := util.ParseQuery(filter)
+	if err != nil {
+		util.RespondError(w, err.Error())
+		return
+	}
+
+	limit := defaultQueryResults
+	if s := params.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxQueryResults {
+			util.RespondError(w, fmt.Sprintf("limit must be between 1 and %d", maxQueryResults))
+			return
+		}
+		limit = n
+	}
+
+	page, err := util.RunQuery(dataStore, query, params.Get("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidCursor) {
+			util.RespondError(w, "Invalid cursor")
+			return
+		}
+		log.Println("Failed to run query:", err)
+		util.RespondError(w, "Failed to run query")
+		return
+	}
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":      "ok",
+		"filter":      filter,
+		"results":     page.Results,
+		"next_cursor": page.NextCursor,
+	})
+}
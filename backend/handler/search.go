@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+const (
+	// defaultSearchResults is the number of datasets a search returns by default
+	defaultSearchResults = 20
+	// maxSearchResults caps the number of datasets a search returns
+	maxSearchResults = 100
+	// maxQueryLength caps the length of a search query
+	maxQueryLength = 500
+)
+
+// SearchHandler finds the uploaded documents containing the words of ?q=,
+// returning snippets of the matching passages and the data points in them
+func SearchHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		util.RespondError(w, "q is required")
+		return
+	}
+	if len(query) > maxQueryLength {
+		util.RespondError(w, "Query is too long")
+		return
+	}
+
+	limit := defaultSearchResults
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxSearchResults {
+			util.RespondError(w, fmt.Sprintf("limit must be between 1 and %d", maxSearchResults))
+			return
+		}
+		limit = n
+	}
+
+	results, err := util.Search(dataStore, query, limit)
+	if err != nil {
+		log.Println("Failed to search documents:", err)
+		util.RespondError(w, "Failed to search documents")
+		return
+	}
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":  "ok",
+		"query":   query,
+		"results": results,
+	})
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"github.com/Vinolia-E/BioTree/backend/store"
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+// dataStore holds the uploaded documents, the datasets extracted from them and
+// their sidecar records
+var dataStore store.Store = store.NewLocal(".")
+
+// SetStore replaces the store every handler reads from and writes to
+func SetStore(s store.Store) {
+	dataStore = s
+}
+
+// readSourceDocument reads the uploaded document a data file was extracted from
+func readSourceDocument(dataFile string) ([]byte, error) {
+	return dataStore.Get(store.Documents, util.SourceName(dataFile))
+}
+
+// readDataPoints reads the stored data points of a data file
+func readDataPoints(dataFile string) ([]util.DataPoint, error) {
+	return util.ReadDataset(dataStore, dataFile)
+}
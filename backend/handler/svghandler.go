@@ -5,16 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
-	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/Vinolia-E/BioTree/backend/store"
 	"github.com/Vinolia-E/BioTree/backend/svgchart"
 	"github.com/Vinolia-E/BioTree/backend/util"
 	"golang.org/x/time/rate"
@@ -26,14 +24,34 @@ const (
 	maxHeight     = 4096
 	defaultWidth  = 800
 	defaultHeight = 400
+	maxKeywords   = 500
 	cacheExpiry   = 1 * time.Hour
 )
 
 var (
 	validChartTypes = map[string]bool{
-		"line": true,
-		"bar":  true,
-		"pie":  true,
+		"line":      true,
+		"bar":       true,
+		"pie":       true,
+		"map":       true,
+		"tree":      true,
+		"treemap":   true,
+		"wordcloud": true,
+	}
+
+	validHierarchies = map[string]bool{
+		"taxonomy": true,
+		"outline":  true,
+	}
+
+	validProjections = map[string]svgchart.Projection{
+		"equirectangular": svgchart.Equirectangular,
+		"bbox":            svgchart.BoundingBox,
+	}
+
+	validEncodings = map[string]svgchart.Encoding{
+		"size":  svgchart.SizeEncoding,
+		"color": svgchart.ColorEncoding,
 	}
 
 	// Cache for generated SVGs
@@ -47,14 +65,23 @@ var (
 
 // ChartRequest represents the request payload for chart generation
 type ChartRequest struct {
-	DataFile  string `json:"dataFile"`
-	Unit      string `json:"unit,omitempty"`
-	ChartType string `json:"chartType"`
-	Title     string `json:"title,omitempty"`
-	XLabel    string `json:"xLabel,omitempty"`
-	YLabel    string `json:"yLabel,omitempty"`
-	Width     int    `json:"width,omitempty"`
-	Height    int    `json:"height,omitempty"`
+	DataFile   string `json:"dataFile"`
+	Unit       string `json:"unit,omitempty"`
+	Category   string `json:"category,omitempty"`   // chart every unit of a category together
+	Series     string `json:"series,omitempty"`     // keep only derived points of a series
+	Compliance string `json:"compliance,omitempty"` // jurisdiction or standard ID whose limits are drawn
+	Projection string `json:"projection,omitempty"` // map charts: equirectangular or bbox
+	Encoding   string `json:"encoding,omitempty"`   // map charts: size or color
+	Hierarchy  string `json:"hierarchy,omitempty"`  // tree charts: which hierarchy of the document to draw
+	Keywords   int    `json:"keywords,omitempty"`   // chart the top keywords of the document instead of its data points
+	Version    int    `json:"version,omitempty"`    // pin an extraction version of the dataset; 0 is the latest
+	Project    string `json:"project,omitempty"`    // chart every dataset of a project, or check dataFile belongs to it
+	ChartType  string `json:"chartType"`
+	Title      string `json:"title,omitempty"`
+	XLabel     string `json:"xLabel,omitempty"`
+	YLabel     string `json:"yLabel,omitempty"`
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
 }
 
 type cache struct {
@@ -69,14 +96,46 @@ type cacheItem struct {
 
 // validate checks if the chart request is valid
 func (r *ChartRequest) validate() error {
-	if r.DataFile == "" {
+	if r.DataFile == "" && r.Project == "" {
 		return errors.New("data_file is required")
 	}
 
+	if r.DataFile == "" && (r.ChartType == "tree" || r.ChartType == "treemap" || r.ChartType == "wordcloud" || r.Keywords > 0) {
+		return fmt.Errorf("%s charts need a data_file", r.ChartType)
+	}
+
+	if r.DataFile == "" && r.Version > 0 {
+		return errors.New("version can only be pinned for a data_file")
+	}
+
+	if r.Category != "" && !util.IsCategory(r.Category) {
+		return fmt.Errorf("invalid category: %s", r.Category)
+	}
+
 	if r.ChartType != "" && !validChartTypes[r.ChartType] {
 		return fmt.Errorf("invalid chart type: %s", r.ChartType)
 	}
 
+	if r.Hierarchy != "" && !validHierarchies[r.Hierarchy] {
+		return fmt.Errorf("invalid hierarchy: %s", r.Hierarchy)
+	}
+
+	if r.Version < 0 {
+		return errors.New("version must not be negative")
+	}
+
+	if r.Keywords < 0 || r.Keywords > maxKeywords {
+		return fmt.Errorf("keywords must be between 0 and %d", maxKeywords)
+	}
+
+	if _, ok := validProjections[r.Projection]; r.Projection != "" && !ok {
+		return fmt.Errorf("invalid projection: %s", r.Projection)
+	}
+
+	if _, ok := validEncodings[r.Encoding]; r.Encoding != "" && !ok {
+		return fmt.Errorf("invalid encoding: %s", r.Encoding)
+	}
+
 	if r.Width < 0 || r.Width > maxWidth {
 		return fmt.Errorf("width must be between 0 and %d", maxWidth)
 	}
@@ -120,7 +179,7 @@ func GenerateChartHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Received chart request: %+v", req)
 
-	if req.DataFile == "" {
+	if req.DataFile == "" && req.Project == "" {
 		log.Printf("Missing data_file in request: %+v", req)
 		util.RespondError(w, "data_file is required")
 		return
@@ -137,29 +196,58 @@ func GenerateChartHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject names that would reach outside the datasets
+	if (req.DataFile != "" && !store.ValidName(req.DataFile)) || (req.Project != "" && !store.ValidName(req.Project)) {
+		log.Println("Path traversal attempt detected")
+		util.RespondError(w, "Invalid file path")
+		return
+	}
+
+	// Charts of a project draw its datasets, and a data file must be one of them
+	var project *util.Project
+	if req.Project != "" {
+		var err error
+		project, err = util.LoadProject(dataStore, req.Project)
+		if err != nil {
+			log.Println("Failed to load project:", err)
+			util.RespondError(w, "Project not found")
+			return
+		}
+		if req.DataFile != "" && !project.Contains(req.DataFile) {
+			util.RespondError(w, "Data file is not part of the project")
+			return
+		}
+	}
+
 	// Check cache first (include unit in cache key)
-	cacheKey := fmt.Sprintf("%s-%s-%s-%d-%d", req.DataFile, req.Unit, req.ChartType, req.Width, req.Height)
+	cacheKey := fmt.Sprintf("%s-%s-%s-%s-%s-%s-%s-%s-%s-%d-%d-%d-%d", chartSource(req), req.Unit, req.Category, req.Series, req.Compliance,
+		req.ChartType, req.Projection, req.Encoding, req.Hierarchy, req.Keywords, req.Version, req.Width, req.Height)
 	svgCache.RLock()
 	if item, ok := svgCache.items[cacheKey]; ok {
 		if time.Since(item.createdAt) < cacheExpiry {
 			svgCache.RUnlock()
 			responseWithCompression(w, r, map[string]interface{}{
-				"status": "ok",
-				"svg":    item.svg,
-				"type":   req.ChartType,
-				"unit":   req.Unit,
-				"cached": true,
+				"status":   "ok",
+				"svg":      item.svg,
+				"type":     req.ChartType,
+				"unit":     req.Unit,
+				"category": req.Category,
+				"cached":   true,
 			})
 			return
 		}
 	}
 	svgCache.RUnlock()
 
-	// Construct the full path to the data file
-	dataPath := filepath.Clean(filepath.Join("data", req.DataFile))
-	if !strings.HasPrefix(dataPath, filepath.Clean("data/")) {
-		log.Println("Path traversal attempt detected")
-		util.RespondError(w, "Invalid file path")
+	// Tree charts are built from the source document rather than its data points
+	if req.ChartType == "tree" || req.ChartType == "treemap" {
+		generateHierarchyChart(w, r, req, cacheKey)
+		return
+	}
+
+	// Keyword charts rank the words of the document instead of its numbers
+	if req.ChartType == "wordcloud" || req.Keywords > 0 {
+		generateKeywordChart(w, r, req, cacheKey)
 		return
 	}
 
@@ -167,36 +255,54 @@ func GenerateChartHandler(w http.ResponseWriter, r *http.Request) {
 	var err error
 
 	// If unit is specified, filter data by unit
-	if req.Unit != "" {
-		// Use GetDataByUnitFromFile to filter data
-		filteredDataJSON, err := util.GetDataByUnitFromFile(dataPath, req.Unit)
+	if req.DataFile == "" {
+		// Chart the points of every dataset in the project
+		dataPoints, err = readProjectPoints(project, req.Unit)
 		if err != nil {
-			log.Printf("Failed to filter data by unit '%s': %v", req.Unit, err)
-			util.RespondError(w, "Failed to filter data by unit")
+			log.Println("Failed to read project datasets:", err)
+			util.RespondError(w, "Failed to read project datasets")
 			return
 		}
-
-		// Parse the filtered JSON data
-		if err := json.Unmarshal([]byte(filteredDataJSON), &dataPoints); err != nil {
-			log.Println("Failed to parse filtered JSON data:", err)
-			util.RespondError(w, "Invalid filtered data format")
+	} else if req.Version > 0 {
+		// Pinned versions are read whole from their snapshot
+		dataPoints, err = util.ReadDatasetVersion(dataStore, req.DataFile, req.Version)
+		if err != nil {
+			log.Printf("Failed to read version %d: %v", req.Version, err)
+			util.RespondError(w, fmt.Sprintf("Version %d not found", req.Version))
+			return
+		}
+		if req.Unit != "" {
+			dataPoints = filterByUnit(dataPoints, req.Unit)
+		}
+	} else if req.Unit != "" {
+		// Read only the points with the unit
+		dataPoints, err = util.ReadDatasetByUnit(dataStore, req.DataFile, req.Unit)
+		if err != nil {
+			log.Printf("Failed to filter data by unit '%s': %v", req.Unit, err)
+			util.RespondError(w, "Failed to filter data by unit")
 			return
 		}
 	} else {
-		// Read all data from the file
-		jsonData, err := os.ReadFile(dataPath)
+		// Read all data from the dataset
+		dataPoints, err = readDataPoints(req.DataFile)
 		if err != nil {
 			log.Println("Failed to read data file:", err)
 			util.RespondError(w, "Failed to read data file")
 			return
 		}
+	}
 
-		// Parse JSON data
-		if err := json.Unmarshal(jsonData, &dataPoints); err != nil {
-			log.Println("Failed to parse JSON data:", err)
-			util.RespondError(w, "Invalid data format")
-			return
-		}
+	// If a category is specified without a unit, chart all of its units together
+	if req.Category != "" && req.Unit == "" {
+		dataPoints = util.FilterByCategory(dataPoints, req.Category)
+	}
+
+	// If a series is specified, keep only the derived points of that series;
+	// otherwise charts of a unit or category only show measured points
+	if req.Series != "" {
+		dataPoints = filterBySeries(dataPoints, req.Series)
+	} else if req.Unit != "" || req.Category != "" {
+		dataPoints = util.MeasuredPoints(dataPoints)
 	}
 
 	// Check if we have data points
@@ -205,6 +311,12 @@ func GenerateChartHandler(w http.ResponseWriter, r *http.Request) {
 		if req.Unit != "" {
 			message = fmt.Sprintf("No data points found for unit '%s'", req.Unit)
 		}
+		if req.Category != "" && req.Unit == "" {
+			message = fmt.Sprintf("No data points found for category '%s'", req.Category)
+		}
+		if req.Series != "" {
+			message = fmt.Sprintf("No data points found for series '%s'", req.Series)
+		}
 		util.RespondError(w, message)
 		return
 	}
@@ -217,6 +329,8 @@ func GenerateChartHandler(w http.ResponseWriter, r *http.Request) {
 	} else if req.Unit != "" {
 		// Auto-generate title with unit if not provided
 		opts = append(opts, svgchart.WithTitle(fmt.Sprintf("Data for %s", req.Unit)))
+	} else if req.Category != "" {
+		opts = append(opts, svgchart.WithTitle(fmt.Sprintf("Data for %s units", req.Category)))
 	}
 
 	if req.XLabel != "" {
@@ -230,6 +344,22 @@ func GenerateChartHandler(w http.ResponseWriter, r *http.Request) {
 		opts = append(opts, svgchart.WithYLabel(req.Unit))
 	}
 
+	// Draw the applicable regulatory limits as reference lines
+	if req.Compliance != "" && req.Unit != "" {
+		for _, s := range util.CurrentStandardsCatalogue().Find(req.Unit, req.Compliance) {
+			label := fmt.Sprintf("%s %s limit (%s)", s.Jurisdiction, s.Parameter, s.AveragingPeriod)
+			opts = append(opts, svgchart.WithReferenceLine(s.Limit, label))
+		}
+	}
+
+	// Map charts can choose their projection and value encoding
+	if p, ok := validProjections[req.Projection]; ok {
+		opts = append(opts, svgchart.WithProjection(p))
+	}
+	if e, ok := validEncodings[req.Encoding]; ok {
+		opts = append(opts, svgchart.WithEncoding(e))
+	}
+
 	// Set dimensions (with defaults)
 	width := req.Width
 	if width == 0 {
@@ -250,9 +380,11 @@ func GenerateChartHandler(w http.ResponseWriter, r *http.Request) {
 		chartType = svgchart.Line
 	case "pie":
 		chartType = svgchart.Pie
+	case "map":
+		chartType = svgchart.Map
 	default:
 		log.Printf("Invalid chart type requested: %s", req.ChartType)
-		util.RespondError(w, fmt.Sprintf("Invalid chart type: %s. Valid types are: line, bar, pie", req.ChartType))
+		util.RespondError(w, fmt.Sprintf("Invalid chart type: %s. Valid types are: line, bar, pie, map, tree, treemap, wordcloud", req.ChartType))
 		return
 	}
 
@@ -280,6 +412,7 @@ func GenerateChartHandler(w http.ResponseWriter, r *http.Request) {
 		"svg":        svgContent,
 		"type":       req.ChartType,
 		"unit":       req.Unit,
+		"category":   req.Category,
 		"data_count": len(dataPoints),
 		"cached":     false,
 	})
@@ -310,19 +443,6 @@ func ProcessAndGenerateHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	// Create directories if they don't exist
-	if err := os.MkdirAll("files", os.ModePerm); err != nil {
-		log.Println("Failed to create files directory:", err)
-		util.RespondError(w, "Internal server error")
-		return
-	}
-
-	if err := os.MkdirAll("data", os.ModePerm); err != nil {
-		log.Println("Failed to create data directory:", err)
-		util.RespondError(w, "Internal server error")
-		return
-	}
-
 	// Parse multipart form with size limit
 	if err := r.ParseMultipartForm(maxFileSize); err != nil {
 		log.Println("Failed to parse form data:", err)
@@ -339,59 +459,187 @@ func ProcessAndGenerateHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	// Generate unique filename
-	filename := generateUniqueFilename(header.Filename)
-	inputPath := filepath.Join("files", filename)
-	outputPath := filepath.Join("data", filename+".json")
-
-	// Save uploaded file
-	if err := saveUploadedFile(file, inputPath); err != nil {
-		log.Println("Failed to save file:", err)
+	filename, release, err := generateUniqueFilename(header.Filename)
+	if err != nil {
+		log.Println("Failed to name upload:", err)
 		util.RespondError(w, "Failed to save file")
 		return
 	}
+	defer release()
 
-	// Process file through ParseDocumentToJSON
-	if err := util.ParseDocumentToJSON(inputPath, outputPath); err != nil {
-		log.Println("Failed to parse document:", err)
+	// Store and extract the upload, reusing an earlier upload of the same content
+	result, err := ingestUpload(file, header, filename, forceRequested(r))
+	if err != nil {
+		log.Println("Failed to process upload:", err)
 		util.RespondError(w, "Failed to parse document")
 		return
 	}
+	dataFile := result.DataFile
 
 	// Get units from the processed file
-	units, err := util.GetUnitsFromFile(outputPath)
+	units, err := util.GetUnitsFromDataset(dataStore, dataFile)
 	if err != nil {
 		log.Println("Failed to get units from processed file:", err)
 		util.RespondError(w, "Failed to extract units from processed data")
 		return
 	}
 
+	message := "Document processed successfully"
+	if result.Duplicate {
+		message = "Document was already processed, returning the existing dataset"
+	}
+
 	// Return success response with units and data file name
 	response := map[string]interface{}{
-		"status":    "ok",
-		"units":     units,
-		"data_file": filename + ".json",
-		"message":   "Document processed successfully",
+		"status":     "ok",
+		"units":      units,
+		"categories": util.GroupUnitsByCategory(units),
+		"data_file":  dataFile,
+		"quality":    result.Report,
+		"duplicate":  result.Duplicate,
+		"message":    message,
 	}
 
 	json.NewEncoder(w).Encode(response)
 }
 
 // Helper functions
-func generateUniqueFilename(originalName string) string {
+// filterByUnit keeps the points with unit
+func filterByUnit(points []util.DataPoint, unit string) []util.DataPoint {
+	var filtered []util.DataPoint
+	for _, dp := range points {
+		if dp.Unit == unit {
+			filtered = append(filtered, dp)
+		}
+	}
+	return filtered
+}
+
+// filterBySeries keeps the points whose series is series or nested under it,
+// so "aqi.us-epa" selects every pollutant of that standard
+func filterBySeries(points []util.DataPoint, series string) []util.DataPoint {
+	var filtered []util.DataPoint
+	for _, dp := range points {
+		if dp.Series == series || strings.HasPrefix(dp.Series, series+".") {
+			filtered = append(filtered, dp)
+		}
+	}
+	return filtered
+}
+
+// generateUniqueFilename names an upload after the original file and the
+// current time, adding a counter when that name is already taken. The name is
+// reserved until release is called, which must happen after the upload is
+// written.
+func generateUniqueFilename(originalName string) (name string, release func(), err error) {
 	timestamp := time.Now().Unix()
 	ext := filepath.Ext(originalName)
 	base := originalName[:len(originalName)-len(ext)]
-	return fmt.Sprintf("%s_%d%s", base, timestamp, ext)
+
+	return reserveSourceName(func(n int) string {
+		if n == 1 {
+			return fmt.Sprintf("%s_%d%s", base, timestamp, ext)
+		}
+		return fmt.Sprintf("%s_%d_%d%s", base, timestamp, n, ext)
+	})
 }
 
-func saveUploadedFile(src multipart.File, dst string) error {
-	out, err := os.Create(dst)
-	if err != nil {
-		return err
+var (
+	// namesMu guards reservedNames, the source names handed out to uploads
+	// and merges that are not written yet
+	namesMu       sync.Mutex
+	reservedNames = make(map[string]bool)
+)
+
+// reserveSourceName reserves the first of candidate(1), candidate(2), ...
+// that is neither reserved nor taken in the store
+func reserveSourceName(candidate func(n int) string) (string, func(), error) {
+	namesMu.Lock()
+	defer namesMu.Unlock()
+
+	for n := 1; ; n++ {
+		name := candidate(n)
+		if reservedNames[name] {
+			continue
+		}
+		taken, err := sourceNameTaken(name)
+		if err != nil {
+			return "", nil, err
+		}
+		if !taken {
+			reservedNames[name] = true
+			return name, func() {
+				namesMu.Lock()
+				delete(reservedNames, name)
+				namesMu.Unlock()
+			}, nil
+		}
 	}
-	defer out.Close()
+}
+
+// sourceNameTaken reports whether a document or a dataset already uses name
+// as its source name. Uploads and merged datasets share the dataset names, so
+// both kinds are checked.
+func sourceNameTaken(name string) (bool, error) {
+	for _, obj := range []struct {
+		kind store.Kind
+		name string
+	}{
+		{store.Documents, name},
+		{store.Datasets, name + ".json"},
+	} {
+		_, err := dataStore.Stat(obj.kind, obj.name)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// projectChartPrefix starts the cache keys of charts drawn from a whole project
+const projectChartPrefix = "project:"
 
-	_, err = io.Copy(out, src)
-	return err
+// chartSource names what a chart is drawn from at the start of its cache key
+func chartSource(req ChartRequest) string {
+	if req.DataFile == "" {
+		return projectChartPrefix + req.Project
+	}
+	return req.DataFile
+}
+
+// readProjectPoints reads the points of every dataset in a project, only those
+// with unit when it is set
+func readProjectPoints(project *util.Project, unit string) ([]util.DataPoint, error) {
+	var points []util.DataPoint
+	for _, dataset := range project.Datasets {
+		var dataPoints []util.DataPoint
+		var err error
+		if unit != "" {
+			dataPoints, err = util.ReadDatasetByUnit(dataStore, dataset, unit)
+		} else {
+			dataPoints, err = readDataPoints(dataset)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", dataset, err)
+		}
+		points = append(points, dataPoints...)
+	}
+	return points, nil
+}
+
+// invalidateCharts drops the cached charts of a data file, along with the
+// project charts that may include it
+func invalidateCharts(dataFile string) {
+	prefix := dataFile + "-"
+
+	svgCache.Lock()
+	defer svgCache.Unlock()
+	for key := range svgCache.items {
+		if strings.HasPrefix(key, prefix) || strings.HasPrefix(key, projectChartPrefix) {
+			delete(svgCache.items, key)
+		}
+	}
 }
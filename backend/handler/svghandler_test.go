@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+	"golang.org/x/time/rate"
+)
+
+func TestGenerateChartByCategory(t *testing.T) {
+	SetStore(store.NewMemory())
+	defer SetStore(store.NewLocal("."))
+	defer func(l *rate.Limiter) { limiter = l }(limiter)
+	limiter = rate.NewLimiter(rate.Inf, 1)
+
+	uploaded := uploadDocument(t, "survey.txt",
+		"Rainfall was 5 mm and the air reached 21 °C. PM2.5 was 40 µg/m³ and the river carried 4 NTU.", nil)
+
+	tests := []struct {
+		name      string
+		request   ChartRequest
+		wantCount int
+		wantError string
+	}{
+		{"climate", ChartRequest{Category: "climate"}, 2, ""},
+		{"air leaves out the AQI", ChartRequest{Category: "air"}, 1, ""},
+		{"unit wins over category", ChartRequest{Category: "climate", Unit: "mm"}, 1, ""},
+		{"category without data", ChartRequest{Category: "energy"}, 0, "No data points found for category 'energy'"},
+		{"unknown category", ChartRequest{Category: "space"}, 0, "invalid category: space"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.request.DataFile = uploaded.DataFile
+			tt.request.ChartType = "bar"
+			body, _ := json.Marshal(tt.request)
+
+			rec := httptest.NewRecorder()
+			GenerateChartHandler(rec, httptest.NewRequest(http.MethodPost, "/api/generate-chart", strings.NewReader(string(body))))
+
+			if tt.wantError != "" {
+				if !strings.Contains(rec.Body.String(), tt.wantError) {
+					t.Errorf("body = %s, want error %q", rec.Body, tt.wantError)
+				}
+				return
+			}
+
+			var chart struct {
+				Status    string `json:"status"`
+				SVG       string `json:"svg"`
+				Category  string `json:"category"`
+				DataCount int    `json:"data_count"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&chart); err != nil {
+				t.Fatal(err)
+			}
+			if chart.Status != "ok" || chart.DataCount != tt.wantCount || chart.Category != tt.request.Category {
+				t.Errorf("chart = %+v, want %d points of %s", chart, tt.wantCount, tt.request.Category)
+			}
+			if !strings.HasPrefix(strings.TrimSpace(chart.SVG), "<svg") {
+				t.Errorf("svg = %.60s", chart.SVG)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+// TaxaHandler returns the species named in the source document of a dataset,
+// resolved to their higher taxa, and the resulting taxonomy tree. A CSV
+// checklist can be posted as "checklist" to extend the reference list.
+func TaxaHandler(w http.ResponseWriter, r *http.Request) {
+	// Set CORS headers if needed
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	dataFile := r.URL.Query().Get("dataFile")
+	if dataFile == "" {
+		util.RespondError(w, "dataFile is required")
+		return
+	}
+
+	reference := util.CurrentTaxonReference()
+
+	if r.Method == http.MethodPost {
+		if err := r.ParseMultipartForm(maxFileSize); err != nil {
+			log.Println("Failed to parse form data:", err)
+			util.RespondError(w, "Failed to parse form data")
+			return
+		}
+
+		file, _, err := r.FormFile("checklist")
+		if err != nil {
+			log.Println("Failed to retrieve checklist from form data:", err)
+			util.RespondError(w, "Failed to retrieve checklist")
+			return
+		}
+		defer file.Close()
+
+		checklist, err := util.ParseChecklist(file)
+		if err != nil {
+			log.Println("Failed to parse checklist:", err)
+			util.RespondError(w, "Invalid checklist: "+err.Error())
+			return
+		}
+		reference = reference.With(checklist)
+	}
+
+	source, err := readSourceDocument(dataFile)
+	if err != nil {
+		log.Println("Failed to read source document:", err)
+		util.RespondError(w, "Source document not found for this data file")
+		return
+	}
+
+	species := util.FindSpecies(string(source), reference)
+
+	responseWithCompression(w, r, map[string]interface{}{
+		"status":    "ok",
+		"data_file": dataFile,
+		"species":   species,
+		"tree":      util.TaxonomyTree(species),
+	})
+}
@@ -1,11 +1,8 @@
 package handler
 
 import (
-	"io"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
 
 	"github.com/Vinolia-E/BioTree/backend/util"
 	"github.com/google/uuid"
@@ -14,18 +11,6 @@ import (
 func UploadHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if err := os.MkdirAll("files", os.ModePerm); err != nil {
-		log.Println("Failed to create files directory:", err)
-		util.RespondError(w, "Internal server error")
-		return
-	}
-
-	if err := os.MkdirAll("data", os.ModePerm); err != nil {
-		log.Println("Failed to create data directory:", err)
-		util.RespondError(w, "Internal server error")
-		return
-	}
-
 	// Parse multipart form (10MB max memory)
 	if err := r.ParseMultipartForm(10 << 20); err != nil {
 		log.Println("Failed to parse form data:", err)
@@ -34,7 +19,7 @@ func UploadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get file from form data
-	file, _, err := r.FormFile("document")
+	file, header, err := r.FormFile("document")
 	if err != nil {
 		log.Println("Failed to retrieve file from form data:", err)
 		util.RespondError(w, "Failed to retrieve file: "+err.Error())
@@ -42,45 +27,26 @@ func UploadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	// Generate unique filename
-	filename := uuid.New().String()
-	inputPath := filepath.Join("files", filename)
-	outputPath := filepath.Join("data", filename+".json")
-
-	if inputPath == "" || outputPath == "" {
-		log.Println("Invalid file paths")
-		util.RespondError(w, "Invalid file paths")
-		return
-	}
-
-	// Save uploaded file
-	dst, err := os.Create(inputPath)
+	// Store and extract the upload, reusing an earlier upload of the same content
+	result, err := ingestUpload(file, header, uuid.New().String(), forceRequested(r))
 	if err != nil {
-		log.Println("Failed to create destination file:", err)
-		util.RespondError(w, "Failed to create destination file: "+err.Error())
-		return
-	}
-	defer dst.Close()
-
-	if _, err := io.Copy(dst, file); err != nil {
-		log.Println("Failed to save file:", err)
-		util.RespondError(w, "Failed to save file: "+err.Error())
-		return
-	}
-
-	// Process file through ParseDocumentToJSON
-	if err := util.ParseDocumentToJSON(inputPath, outputPath); err != nil {
-		log.Println("Failed to parse document:", err)
+		log.Println("Failed to process upload:", err)
 		util.RespondError(w, "Failed to parse document")
 		return
 	}
+	dataFile := result.DataFile
 
-	units, err := util.GetUnitsFromFile(outputPath)
+	units, err := util.GetUnitsFromDataset(dataStore, dataFile)
 	if err != nil {
 		log.Println("Failed to get units from file:", err)
 		util.RespondError(w, "Failed to get units from file: "+err.Error())
 		return
 	}
 
-	util.RespondSuccess(w, units)
+	util.RespondSuccess(w, map[string]interface{}{
+		"units":      units,
+		"categories": util.GroupUnitsByCategory(units),
+		"data_file":  dataFile,
+		"duplicate":  result.Duplicate,
+	})
 }
@@ -30,11 +30,34 @@ func InitRoutes() *http.ServeMux {
 	r.HandleFunc("/api/data-files", handler.ListDataFilesHandler)
 	r.HandleFunc("/api/generate-chart", handler.GenerateChartHandler)
 	r.HandleFunc("/api/process-and-generate", handler.ProcessAndGenerateHandler)
+	r.HandleFunc("/api/extract/preview", handler.ExtractPreviewHandler)
+	r.HandleFunc("/api/annotated-source", handler.AnnotatedSourceHandler)
+	r.HandleFunc("/api/compliance", handler.ComplianceHandler)
+	r.HandleFunc("/api/standards", handler.StandardsHandler)
+	r.HandleFunc("/api/taxa", handler.TaxaHandler)
+	r.HandleFunc("/api/outline", handler.OutlineHandler)
+	r.HandleFunc("/api/keywords", handler.KeywordsHandler)
+	r.HandleFunc("/api/datasets/merge", handler.MergeHandler)
+	r.HandleFunc("/api/datasets/{id}", handler.DatasetHandler)
+	r.HandleFunc("/api/datasets/{id}/reextract", handler.ReextractHandler)
+	r.HandleFunc("/api/datasets/{id}/versions", handler.DatasetVersionsHandler)
+	r.HandleFunc("/api/datasets/{id}/points", handler.PointsHandler)
+	r.HandleFunc("/api/datasets/{id}/points/{point}", handler.PointHandler)
+	r.HandleFunc("/api/datasets/{id}/audit", handler.AuditHandler)
+	r.HandleFunc("/api/datasets/{id}/export", handler.ExportHandler)
+	r.HandleFunc("/api/search", handler.SearchHandler)
+	r.HandleFunc("/api/query", handler.QueryHandler)
+	r.HandleFunc("/api/admin/backup", handler.BackupHandler)
+	r.HandleFunc("/api/admin/restore", handler.RestoreHandler)
+	r.HandleFunc("/api/projects", handler.ProjectsHandler)
+	r.HandleFunc("/api/projects/{id}", handler.ProjectHandler)
+	r.HandleFunc("/api/projects/{id}/datasets", handler.ProjectDatasetsHandler)
+	r.HandleFunc("/api/projects/{id}/datasets/{dataset}", handler.ProjectDatasetHandler)
 	r.HandleFunc("/generate-chart", handler.GenerateChartHandler)
 	r.HandleFunc("/upload", handler.ProcessAndGenerateHandler)
 
-	// Serve files from the data directory
-	r.Handle("/data/", http.StripPrefix("/data/", http.FileServer(http.Dir("data"))))
+	// Serve datasets from the store
+	r.Handle("/data/", http.StripPrefix("/data/", http.HandlerFunc(handler.DatasetFileHandler)))
 
 	// Serve the about page
 	r.HandleFunc("/about", func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,368 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Buckets of the embedded database. Objects of every kind are kept in a
+// bucket of their own; datasets are also split into the indexed buckets below.
+var (
+	datasetsBucket = []byte("datasets") // dataset name → datasetRecord
+	pointsBucket   = []byte("points")   // dataset \x00 position → point JSON
+	unitsBucket    = []byte("units")    // unit \x00 dataset \x00 position → nothing
+	objectsPrefix  = "objects:"
+)
+
+// datasetRecord is the row kept for every dataset
+type datasetRecord struct {
+	Points   int            `json:"points"`
+	Units    map[string]int `json:"units"`
+	Size     int64          `json:"size"`
+	Modified time.Time      `json:"modified"`
+}
+
+// IndexedPoint is the JSON of a point and its position in its dataset
+type IndexedPoint struct {
+	Position int
+	Data     json.RawMessage
+}
+
+// PointIndex is implemented by stores that index the points of datasets, so
+// they can be read by unit without decoding the whole dataset
+type PointIndex interface {
+	// PointsByUnit returns the points of dataset with unit, in dataset order
+	PointsByUnit(dataset, unit string) ([]IndexedPoint, error)
+	// Units returns the units of dataset and how many points have each
+	Units(dataset string) (map[string]int, error)
+	// DatasetsWithUnit returns the datasets that have points with unit
+	DatasetsWithUnit(unit string) ([]string, error)
+}
+
+// Bolt is a Store backed by an embedded bbolt database file
+type Bolt struct {
+	db *bolt.DB
+}
+
+// OpenBolt opens or creates the database at path
+func OpenBolt(path string) (*Bolt, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{datasetsBucket, pointsBucket, unitsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating buckets: %w", err)
+	}
+
+	return &Bolt{db: db}, nil
+}
+
+// Close closes the database file
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
+
+func objectsBucket(kind Kind) []byte {
+	return []byte(objectsPrefix + string(kind))
+}
+
+// pointKey builds the key of the point at position in dataset
+func pointKey(dataset string, position int) []byte {
+	key := make([]byte, 0, len(dataset)+9)
+	key = append(key, dataset...)
+	key = append(key, 0)
+	return binary.BigEndian.AppendUint64(key, uint64(position))
+}
+
+func unitKey(unit, dataset string, position int) []byte {
+	key := make([]byte, 0, len(unit)+len(dataset)+10)
+	key = append(key, unit...)
+	key = append(key, 0)
+	return append(key, pointKey(dataset, position)...)
+}
+
+// Put stores an object. Datasets are also indexed point by point; a dataset
+// that is not a JSON array is stored but not indexed.
+func (b *Bolt) Put(kind Kind, name string, data []byte) error {
+	return b.PutModified(kind, name, data, time.Now())
+}
+
+// PutModified stores an object like Put, recording modified as the time a
+// dataset was written
+func (b *Bolt) PutModified(kind Kind, name string, data []byte, modified time.Time) error {
+	if !ValidName(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		objects, err := tx.CreateBucketIfNotExists(objectsBucket(kind))
+		if err != nil {
+			return err
+		}
+		if err := objects.Put([]byte(name), data); err != nil {
+			return err
+		}
+
+		if kind != Datasets {
+			return nil
+		}
+
+		if err := deleteIndexes(tx, name); err != nil {
+			return err
+		}
+		return indexDataset(tx, name, data, modified)
+	})
+}
+
+// indexDataset writes the dataset row and its point and unit index entries
+func indexDataset(tx *bolt.Tx, name string, data []byte, modified time.Time) error {
+	record := datasetRecord{
+		Units:    make(map[string]int),
+		Size:     int64(len(data)),
+		Modified: modified,
+	}
+
+	var points []json.RawMessage
+	if err := json.Unmarshal(data, &points); err == nil {
+		pointsB := tx.Bucket(pointsBucket)
+		unitsB := tx.Bucket(unitsBucket)
+		for i, raw := range points {
+			var p struct {
+				Unit string `json:"unit"`
+			}
+			if err := json.Unmarshal(raw, &p); err != nil {
+				continue
+			}
+			if err := pointsB.Put(pointKey(name, i), raw); err != nil {
+				return err
+			}
+			if err := unitsB.Put(unitKey(p.Unit, name, i), nil); err != nil {
+				return err
+			}
+			record.Units[p.Unit]++
+			record.Points++
+		}
+	}
+
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(datasetsBucket).Put([]byte(name), recordBytes)
+}
+
+// deleteIndexes removes the dataset row and every index entry of a dataset
+func deleteIndexes(tx *bolt.Tx, name string) error {
+	datasets := tx.Bucket(datasetsBucket)
+	recordBytes := datasets.Get([]byte(name))
+	if recordBytes == nil {
+		return nil
+	}
+
+	var record datasetRecord
+	if err := json.Unmarshal(recordBytes, &record); err != nil {
+		return err
+	}
+
+	prefix := append([]byte(name), 0)
+	if err := deletePrefix(tx.Bucket(pointsBucket), prefix); err != nil {
+		return err
+	}
+	for unit := range record.Units {
+		unitPrefix := append(append([]byte(unit), 0), prefix...)
+		if err := deletePrefix(tx.Bucket(unitsBucket), unitPrefix); err != nil {
+			return err
+		}
+	}
+
+	return datasets.Delete([]byte(name))
+}
+
+func deletePrefix(bucket *bolt.Bucket, prefix []byte) error {
+	c := bucket.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Seek(prefix) {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get reads an object
+func (b *Bolt) Get(kind Kind, name string) ([]byte, error) {
+	if !ValidName(name) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	var data []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		objects := tx.Bucket(objectsBucket(kind))
+		if objects == nil {
+			return fmt.Errorf("%s/%s: %w", kind, name, ErrNotFound)
+		}
+		v := objects.Get([]byte(name))
+		if v == nil {
+			return fmt.Errorf("%s/%s: %w", kind, name, ErrNotFound)
+		}
+		data = append([]byte(nil), v...)
+		return nil
+	})
+	return data, err
+}
+
+// Open returns a reader of a copy of an object, as its value is only valid
+// during the transaction it is read in
+func (b *Bolt) Open(kind Kind, name string) (io.ReadCloser, error) {
+	data, err := b.Get(kind, name)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Stat describes an object. Like List it only knows when datasets were written.
+func (b *Bolt) Stat(kind Kind, name string) (Object, error) {
+	if !ValidName(name) {
+		return Object{}, fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	obj := Object{Name: name}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		objects := tx.Bucket(objectsBucket(kind))
+		if objects == nil {
+			return fmt.Errorf("%s/%s: %w", kind, name, ErrNotFound)
+		}
+		v := objects.Get([]byte(name))
+		if v == nil {
+			return fmt.Errorf("%s/%s: %w", kind, name, ErrNotFound)
+		}
+		obj.Size = int64(len(v))
+		if kind == Datasets {
+			var record datasetRecord
+			if err := json.Unmarshal(tx.Bucket(datasetsBucket).Get([]byte(name)), &record); err == nil {
+				obj.Modified = record.Modified
+			}
+		}
+		return nil
+	})
+	return obj, err
+}
+
+// List returns the objects of a kind ordered by name, which is the order bbolt
+// keeps keys in. Only datasets record when they were written; other objects
+// report a zero modification time.
+func (b *Bolt) List(kind Kind) ([]Object, error) {
+	objects := []Object{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(objectsBucket(kind))
+		if bucket == nil {
+			return nil
+		}
+		datasets := tx.Bucket(datasetsBucket)
+		return bucket.ForEach(func(k, v []byte) error {
+			obj := Object{Name: string(k), Size: int64(len(v))}
+			if kind == Datasets {
+				var record datasetRecord
+				if err := json.Unmarshal(datasets.Get(k), &record); err == nil {
+					obj.Modified = record.Modified
+				}
+			}
+			objects = append(objects, obj)
+			return nil
+		})
+	})
+	return objects, err
+}
+
+// Delete removes an object and, for datasets, its indexes
+func (b *Bolt) Delete(kind Kind, name string) error {
+	if !ValidName(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if objects := tx.Bucket(objectsBucket(kind)); objects != nil {
+			if err := objects.Delete([]byte(name)); err != nil {
+				return err
+			}
+		}
+		if kind == Datasets {
+			return deleteIndexes(tx, name)
+		}
+		return nil
+	})
+}
+
+// PointsByUnit reads the points of dataset with unit from the unit index
+func (b *Bolt) PointsByUnit(dataset, unit string) ([]IndexedPoint, error) {
+	if !ValidName(dataset) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidName, dataset)
+	}
+
+	var points []IndexedPoint
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(datasetsBucket).Get([]byte(dataset)) == nil {
+			return fmt.Errorf("%s/%s: %w", Datasets, dataset, ErrNotFound)
+		}
+
+		pointsB := tx.Bucket(pointsBucket)
+		prefix := append(append([]byte(unit), 0), append([]byte(dataset), 0)...)
+		c := tx.Bucket(unitsBucket).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			key := k[len(unit)+1:]
+			points = append(points, IndexedPoint{
+				Position: int(binary.BigEndian.Uint64(key[len(key)-8:])),
+				Data:     append(json.RawMessage(nil), pointsB.Get(key)...),
+			})
+		}
+		return nil
+	})
+	return points, err
+}
+
+// Units returns the point count per unit recorded for dataset
+func (b *Bolt) Units(dataset string) (map[string]int, error) {
+	var record datasetRecord
+	err := b.db.View(func(tx *bolt.Tx) error {
+		recordBytes := tx.Bucket(datasetsBucket).Get([]byte(dataset))
+		if recordBytes == nil {
+			return fmt.Errorf("%s/%s: %w", Datasets, dataset, ErrNotFound)
+		}
+		return json.Unmarshal(recordBytes, &record)
+	})
+	return record.Units, err
+}
+
+// DatasetsWithUnit scans the unit index for the datasets that use unit
+func (b *Bolt) DatasetsWithUnit(unit string) ([]string, error) {
+	var datasets []string
+	err := b.db.View(func(tx *bolt.Tx) error {
+		prefix := append([]byte(unit), 0)
+		c := tx.Bucket(unitsBucket).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			rest := k[len(prefix):]
+			dataset := string(rest[:bytes.IndexByte(rest, 0)])
+			if len(datasets) == 0 || datasets[len(datasets)-1] != dataset {
+				datasets = append(datasets, dataset)
+			}
+		}
+		return nil
+	})
+	return datasets, err
+}
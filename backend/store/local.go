@@ -0,0 +1,164 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Local is a Store that keeps every kind in its own directory below a root
+// directory, so a root of "." gives the files/ and data/ layout
+type Local struct {
+	root string
+}
+
+// NewLocal returns a Store rooted at dir
+func NewLocal(dir string) *Local {
+	return &Local{root: dir}
+}
+
+// Root returns the directory the store is rooted at
+func (l *Local) Root() string {
+	return l.root
+}
+
+func (l *Local) path(kind Kind, name string) (string, error) {
+	if !ValidName(name) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	return filepath.Join(l.root, filepath.FromSlash(string(kind)), name), nil
+}
+
+// Put writes the object to a temporary file and renames it into place so
+// readers never see a partial object
+func (l *Local) Put(kind Kind, name string, data []byte) error {
+	path, err := l.path(kind, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("creating %s directory: %w", kind, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+name+".*")
+	if err != nil {
+		return fmt.Errorf("creating %s/%s: %w", kind, name, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing %s/%s: %w", kind, name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing %s/%s: %w", kind, name, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing %s/%s: %w", kind, name, err)
+	}
+	return nil
+}
+
+// PutModified writes an object and sets its modification time
+func (l *Local) PutModified(kind Kind, name string, data []byte, modified time.Time) error {
+	if err := l.Put(kind, name, data); err != nil {
+		return err
+	}
+	path, _ := l.path(kind, name)
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		return fmt.Errorf("writing %s/%s: %w", kind, name, err)
+	}
+	return nil
+}
+
+// Get reads an object
+func (l *Local) Get(kind Kind, name string) ([]byte, error) {
+	path, err := l.path(kind, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s/%s: %w", kind, name, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s/%s: %w", kind, name, err)
+	}
+	return data, nil
+}
+
+// Open opens the file of an object for reading
+func (l *Local) Open(kind Kind, name string) (io.ReadCloser, error) {
+	path, err := l.path(kind, name)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s/%s: %w", kind, name, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening %s/%s: %w", kind, name, err)
+	}
+	return file, nil
+}
+
+// Stat describes an object from its file
+func (l *Local) Stat(kind Kind, name string) (Object, error) {
+	path, err := l.path(kind, name)
+	if err != nil {
+		return Object{}, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return Object{}, fmt.Errorf("%s/%s: %w", kind, name, ErrNotFound)
+	}
+	if err != nil {
+		return Object{}, fmt.Errorf("reading %s/%s: %w", kind, name, err)
+	}
+	return Object{Name: name, Size: info.Size(), Modified: info.ModTime()}, nil
+}
+
+// List returns the regular files of a kind's directory, skipping the
+// directories of other kinds nested inside it and hidden temporary files
+func (l *Local) List(kind Kind) ([]Object, error) {
+	entries, err := os.ReadDir(filepath.Join(l.root, filepath.FromSlash(string(kind))))
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Object{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", kind, err)
+	}
+
+	objects := []Object{}
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name()[0] == '.' {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		objects = append(objects, Object{Name: entry.Name(), Size: info.Size(), Modified: info.ModTime()})
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects, nil
+}
+
+// Delete removes an object
+func (l *Local) Delete(kind Kind, name string) error {
+	path, err := l.path(kind, name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("deleting %s/%s: %w", kind, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Memory is a Store that keeps every object in memory, for tests
+type Memory struct {
+	mu      sync.RWMutex
+	objects map[Kind]map[string]memoryObject
+}
+
+type memoryObject struct {
+	data     []byte
+	modified time.Time
+}
+
+// NewMemory returns an empty in-memory Store
+func NewMemory() *Memory {
+	return &Memory{objects: make(map[Kind]map[string]memoryObject)}
+}
+
+// Put stores a copy of data
+func (m *Memory) Put(kind Kind, name string, data []byte) error {
+	return m.PutModified(kind, name, data, time.Now())
+}
+
+// PutModified stores a copy of data written at modified
+func (m *Memory) PutModified(kind Kind, name string, data []byte, modified time.Time) error {
+	if !ValidName(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.objects[kind] == nil {
+		m.objects[kind] = make(map[string]memoryObject)
+	}
+	m.objects[kind][name] = memoryObject{
+		data:     append([]byte(nil), data...),
+		modified: modified,
+	}
+	return nil
+}
+
+// Get returns a copy of an object
+func (m *Memory) Get(kind Kind, name string) ([]byte, error) {
+	if !ValidName(name) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	obj, ok := m.objects[kind][name]
+	if !ok {
+		return nil, fmt.Errorf("%s/%s: %w", kind, name, ErrNotFound)
+	}
+	return append([]byte(nil), obj.data...), nil
+}
+
+// Open returns a reader of an object. Stored data is never changed in
+// place, so the reader needs no copy.
+func (m *Memory) Open(kind Kind, name string) (io.ReadCloser, error) {
+	if !ValidName(name) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	obj, ok := m.objects[kind][name]
+	if !ok {
+		return nil, fmt.Errorf("%s/%s: %w", kind, name, ErrNotFound)
+	}
+	return io.NopCloser(bytes.NewReader(obj.data)), nil
+}
+
+// Stat describes an object
+func (m *Memory) Stat(kind Kind, name string) (Object, error) {
+	if !ValidName(name) {
+		return Object{}, fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	obj, ok := m.objects[kind][name]
+	if !ok {
+		return Object{}, fmt.Errorf("%s/%s: %w", kind, name, ErrNotFound)
+	}
+	return Object{Name: name, Size: int64(len(obj.data)), Modified: obj.modified}, nil
+}
+
+// List returns the objects of a kind ordered by name
+func (m *Memory) List(kind Kind) ([]Object, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	objects := []Object{}
+	for name, obj := range m.objects[kind] {
+		objects = append(objects, Object{Name: name, Size: int64(len(obj.data)), Modified: obj.modified})
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects, nil
+}
+
+// Delete removes an object
+func (m *Memory) Delete(kind Kind, name string) error {
+	if !ValidName(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.objects[kind], name)
+	return nil
+}
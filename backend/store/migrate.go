@@ -0,0 +1,50 @@
+package store
+
+import "fmt"
+
+// Kinds lists every kind of object a Store holds
+var Kinds = []Kind{Documents, Datasets, Reports, Keywords, Meta, Hashes, Versions, Audit, Projects, Index, Search}
+
+// Migrate copies every object of from that to does not have yet and returns
+// the number of objects copied per kind. Objects already in to are left alone,
+// so an interrupted migration can simply be run again. Copies keep the
+// modification time of the original when to is a TimedStore.
+func Migrate(from, to Store) (map[Kind]int, error) {
+	copied := make(map[Kind]int)
+
+	for _, kind := range Kinds {
+		existing, err := to.List(kind)
+		if err != nil {
+			return copied, err
+		}
+		have := make(map[string]bool, len(existing))
+		for _, obj := range existing {
+			have[obj.Name] = true
+		}
+
+		objects, err := from.List(kind)
+		if err != nil {
+			return copied, err
+		}
+		for _, obj := range objects {
+			if have[obj.Name] {
+				continue
+			}
+			data, err := from.Get(kind, obj.Name)
+			if err != nil {
+				return copied, fmt.Errorf("migrating %s/%s: %w", kind, obj.Name, err)
+			}
+			if timed, ok := to.(TimedStore); ok {
+				err = timed.PutModified(kind, obj.Name, data, obj.Modified)
+			} else {
+				err = to.Put(kind, obj.Name, data)
+			}
+			if err != nil {
+				return copied, fmt.Errorf("migrating %s/%s: %w", kind, obj.Name, err)
+			}
+			copied[kind]++
+		}
+	}
+
+	return copied, nil
+}
@@ -0,0 +1,85 @@
+// Package store keeps uploaded documents, the datasets extracted from them
+// and the sidecar records that describe each dataset.
+package store
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// Kind is a class of object held by a Store
+type Kind string
+
+const (
+	// Documents are uploaded source documents
+	Documents Kind = "files"
+	// Datasets are the JSON data points extracted from a document
+	Datasets Kind = "data"
+	// Reports are the extraction quality reports of datasets
+	Reports Kind = "data/reports"
+	// Keywords are the term frequencies of datasets
+	Keywords Kind = "data/keywords"
+	// Meta are the metadata records of datasets
+	Meta Kind = "data/meta"
+	// Hashes map the SHA-256 of an uploaded document to its dataset
+	Hashes Kind = "data/hashes"
+	// Versions are the immutable extractions of datasets, named
+	// <dataset>@<version>, and the list of each dataset's versions, named
+	// after the dataset
+	Versions Kind = "data/versions"
+	// Audit are the trails of manual corrections made to datasets
+	Audit Kind = "data/audit"
+	// Projects are named collections of datasets
+	Projects Kind = "data/projects"
+	// Index holds the listing index of datasets
+	Index Kind = "data/index"
+	// Search holds the full-text index of the uploaded documents
+	Search Kind = "data/search"
+)
+
+var (
+	// ErrNotFound is returned when an object does not exist
+	ErrNotFound = errors.New("object not found")
+	// ErrInvalidName is returned for names that could escape their kind,
+	// such as "../secret" or names containing a path separator
+	ErrInvalidName = errors.New("invalid object name")
+)
+
+// Object describes a stored object
+type Object struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// Store holds objects by kind and name
+type Store interface {
+	// Put creates or replaces an object
+	Put(kind Kind, name string, data []byte) error
+	// Get returns the contents of an object or ErrNotFound
+	Get(kind Kind, name string) ([]byte, error)
+	// Open returns a reader of the contents of an object or ErrNotFound;
+	// the caller closes it
+	Open(kind Kind, name string) (io.ReadCloser, error)
+	// Stat describes an object without reading it, or returns ErrNotFound
+	Stat(kind Kind, name string) (Object, error)
+	// List returns the objects of a kind ordered by name
+	List(kind Kind) ([]Object, error)
+	// Delete removes an object; deleting a missing object is not an error
+	Delete(kind Kind, name string) error
+}
+
+// TimedStore is implemented by stores that can write an object with a given
+// modification time, so a copied object keeps the time of the original
+type TimedStore interface {
+	// PutModified creates or replaces an object written at modified
+	PutModified(kind Kind, name string, data []byte, modified time.Time) error
+}
+
+// ValidName reports whether name can be used as an object name
+func ValidName(name string) bool {
+	return name != "" && name != "." && name != ".." &&
+		!strings.ContainsAny(name, `/\`) && !strings.ContainsRune(name, 0)
+}
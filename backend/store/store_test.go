@@ -0,0 +1,186 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func openTestBolt(t *testing.T) *Bolt {
+	b, err := OpenBolt(filepath.Join(t.TempDir(), "biotree.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+func TestStores(t *testing.T) {
+	stores := []struct {
+		name  string
+		store Store
+	}{
+		{name: "Local", store: NewLocal(t.TempDir())},
+		{name: "Memory", store: NewMemory()},
+		{name: "Bolt", store: openTestBolt(t)},
+	}
+
+	for _, tt := range stores {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.store
+
+			if _, err := s.Get(Datasets, "missing.json"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() missing error = %v, want ErrNotFound", err)
+			}
+
+			if err := s.Put(Datasets, "b.json", []byte("[]")); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			if err := s.Put(Datasets, "a.json", []byte(`[{"value":1}]`)); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			if err := s.Put(Reports, "a.json", []byte("{}")); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+
+			got, err := s.Get(Datasets, "a.json")
+			if err != nil || string(got) != `[{"value":1}]` {
+				t.Errorf("Get() = %q, %v", got, err)
+			}
+
+			if r, err := s.Open(Datasets, "a.json"); err != nil {
+				t.Errorf("Open() error = %v", err)
+			} else {
+				opened, err := io.ReadAll(r)
+				r.Close()
+				if err != nil || string(opened) != `[{"value":1}]` {
+					t.Errorf("Open() read %q, %v", opened, err)
+				}
+			}
+			if _, err := s.Open(Datasets, "missing.json"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Open() missing error = %v, want ErrNotFound", err)
+			}
+
+			if obj, err := s.Stat(Datasets, "a.json"); err != nil || obj.Name != "a.json" || obj.Size != 13 {
+				t.Errorf("Stat() = %+v, %v", obj, err)
+			}
+			if _, err := s.Stat(Datasets, "missing.json"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Stat() missing error = %v, want ErrNotFound", err)
+			}
+
+			objects, err := s.List(Datasets)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(objects) != 2 || objects[0].Name != "a.json" || objects[1].Name != "b.json" || objects[0].Size != 13 {
+				t.Errorf("List() = %+v, want a.json and b.json only", objects)
+			}
+
+			if err := s.Delete(Datasets, "a.json"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if err := s.Delete(Datasets, "a.json"); err != nil {
+				t.Errorf("Delete() of a missing object error = %v", err)
+			}
+			if _, err := s.Get(Datasets, "a.json"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() after Delete() error = %v, want ErrNotFound", err)
+			}
+
+			written := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+			if err := s.(TimedStore).PutModified(Datasets, "c.json", []byte("[]"), written); err != nil {
+				t.Fatalf("PutModified() error = %v", err)
+			}
+			if objects, _ := s.List(Datasets); len(objects) != 2 || !objects[1].Modified.Equal(written) {
+				t.Errorf("List() after PutModified() = %+v, want c.json modified %v", objects, written)
+			}
+
+			for _, name := range []string{"", "..", "../files/x", `a\b`} {
+				if err := s.Put(Documents, name, nil); !errors.Is(err, ErrInvalidName) {
+					t.Errorf("Put(%q) error = %v, want ErrInvalidName", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestBoltPointIndex(t *testing.T) {
+	b := openTestBolt(t)
+
+	put := func(name, data string) {
+		if err := b.Put(Datasets, name, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("a.json", `[{"value":1,"unit":"mm"},{"value":2,"unit":"°C"},{"value":3,"unit":"mm"}]`)
+	put("b.json", `[{"value":4,"unit":"°C"}]`)
+
+	points, err := b.PointsByUnit("a.json", "mm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []float64
+	var positions []int
+	for _, point := range points {
+		var p struct{ Value float64 }
+		json.Unmarshal(point.Data, &p)
+		values = append(values, p.Value)
+		positions = append(positions, point.Position)
+	}
+	if !reflect.DeepEqual(values, []float64{1, 3}) || !reflect.DeepEqual(positions, []int{0, 2}) {
+		t.Errorf("PointsByUnit() values = %v at %v, want [1 3] at [0 2]", values, positions)
+	}
+
+	if units, _ := b.Units("a.json"); !reflect.DeepEqual(units, map[string]int{"mm": 2, "°C": 1}) {
+		t.Errorf("Units() = %v", units)
+	}
+	if datasets, _ := b.DatasetsWithUnit("°C"); !reflect.DeepEqual(datasets, []string{"a.json", "b.json"}) {
+		t.Errorf("DatasetsWithUnit() = %v", datasets)
+	}
+
+	// Replacing and deleting datasets keeps the indexes in step
+	put("a.json", `[{"value":5,"unit":"mm"}]`)
+	if datasets, _ := b.DatasetsWithUnit("°C"); !reflect.DeepEqual(datasets, []string{"b.json"}) {
+		t.Errorf("DatasetsWithUnit() after replace = %v", datasets)
+	}
+	if err := b.Delete(Datasets, "b.json"); err != nil {
+		t.Fatal(err)
+	}
+	if datasets, _ := b.DatasetsWithUnit("°C"); len(datasets) != 0 {
+		t.Errorf("DatasetsWithUnit() after delete = %v", datasets)
+	}
+	if _, err := b.PointsByUnit("b.json", "°C"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("PointsByUnit() of a deleted dataset error = %v", err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	written := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	from := NewMemory()
+	from.Put(Documents, "a.txt", []byte("5 mm"))
+	from.PutModified(Datasets, "a.txt.json", []byte(`[{"value":5,"unit":"mm"}]`), written)
+	from.Put(Meta, "a.txt.json", []byte(`{}`))
+
+	to := openTestBolt(t)
+	to.Put(Documents, "a.txt", []byte("already there"))
+
+	copied, err := Migrate(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[Kind]int{Datasets: 1, Meta: 1}; !reflect.DeepEqual(copied, want) {
+		t.Errorf("Migrate() copied %v, want %v", copied, want)
+	}
+	if doc, _ := to.Get(Documents, "a.txt"); string(doc) != "already there" {
+		t.Errorf("Migrate() overwrote an existing object with %q", doc)
+	}
+	if units, _ := to.Units("a.txt.json"); units["mm"] != 1 {
+		t.Errorf("Migrate() did not index the migrated dataset: %v", units)
+	}
+	if datasets, _ := to.List(Datasets); len(datasets) != 1 || !datasets[0].Modified.Equal(written) {
+		t.Errorf("Migrate() did not keep the modification time: %+v", datasets)
+	}
+}
@@ -2,7 +2,6 @@ package svgchart
 
 import (
 	"fmt"
-	"math"
 )
 
 // barChart implements the Chart interface for bar charts
@@ -24,26 +23,15 @@ func newBarChart(data ChartData, options Options) Chart {
 		padding: 40,
 	}
 
-	// Calculate min and max values
-	bc.minValue = math.MaxFloat64
-	bc.maxValue = -math.MaxFloat64
-	for _, d := range data {
-		if d.Value < bc.minValue {
-			bc.minValue = d.Value
-		}
-		if d.Value > bc.maxValue {
-			bc.maxValue = d.Value
-		}
-	}
-
-	// Add some padding to min/max
-	valueRange := bc.maxValue - bc.minValue
-	bc.minValue = math.Max(0, bc.minValue-valueRange*0.1) // Don't go below 0 for bar charts
-	bc.maxValue += valueRange * 0.1
+	// Calculate min and max values with some padding, including any
+	// reference lines. Don't go below 0 for bar charts.
+	bc.minValue, bc.maxValue = valueRange(data, options.ReferenceLines, true)
 
 	// Calculate bar width
-	graphWidth := float64(options.Width - (bc.padding * 2))
-	bc.barWidth = (graphWidth / float64(len(data))) * 0.8 // Leave 20% gap between bars
+	if len(data) > 0 {
+		graphWidth := float64(options.Width - (bc.padding * 2))
+		bc.barWidth = (graphWidth / float64(len(data))) * 0.8 // Leave 20% gap between bars
+	}
 
 	return bc
 }
@@ -51,7 +39,7 @@ func newBarChart(data ChartData, options Options) Chart {
 // Generate produces the SVG string for the bar chart
 func (b *barChart) Generate() string {
 	if len(b.data) == 0 {
-		return ""
+		return generateEmptyChart(b.options, "No data available")
 	}
 
 	width := b.options.Width
@@ -59,22 +47,30 @@ func (b *barChart) Generate() string {
 	graphWidth := float64(width - (b.padding * 2))
 	graphHeight := float64(height - (b.padding * 2))
 
+	yFor := func(value float64) float64 {
+		return float64(height-b.padding) - (value-b.minValue)/(b.maxValue-b.minValue)*graphHeight
+	}
+
 	// Create SVG with styles
+	gridStyle := ""
+	if b.options.ShowGrid {
+		gridStyle = ".grid { stroke: #ccc; stroke-width: 0.5; stroke-dasharray: 4 2; }"
+	}
 	svg := fmt.Sprintf(`<svg width="%d" height="%d" xmlns="http://www.w3.org/2000/svg">
 		<style>
 			.axis { stroke: #333; stroke-width: 1; }
-			.grid { stroke: #ccc; stroke-width: 0.5; stroke-dasharray: 4 2; }
+			%s
 			.label { font-family: Arial; font-size: 12px; }
 			.title { font-family: Arial; font-size: 16px; font-weight: bold; }
 			.bar { fill: #4285f4; transition: fill 0.3s; }
 			.bar:hover { fill: #2962ff; }
 			.value-label { font-family: Arial; font-size: 12px; fill: #333; }
-		</style>`, width, height)
+		</style>`, width, height, gridStyle)
 
 	// Add title if present
 	if b.options.Title != "" {
 		svg += fmt.Sprintf(`<text x="%d" y="%d" text-anchor="middle" class="title">%s</text>`,
-			width/2, b.padding/2, b.options.Title)
+			width/2, b.padding/2, escapeText(b.options.Title))
 	}
 
 	// Draw axes
@@ -90,8 +86,10 @@ func (b *barChart) Generate() string {
 		value := b.maxValue - ((b.maxValue - b.minValue) * float64(i) / float64(numGridLines))
 
 		// Grid line
-		svg += fmt.Sprintf(`<line x1="%d" y1="%f" x2="%d" y2="%f" class="grid"/>`,
-			b.padding, y, width-b.padding, y)
+		if b.options.ShowGrid {
+			svg += fmt.Sprintf(`<line x1="%d" y1="%f" x2="%d" y2="%f" class="grid"/>`,
+				b.padding, y, width-b.padding, y)
+		}
 
 		// Y-axis label
 		svg += fmt.Sprintf(`<text x="%d" y="%f" text-anchor="end" alignment-baseline="middle" class="label">%.1f</text>`,
@@ -103,15 +101,23 @@ func (b *barChart) Generate() string {
 	for i, d := range b.data {
 		// Calculate bar position and height
 		x := float64(b.padding) + spacing + (float64(i) * (b.barWidth + spacing))
-		heightRatio := (d.Value - b.minValue) / (b.maxValue - b.minValue)
-		barHeight := heightRatio * float64(graphHeight)
-		y := float64(height-b.padding) - barHeight
-
-		// Draw bar
-		svg += fmt.Sprintf(`<rect x="%f" y="%f" width="%f" height="%f" class="bar">
+		y := yFor(d.Value)
+		barHeight := float64(height-b.padding) - y
+
+		// Draw bar, using the point's own colour when it has one and
+		// highlighting values above a reference line
+		fill, class := "", "bar"
+		if d.Color != "" {
+			fill = fmt.Sprintf(` style="fill: %s"`, escapeText(d.Color))
+		}
+		if color, ok := exceedanceColor(d.Value, b.options.ReferenceLines); ok {
+			fill = fmt.Sprintf(` style="fill: %s"`, color)
+			class = "bar exceedance"
+		}
+		svg += fmt.Sprintf(`<rect x="%f" y="%f" width="%f" height="%f" class="%s"%s>
 			<title>%s: %.2f</title>
 		</rect>`,
-			x, y, b.barWidth, barHeight, d.Label, d.Value)
+			x, y, b.barWidth, barHeight, class, fill, escapeText(d.Label), d.Value)
 
 		// Add value label on top of bar
 		svg += fmt.Sprintf(`<text x="%f" y="%f" text-anchor="middle" class="value-label">%.1f</text>`,
@@ -119,9 +125,15 @@ func (b *barChart) Generate() string {
 
 		// X-axis label
 		svg += fmt.Sprintf(`<text x="%f" y="%d" text-anchor="middle" transform="rotate(45 %f,%d)" class="label">%s</text>`,
-			x+b.barWidth/2, height-b.padding+5, x+b.barWidth/2, height-b.padding+5, d.Label)
+			x+b.barWidth/2, height-b.padding+5, x+b.barWidth/2, height-b.padding+5, escapeText(d.Label))
 	}
 
+	// Draw reference lines over the bars
+	svg += referenceLinesSVG(b.options.ReferenceLines, b.padding, width-b.padding, yFor)
+
+	// Add axis labels if present
+	svg += axisLabelsSVG(b.options)
+
 	// Close SVG
 	svg += "</svg>"
 
@@ -5,9 +5,13 @@ import "fmt"
 type ChartType string
 
 const (
-	Line ChartType = "line"
-	Bar  ChartType = "bar"
-	Pie  ChartType = "pie"
+	Line      ChartType = "line"
+	Bar       ChartType = "bar"
+	Pie       ChartType = "pie"
+	Map       ChartType = "map"
+	Tree      ChartType = "tree"
+	Treemap   ChartType = "treemap"
+	WordCloud ChartType = "wordcloud"
 )
 
 type Chart interface {
@@ -16,16 +20,28 @@ type Chart interface {
 
 // New creates a new chart based on the specified type
 func New(data interface{}, chartType ChartType, opts ...Option) (Chart, error) {
-	chartData, err := ConvertData(data)
-	if err != nil {
-		return nil, err
-	}
-
 	options := DefaultOptions()
 	for _, opt := range opts {
 		opt(&options)
 	}
 
+	// Hierarchical charts take tree data instead of a flat list of points
+	if chartType == Tree || chartType == Treemap {
+		root, err := ConvertTree(data)
+		if err != nil {
+			return nil, err
+		}
+		if chartType == Treemap {
+			return newTreemapChart(root, options), nil
+		}
+		return newTreeChart(root, options), nil
+	}
+
+	chartData, err := ConvertData(data)
+	if err != nil {
+		return nil, err
+	}
+
 	switch chartType {
 	case Bar:
 		return newBarChart(chartData, options), nil
@@ -33,6 +49,10 @@ func New(data interface{}, chartType ChartType, opts ...Option) (Chart, error) {
 		return newPieChart(chartData, options), nil
 	case Line:
 		return newLineChart(chartData, options), nil
+	case Map:
+		return newMapChart(chartData, options), nil
+	case WordCloud:
+		return newWordCloud(chartData, options), nil
 	default:
 		return nil, fmt.Errorf("unsupported chart type: %s", chartType)
 	}
@@ -1,6 +1,8 @@
 package svgchart
 
 import (
+	"encoding/xml"
+	"io"
 	"strings"
 	"testing"
 
@@ -85,3 +87,45 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+func TestChartsEscapeText(t *testing.T) {
+	const hostile = `<img src=x onerror="alert(1)"> & co`
+	points := []util.DataPoint{
+		{Unit: "mm", Value: 1, Label: hostile, Location: &util.Coordinate{Lat: -1.29, Lon: 36.82}},
+		{Unit: "mm", Value: 2, Label: "Rain & snow", Location: &util.Coordinate{Lat: -1.3, Lon: 36.9}},
+	}
+	tree := &util.TreeNode{Name: hostile, Count: 2, Children: []*util.TreeNode{{Name: "a < b", Count: 2}}}
+	opts := []Option{WithTitle(hostile), WithXLabel(hostile), WithYLabel(hostile), WithReferenceLine(1.5, hostile)}
+
+	for _, tt := range []struct {
+		chartType ChartType
+		data      interface{}
+	}{
+		{Bar, points},
+		{Line, points},
+		{Pie, points},
+		{Map, points},
+		{WordCloud, points},
+		{Tree, tree},
+		{Treemap, tree},
+	} {
+		t.Run(string(tt.chartType), func(t *testing.T) {
+			chart, err := New(tt.data, tt.chartType, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			svg := chart.Generate()
+			if strings.Contains(svg, "<img") {
+				t.Errorf("SVG contains unescaped markup: %s", svg)
+			}
+			dec := xml.NewDecoder(strings.NewReader(svg))
+			for {
+				if _, err := dec.Token(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("SVG is not well-formed XML: %v\n%s", err, svg)
+				}
+			}
+		})
+	}
+}
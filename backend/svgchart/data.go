@@ -13,6 +13,15 @@ type DataPoint struct {
 	Label string  `json:"label"`
 	Value float64 `json:"value"`
 	Unit  string  `json:"unit,omitempty"`
+	Color string  `json:"color,omitempty"`
+	// Location places the point on a map chart
+	Location *Location `json:"location,omitempty"`
+}
+
+// Location is a latitude and longitude in decimal degrees
+type Location struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
 }
 
 // ChartData represents the data to be visualized
@@ -20,6 +29,8 @@ type ChartData []DataPoint
 
 func ConvertData(data interface{}) (ChartData, error) {
 	switch d := data.(type) {
+	case ChartData:
+		return d, nil
 	case map[string]float64:
 		return mapToDataPoints(d), nil
 	case []util.DataPoint:
@@ -36,10 +47,18 @@ func ConvertData(data interface{}) (ChartData, error) {
 func convertUtilDataPoints(data []util.DataPoint) ChartData {
 	result := make(ChartData, len(data))
 	for i, d := range data {
+		label := fmt.Sprintf("%.2f %s", d.Value, d.Unit)
+		if d.Label != "" {
+			label = d.Label
+		}
 		result[i] = DataPoint{
-			Label: fmt.Sprintf("%.2f %s", d.Value, d.Unit),
+			Label: label,
 			Value: d.Value,
 			Unit:  d.Unit,
+			Color: d.Color,
+		}
+		if d.Location != nil {
+			result[i].Location = &Location{Lat: d.Location.Lat, Lon: d.Location.Lon}
 		}
 	}
 	return result
@@ -82,6 +101,8 @@ func sliceToDataPoints(data [][]interface{}) (ChartData, error) {
 		switch v := row[1].(type) {
 		case float64:
 			value = v
+		case int:
+			value = float64(v)
 		case string:
 			var err error
 			value, err = strconv.ParseFloat(v, 64)
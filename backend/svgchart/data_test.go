@@ -25,6 +25,12 @@ func TestDataConversion(t *testing.T) {
 			want:    2,
 			wantErr: false,
 		},
+		{
+			name:    "Chart data",
+			data:    ChartData{{Label: "A", Value: 1.0}, {Label: "B", Value: 2.0}},
+			want:    2,
+			wantErr: false,
+		},
 		{
 			name:    "Point slice",
 			data:    []Point{{X: "A", Y: 1.0}, {X: "B", Y: 2.0}},
@@ -2,7 +2,6 @@ package svgchart
 
 import (
 	"fmt"
-	"math"
 	"strings"
 )
 
@@ -23,22 +22,9 @@ func newLineChart(data ChartData, options Options) Chart {
 		padding: 40,
 	}
 
-	// Calculate min and max values
-	lc.minValue = math.MaxFloat64
-	lc.maxValue = -math.MaxFloat64
-	for _, d := range data {
-		if d.Value < lc.minValue {
-			lc.minValue = d.Value
-		}
-		if d.Value > lc.maxValue {
-			lc.maxValue = d.Value
-		}
-	}
-
-	// Add some padding to min/max
-	valueRange := lc.maxValue - lc.minValue
-	lc.minValue -= valueRange * 0.1
-	lc.maxValue += valueRange * 0.1
+	// Calculate min and max values with some padding, including any
+	// reference lines
+	lc.minValue, lc.maxValue = valueRange(data, options.ReferenceLines, false)
 
 	return lc
 }
@@ -46,7 +32,7 @@ func newLineChart(data ChartData, options Options) Chart {
 // Generate produces the SVG string for the line chart
 func (lc *lineChart) Generate() string {
 	if len(lc.data) == 0 {
-		return ""
+		return generateEmptyChart(lc.options, "No data available")
 	}
 
 	width := lc.options.Width
@@ -54,22 +40,30 @@ func (lc *lineChart) Generate() string {
 	graphWidth := float64(width - (lc.padding * 2))
 	graphHeight := float64(height - (lc.padding * 2))
 
+	yFor := func(value float64) float64 {
+		return float64(height-lc.padding) - (value-lc.minValue)/(lc.maxValue-lc.minValue)*graphHeight
+	}
+
 	// Create SVG with styles
+	gridStyle := ""
+	if lc.options.ShowGrid {
+		gridStyle = ".grid { stroke: #ccc; stroke-width: 0.5; stroke-dasharray: 4 2; }"
+	}
 	svg := fmt.Sprintf(`<svg width="%d" height="%d" xmlns="http://www.w3.org/2000/svg">
 		<style>
 			.axis { stroke: #333; stroke-width: 1; }
-			.grid { stroke: #ccc; stroke-width: 0.5; stroke-dasharray: 4 2; }
+			%s
 			.label { font-family: Arial; font-size: 12px; }
 			.title { font-family: Arial; font-size: 16px; font-weight: bold; }
 			.data-point { fill: #4285f4; }
 			.data-line { stroke: #4285f4; stroke-width: 2; fill: none; }
 			.data-point:hover { fill: #2962ff; r: 6; }
-		</style>`, width, height)
+		</style>`, width, height, gridStyle)
 
 	// Add title if present
 	if lc.options.Title != "" {
 		svg += fmt.Sprintf(`<text x="%d" y="%d" text-anchor="middle" class="title">%s</text>`,
-			width/2, lc.padding/2, lc.options.Title)
+			width/2, lc.padding/2, escapeText(lc.options.Title))
 	}
 
 	// Draw axes
@@ -85,39 +79,58 @@ func (lc *lineChart) Generate() string {
 		value := lc.maxValue - ((lc.maxValue - lc.minValue) * float64(i) / float64(numGridLines))
 
 		// Grid line
-		svg += fmt.Sprintf(`<line x1="%d" y1="%f" x2="%d" y2="%f" class="grid"/>`,
-			lc.padding, y, width-lc.padding, y)
+		if lc.options.ShowGrid {
+			svg += fmt.Sprintf(`<line x1="%d" y1="%f" x2="%d" y2="%f" class="grid"/>`,
+				lc.padding, y, width-lc.padding, y)
+		}
 
 		// Y-axis label
 		svg += fmt.Sprintf(`<text x="%d" y="%f" text-anchor="end" alignment-baseline="middle" class="label">%.1f</text>`,
 			lc.padding-5, y, value)
 	}
 
+	// Draw reference lines behind the data
+	svg += referenceLinesSVG(lc.options.ReferenceLines, lc.padding, width-lc.padding, yFor)
+
 	// Generate line path
 	points := make([]string, len(lc.data))
 	for i, d := range lc.data {
-		// Calculate point position
-		x := float64(lc.padding) + (float64(i) * graphWidth / float64(len(lc.data)-1))
-		heightRatio := (d.Value - lc.minValue) / (lc.maxValue - lc.minValue)
-		y := float64(height-lc.padding) - (heightRatio * graphHeight)
+		// Calculate point position; a single point is centred
+		x := float64(lc.padding) + graphWidth/2
+		if len(lc.data) > 1 {
+			x = float64(lc.padding) + (float64(i) * graphWidth / float64(len(lc.data)-1))
+		}
+		y := yFor(d.Value)
 
 		// Add point to path
 		points[i] = fmt.Sprintf("%f,%f", x, y)
 
-		// Add data point circle
-		svg += fmt.Sprintf(`<circle cx="%f" cy="%f" r="4" class="data-point">
+		// Add data point circle, using the point's own colour when it has one
+		// and highlighting values above a reference line
+		fill, radius := "", 4
+		if d.Color != "" {
+			fill = fmt.Sprintf(` style="fill: %s"`, escapeText(d.Color))
+		}
+		if color, ok := exceedanceColor(d.Value, lc.options.ReferenceLines); ok {
+			fill = fmt.Sprintf(` style="fill: %s"`, color)
+			radius = 6
+		}
+		svg += fmt.Sprintf(`<circle cx="%f" cy="%f" r="%d" class="data-point"%s>
 			<title>%s: %.2f</title>
 		</circle>`,
-			x, y, d.Label, d.Value)
+			x, y, radius, fill, escapeText(d.Label), d.Value)
 
 		// X-axis label
 		svg += fmt.Sprintf(`<text x="%f" y="%d" text-anchor="middle" transform="rotate(45 %f,%d)" class="label">%s</text>`,
-			x, height-lc.padding+5, x, height-lc.padding+5, d.Label)
+			x, height-lc.padding+5, x, height-lc.padding+5, escapeText(d.Label))
 	}
 
 	// Draw line connecting points
 	svg += fmt.Sprintf(`<path d="M%s" class="data-line"/>`, strings.Join(points, " L"))
 
+	// Add axis labels if present
+	svg += axisLabelsSVG(lc.options)
+
 	// Close SVG
 	svg += "</svg>"
 
@@ -78,6 +78,26 @@ func TestLineChart(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "With reference line",
+			data: ChartData{{Label: "A", Value: 10.0}, {Label: "B", Value: 30.0}},
+			options: func() Options {
+				o := DefaultOptions()
+				WithReferenceLine(20, "Limit")(&o)
+				return o
+			}(),
+			checks: func(t *testing.T, svg string) {
+				if !strings.Contains(svg, `class="reference"`) {
+					t.Error("Chart should draw the reference line")
+				}
+				if !strings.Contains(svg, "Limit") {
+					t.Error("Chart should label the reference line")
+				}
+				if strings.Count(svg, "#e74c3c") < 3 {
+					t.Error("Values above the reference line should be highlighted")
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
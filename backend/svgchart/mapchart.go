@@ -0,0 +1,190 @@
+package svgchart
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// graticuleSteps are the candidate spacings, in degrees, of map grid lines
+var graticuleSteps = []float64{30, 10, 5, 2, 1, 0.5, 0.2, 0.1, 0.05, 0.02, 0.01}
+
+// mapChart implements the Chart interface for point maps. Points are placed
+// with an equirectangular projection, either of the whole world or of the
+// bounding box of the data, without any external tile service.
+type mapChart struct {
+	data    ChartData
+	options Options
+	padding int
+	// Calculated values
+	minValue float64
+	maxValue float64
+	minLat   float64
+	maxLat   float64
+	minLon   float64
+	maxLon   float64
+}
+
+// newMapChart creates a new map chart instance from the located points
+func newMapChart(data ChartData, options Options) Chart {
+	mc := &mapChart{
+		options: options,
+		padding: 40,
+	}
+
+	for _, d := range data {
+		if d.Location != nil {
+			mc.data = append(mc.data, d)
+		}
+	}
+	if len(mc.data) == 0 {
+		return mc
+	}
+
+	mc.minValue, mc.maxValue = getYMinMax(mc.data)
+
+	if options.Projection == Equirectangular {
+		mc.minLat, mc.maxLat, mc.minLon, mc.maxLon = -90, 90, -180, 180
+		return mc
+	}
+
+	mc.minLat, mc.maxLat = mc.data[0].Location.Lat, mc.data[0].Location.Lat
+	mc.minLon, mc.maxLon = mc.data[0].Location.Lon, mc.data[0].Location.Lon
+	for _, d := range mc.data {
+		mc.minLat = math.Min(mc.minLat, d.Location.Lat)
+		mc.maxLat = math.Max(mc.maxLat, d.Location.Lat)
+		mc.minLon = math.Min(mc.minLon, d.Location.Lon)
+		mc.maxLon = math.Max(mc.maxLon, d.Location.Lon)
+	}
+
+	// Pad the bounding box so points are not drawn on the edge
+	latPad := math.Max((mc.maxLat-mc.minLat)*0.1, 0.01)
+	lonPad := math.Max((mc.maxLon-mc.minLon)*0.1, 0.01)
+	mc.minLat = math.Max(-90, mc.minLat-latPad)
+	mc.maxLat = math.Min(90, mc.maxLat+latPad)
+	mc.minLon = math.Max(-180, mc.minLon-lonPad)
+	mc.maxLon = math.Min(180, mc.maxLon+lonPad)
+
+	return mc
+}
+
+// Generate produces the SVG string for the map chart
+func (mc *mapChart) Generate() string {
+	if len(mc.data) == 0 {
+		return generateEmptyChart(mc.options, "No located data available")
+	}
+
+	width := mc.options.Width
+	height := mc.options.Height
+	graphWidth := float64(width - (mc.padding * 2))
+	graphHeight := float64(height - (mc.padding * 2))
+
+	// Use the same scale on both axes and centre the map in the chart area
+	scale := math.Min(graphWidth/(mc.maxLon-mc.minLon), graphHeight/(mc.maxLat-mc.minLat))
+	offsetX := float64(mc.padding) + (graphWidth-scale*(mc.maxLon-mc.minLon))/2
+	offsetY := float64(mc.padding) + (graphHeight-scale*(mc.maxLat-mc.minLat))/2
+	project := func(lat, lon float64) (float64, float64) {
+		return offsetX + (lon-mc.minLon)*scale, offsetY + (mc.maxLat-lat)*scale
+	}
+
+	// Create SVG with styles
+	svg := fmt.Sprintf(`<svg width="%d" height="%d" xmlns="http://www.w3.org/2000/svg">
+		<style>
+			.frame { fill: #eef6fb; stroke: #333; stroke-width: 1; }
+			.graticule { stroke: #c9d6df; stroke-width: 0.5; }
+			.label { font-family: Arial; font-size: 10px; fill: #555; }
+			.title { font-family: Arial; font-size: 16px; font-weight: bold; }
+			.map-point { stroke: #fff; stroke-width: 1; fill-opacity: 0.85; }
+			.map-point:hover { stroke: #333; }
+		</style>`, width, height)
+
+	// Add title if present
+	if mc.options.Title != "" {
+		svg += fmt.Sprintf(`<text x="%d" y="%d" text-anchor="middle" class="title">%s</text>`,
+			width/2, mc.padding/2, escapeText(mc.options.Title))
+	}
+
+	// Draw the map frame
+	x1, y1 := project(mc.maxLat, mc.minLon)
+	x2, y2 := project(mc.minLat, mc.maxLon)
+	svg += fmt.Sprintf(`<rect x="%f" y="%f" width="%f" height="%f" class="frame"/>`, x1, y1, x2-x1, y2-y1)
+
+	// Draw graticule lines with their degree labels
+	if mc.options.ShowGrid {
+		step := graticuleStep(math.Max(mc.maxLat-mc.minLat, mc.maxLon-mc.minLon))
+		for lat := math.Ceil(mc.minLat/step) * step; lat <= mc.maxLat; lat += step {
+			_, y := project(lat, mc.minLon)
+			svg += fmt.Sprintf(`<line x1="%f" y1="%f" x2="%f" y2="%f" class="graticule"/>`, x1, y, x2, y)
+			svg += fmt.Sprintf(`<text x="%f" y="%f" text-anchor="end" alignment-baseline="middle" class="label">%s°</text>`,
+				x1-4, y, formatNumber(lat))
+		}
+		for lon := math.Ceil(mc.minLon/step) * step; lon <= mc.maxLon; lon += step {
+			x, _ := project(mc.minLat, lon)
+			svg += fmt.Sprintf(`<line x1="%f" y1="%f" x2="%f" y2="%f" class="graticule"/>`, x, y1, x, y2)
+			svg += fmt.Sprintf(`<text x="%f" y="%f" text-anchor="middle" class="label">%s°</text>`,
+				x, y2+12, formatNumber(lon))
+		}
+	}
+
+	// Draw larger values first so smaller points stay visible on top
+	points := make(ChartData, len(mc.data))
+	copy(points, mc.data)
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Value > points[j].Value
+	})
+
+	for _, d := range points {
+		x, y := project(d.Location.Lat, d.Location.Lon)
+		ratio := 0.5
+		if mc.maxValue > mc.minValue {
+			ratio = (d.Value - mc.minValue) / (mc.maxValue - mc.minValue)
+		}
+
+		radius, color := 6.0, "#3366cc"
+		switch mc.options.Encoding {
+		case ColorEncoding:
+			color = interpolateColor(ratio)
+		default:
+			radius = 4 + 12*math.Sqrt(ratio)
+		}
+		if d.Color != "" {
+			color = escapeText(d.Color)
+		}
+
+		svg += fmt.Sprintf(`<circle cx="%f" cy="%f" r="%f" fill="%s" class="map-point">
+			<title>%s: %s (%s, %s)</title>
+		</circle>`,
+			x, y, radius, color, escapeText(d.Label), formatNumber(d.Value),
+			formatNumber(d.Location.Lat), formatNumber(d.Location.Lon))
+	}
+
+	// Add the value range legend
+	svg += fmt.Sprintf(`<text x="%d" y="%d" class="label">min %s • max %s</text>`,
+		mc.padding, height-8, formatNumber(mc.minValue), formatNumber(mc.maxValue))
+
+	// Close SVG
+	svg += "</svg>"
+
+	return svg
+}
+
+// graticuleStep picks a grid spacing that gives a handful of lines over span
+func graticuleStep(span float64) float64 {
+	for _, step := range graticuleSteps {
+		if span/step >= 3 {
+			return step
+		}
+	}
+	return graticuleSteps[len(graticuleSteps)-1]
+}
+
+// interpolateColor returns a colour on a light to dark blue ramp for ratio in [0, 1]
+func interpolateColor(ratio float64) string {
+	from := [3]float64{0xc6, 0xdb, 0xef}
+	to := [3]float64{0x08, 0x30, 0x6b}
+	var rgb [3]int
+	for i := range rgb {
+		rgb[i] = int(math.Round(from[i] + (to[i]-from[i])*ratio))
+	}
+	return fmt.Sprintf("#%02x%02x%02x", rgb[0], rgb[1], rgb[2])
+}
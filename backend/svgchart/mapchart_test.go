@@ -0,0 +1,76 @@
+package svgchart
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMapChart(t *testing.T) {
+	located := ChartData{
+		{Label: "Nairobi", Value: 40, Location: &Location{Lat: -1.2921, Lon: 36.8219}},
+		{Label: "Mombasa", Value: 10, Location: &Location{Lat: -4.0435, Lon: 39.6682}},
+		{Label: "Unknown", Value: 99},
+	}
+
+	tests := []struct {
+		name    string
+		data    ChartData
+		options Options
+		checks  func(t *testing.T, svg string)
+	}{
+		{
+			name:    "No located data",
+			data:    ChartData{{Label: "A", Value: 1}},
+			options: DefaultOptions(),
+			checks: func(t *testing.T, svg string) {
+				if !strings.Contains(svg, "No located data available") {
+					t.Error("Map without coordinates should contain an empty message")
+				}
+			},
+		},
+		{
+			name:    "Bounding box sized by value",
+			data:    located,
+			options: DefaultOptions(),
+			checks: func(t *testing.T, svg string) {
+				if strings.Count(svg, `class="map-point"`) != 2 {
+					t.Error("Map should only draw points with a location")
+				}
+				if !strings.Contains(svg, "Nairobi") {
+					t.Error("Map points should carry their label")
+				}
+			},
+		},
+		{
+			name: "Equirectangular coloured by value",
+			data: located,
+			options: func() Options {
+				o := DefaultOptions()
+				WithProjection(Equirectangular)(&o)
+				WithEncoding(ColorEncoding)(&o)
+				return o
+			}(),
+			checks: func(t *testing.T, svg string) {
+				if !strings.Contains(svg, "#08306b") || !strings.Contains(svg, "#c6dbef") {
+					t.Error("Map should colour the highest and lowest values at the ends of the ramp")
+				}
+				if !strings.Contains(svg, "-180°") {
+					t.Error("World map should label the full longitude range")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mc := newMapChart(tt.data, tt.options)
+			svg := mc.Generate()
+			if svg == "" {
+				t.Error("Generate() returned empty string")
+			}
+			if tt.checks != nil {
+				tt.checks(t, svg)
+			}
+		})
+	}
+}
@@ -14,6 +14,38 @@ type Options struct {
 	ChartType ChartType
 	Colors    ColorScheme
 	Margins   Margins
+	// ReferenceLines are horizontal limits drawn across bar and line charts;
+	// values above a line are highlighted in its colour
+	ReferenceLines []ReferenceLine
+	// Projection and Encoding control how map charts place and draw points
+	Projection Projection
+	Encoding   Encoding
+}
+
+// Projection maps latitude and longitude onto the chart area
+type Projection string
+
+const (
+	// Equirectangular shows the whole world with longitude and latitude
+	// mapped linearly onto x and y
+	Equirectangular Projection = "equirectangular"
+	// BoundingBox zooms the equirectangular projection onto the data
+	BoundingBox Projection = "bbox"
+)
+
+// Encoding is how a map chart shows the value of a point
+type Encoding string
+
+const (
+	SizeEncoding  Encoding = "size"
+	ColorEncoding Encoding = "color"
+)
+
+// ReferenceLine is a labelled horizontal line at a fixed value
+type ReferenceLine struct {
+	Value float64
+	Label string
+	Color string
 }
 
 // ColorScheme defines the color palette for the chart
@@ -38,10 +70,12 @@ type Margins struct {
 // DefaultOptions returns the default chart options
 func DefaultOptions() Options {
 	return Options{
-		Width:     500,
-		Height:    300,
-		ShowGrid:  true,
-		ChartType: Line,
+		Width:      500,
+		Height:     300,
+		ShowGrid:   true,
+		ChartType:  Line,
+		Projection: BoundingBox,
+		Encoding:   SizeEncoding,
 		Colors: ColorScheme{
 			Background: "#ffffff",
 			Axis:       "#333333",
@@ -118,6 +152,32 @@ func WithColors(colors ColorScheme) Option {
 	}
 }
 
+// WithReferenceLine adds a horizontal reference line, such as a regulatory
+// limit, at value
+func WithReferenceLine(value float64, label string) Option {
+	return func(o *Options) {
+		o.ReferenceLines = append(o.ReferenceLines, ReferenceLine{
+			Value: value,
+			Label: label,
+			Color: "#e74c3c",
+		})
+	}
+}
+
+// WithProjection sets the projection used by map charts
+func WithProjection(p Projection) Option {
+	return func(o *Options) {
+		o.Projection = p
+	}
+}
+
+// WithEncoding sets whether map charts size or colour points by value
+func WithEncoding(e Encoding) Option {
+	return func(o *Options) {
+		o.Encoding = e
+	}
+}
+
 // WithMargins sets custom margins
 func WithMargins(m Margins) Option {
 	return func(o *Options) {
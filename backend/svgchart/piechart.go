@@ -58,6 +58,9 @@ func (p *pieChart) Generate() string {
 		}
 
 		color := colors[i%len(colors)]
+		if d.Color != "" {
+			color = escapeText(d.Color)
+		}
 		path := fmt.Sprintf(`<path d="M %f %f L %f %f A %f %f 0 %d 1 %f %f L %f %f Z" fill="%s" stroke="white" stroke-width="1"/>`,
 			centerX, centerY, x1, y1, radius, radius, largeArcFlag, x2, y2, centerX, centerY, color)
 		paths = append(paths, path)
@@ -66,7 +69,7 @@ func (p *pieChart) Generate() string {
 		legend := fmt.Sprintf(`<g transform="translate(%f, %f)">
 			<rect width="10" height="10" fill="%s"/>
 			<text x="15" y="9" font-size="12">%s (%.1f%%)</text>
-		</g>`, width-120, float64(i)*20+40, color, d.Label, percentage)
+		</g>`, width-120, float64(i)*20+40, color, escapeText(d.Label), percentage)
 		legends = append(legends, legend)
 
 		startAngle = endAngle
@@ -82,7 +85,7 @@ func (p *pieChart) Generate() string {
 		<g>%s</g>
 	</svg>`,
 		p.options.Width, p.options.Height,
-		centerX, p.options.Title,
+		centerX, escapeText(p.options.Title),
 		strings.Join(paths, "\n"),
 		strings.Join(legends, "\n"))
 
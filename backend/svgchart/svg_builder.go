@@ -50,7 +50,7 @@ func (sb *SVGBuilder) AddRect(x, y, width, height int, attrs map[string]string)
 func (sb *SVGBuilder) AddText(x, y int, text string, attrs map[string]string) {
 	attrs["x"] = fmt.Sprintf("%d", x)
 	attrs["y"] = fmt.Sprintf("%d", y)
-	sb.AddElement("text", attrs, text)
+	sb.AddElement("text", attrs, escapeText(text))
 }
 
 // AddLine adds a line element
@@ -87,7 +87,7 @@ func (sb *SVGBuilder) writeAttrs(attrs map[string]string) {
 		sb.builder.WriteString(" ")
 		sb.builder.WriteString(k)
 		sb.builder.WriteString(`="`)
-		sb.builder.WriteString(v)
+		sb.builder.WriteString(escapeText(v))
 		sb.builder.WriteString(`"`)
 	}
 }
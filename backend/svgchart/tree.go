@@ -0,0 +1,60 @@
+package svgchart
+
+import (
+	"fmt"
+
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+// TreeNode is a node of hierarchical chart data
+type TreeNode struct {
+	Label    string      `json:"label"`
+	Value    float64     `json:"value"`
+	Children []*TreeNode `json:"children,omitempty"`
+}
+
+// ConvertTree converts supported hierarchical data into a TreeNode
+func ConvertTree(data interface{}) (*TreeNode, error) {
+	switch d := data.(type) {
+	case *TreeNode:
+		return d, nil
+	case *util.TreeNode:
+		return convertUtilTree(d), nil
+	default:
+		return nil, fmt.Errorf("unsupported tree data type")
+	}
+}
+
+func convertUtilTree(node *util.TreeNode) *TreeNode {
+	if node == nil {
+		return nil
+	}
+	result := &TreeNode{Label: node.Name, Value: float64(node.Count)}
+	for _, c := range node.Children {
+		result.Children = append(result.Children, convertUtilTree(c))
+	}
+	return result
+}
+
+// leaves returns the number of leaves below n
+func (n *TreeNode) leaves() int {
+	if len(n.Children) == 0 {
+		return 1
+	}
+	count := 0
+	for _, c := range n.Children {
+		count += c.leaves()
+	}
+	return count
+}
+
+// depth returns the number of levels from n to its deepest leaf
+func (n *TreeNode) depth() int {
+	deepest := 0
+	for _, c := range n.Children {
+		if d := c.depth(); d > deepest {
+			deepest = d
+		}
+	}
+	return deepest + 1
+}
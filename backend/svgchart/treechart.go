@@ -0,0 +1,117 @@
+package svgchart
+
+import (
+	"fmt"
+	"math"
+)
+
+// treeChart implements the Chart interface for dendrograms. Leaves are spread
+// evenly down the chart, parents are centred on their children and every
+// level of the hierarchy gets its own column.
+type treeChart struct {
+	root    *TreeNode
+	options Options
+	padding int
+}
+
+// newTreeChart creates a new dendrogram instance
+func newTreeChart(root *TreeNode, options Options) Chart {
+	return &treeChart{
+		root:    root,
+		options: options,
+		padding: 40,
+	}
+}
+
+// Generate produces the SVG string for the dendrogram
+func (tc *treeChart) Generate() string {
+	if tc.root == nil || (len(tc.root.Children) == 0 && tc.root.Value == 0) {
+		return generateEmptyChart(tc.options, "No data available")
+	}
+
+	width := tc.options.Width
+	height := tc.options.Height
+	labelSpace := 140.0
+	graphWidth := float64(width-(tc.padding*2)) - labelSpace
+	graphHeight := float64(height - (tc.padding * 2))
+
+	levels := tc.root.depth()
+	columnWidth := graphWidth
+	if levels > 1 {
+		columnWidth = graphWidth / float64(levels-1)
+	}
+	rowHeight := graphHeight / float64(tc.root.leaves())
+	maxValue := math.Max(tc.root.Value, 1)
+
+	// Create SVG with styles
+	svg := fmt.Sprintf(`<svg width="%d" height="%d" xmlns="http://www.w3.org/2000/svg">
+		<style>
+			.label { font-family: Arial; font-size: 11px; fill: #333; }
+			.title { font-family: Arial; font-size: 16px; font-weight: bold; }
+			.link { stroke: #9aa5b1; stroke-width: 1.2; fill: none; }
+			.node { fill: #4285f4; stroke: #fff; stroke-width: 1; }
+			.node:hover { fill: #2962ff; }
+		</style>`, width, height)
+
+	// Add title if present
+	if tc.options.Title != "" {
+		svg += fmt.Sprintf(`<text x="%d" y="%d" text-anchor="middle" class="title">%s</text>`,
+			width/2, tc.padding/2, escapeText(tc.options.Title))
+	}
+
+	// First pass: place leaves in order and centre parents on their children
+	type position struct{ x, y float64 }
+	positions := make(map[*TreeNode]position)
+	nextLeaf := 0
+
+	var place func(n *TreeNode, level int) float64
+	place = func(n *TreeNode, level int) float64 {
+		x := float64(tc.padding) + float64(level)*columnWidth
+
+		var y float64
+		if len(n.Children) == 0 {
+			y = float64(tc.padding) + (float64(nextLeaf)+0.5)*rowHeight
+			nextLeaf++
+		} else {
+			first := place(n.Children[0], level+1)
+			last := first
+			for _, c := range n.Children[1:] {
+				last = place(c, level+1)
+			}
+			y = (first + last) / 2
+		}
+
+		positions[n] = position{x, y}
+		return y
+	}
+	place(tc.root, 0)
+
+	// Second pass: draw elbow links below the nodes and labels
+	var links, nodes string
+	var draw func(n *TreeNode)
+	draw = func(n *TreeNode) {
+		p := positions[n]
+		for _, c := range n.Children {
+			cp := positions[c]
+			links += fmt.Sprintf(`<path d="M%f,%f H%f V%f H%f" class="link"/>`,
+				p.x, p.y, p.x+columnWidth/2, cp.y, cp.x)
+			draw(c)
+		}
+
+		radius := 3 + 7*math.Sqrt(n.Value/maxValue)
+		nodes += fmt.Sprintf(`<circle cx="%f" cy="%f" r="%f" class="node">
+			<title>%s: %s</title>
+		</circle>`, p.x, p.y, radius, escapeText(n.Label), formatNumber(n.Value))
+		nodes += fmt.Sprintf(`<text x="%f" y="%f" alignment-baseline="middle" class="label">%s (%s)</text>`,
+			p.x+radius+4, p.y, escapeText(n.Label), formatNumber(n.Value))
+	}
+	draw(tc.root)
+
+	svg += links
+	svg += nodes
+
+	// Close SVG
+	svg += "</svg>"
+
+	return svg
+}
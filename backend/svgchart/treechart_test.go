@@ -0,0 +1,70 @@
+package svgchart
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTreeChart(t *testing.T) {
+	taxonomy := &TreeNode{Label: "Life", Value: 5, Children: []*TreeNode{
+		{Label: "Acacia", Value: 3, Children: []*TreeNode{
+			{Label: "Acacia tortilis", Value: 2},
+			{Label: "Acacia senegal", Value: 1},
+		}},
+		{Label: "Ficus sycomorus", Value: 2},
+	}}
+
+	tests := []struct {
+		name    string
+		root    *TreeNode
+		options Options
+		checks  func(t *testing.T, svg string)
+	}{
+		{
+			name:    "Empty tree",
+			root:    &TreeNode{Label: "Life"},
+			options: DefaultOptions(),
+			checks: func(t *testing.T, svg string) {
+				if !strings.Contains(svg, "No data available") {
+					t.Error("Empty tree should contain an empty message")
+				}
+			},
+		},
+		{
+			name: "Nodes and links",
+			root: taxonomy,
+			options: func() Options {
+				o := DefaultOptions()
+				WithTitle("Taxonomy")(&o)
+				return o
+			}(),
+			checks: func(t *testing.T, svg string) {
+				if strings.Count(svg, `class="node"`) != 5 {
+					t.Error("Tree should draw one circle per node")
+				}
+				if strings.Count(svg, `class="link"`) != 4 {
+					t.Error("Tree should draw one link per child")
+				}
+				if !strings.Contains(svg, "Acacia tortilis (2)") {
+					t.Error("Leaves should be labelled with their counts")
+				}
+				if !strings.Contains(svg, "Taxonomy") {
+					t.Error("Tree should contain the title")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTreeChart(tt.root, tt.options)
+			svg := tc.Generate()
+			if svg == "" {
+				t.Error("Generate() returned empty string")
+			}
+			if tt.checks != nil {
+				tt.checks(t, svg)
+			}
+		})
+	}
+}
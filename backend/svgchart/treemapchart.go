@@ -0,0 +1,202 @@
+package svgchart
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// treemapChart implements the Chart interface for treemaps. Every node is a
+// rectangle with an area proportional to its value, nested inside its parent;
+// children are laid out with the squarified algorithm so rectangles stay close
+// to square.
+type treemapChart struct {
+	root    *TreeNode
+	options Options
+	padding int
+}
+
+// rect is an area of the chart in SVG coordinates
+type rect struct {
+	x, y, w, h float64
+}
+
+// newTreemapChart creates a new treemap instance
+func newTreemapChart(root *TreeNode, options Options) Chart {
+	return &treemapChart{
+		root:    root,
+		options: options,
+		padding: 40,
+	}
+}
+
+// Generate produces the SVG string for the treemap
+func (tm *treemapChart) Generate() string {
+	if tm.root == nil || tm.root.Value <= 0 {
+		return generateEmptyChart(tm.options, "No data available")
+	}
+
+	width := tm.options.Width
+	height := tm.options.Height
+
+	// Create SVG with styles
+	svg := fmt.Sprintf(`<svg width="%d" height="%d" xmlns="http://www.w3.org/2000/svg">
+		<style>
+			.label { font-family: Arial; font-size: 11px; fill: #fff; }
+			.title { font-family: Arial; font-size: 16px; font-weight: bold; }
+			.cell { stroke: #fff; stroke-width: 1; }
+			.cell:hover { opacity: 0.8; }
+		</style>`, width, height)
+
+	// Add title if present
+	if tm.options.Title != "" {
+		svg += fmt.Sprintf(`<text x="%d" y="%d" text-anchor="middle" class="title">%s</text>`,
+			width/2, tm.padding/2, escapeText(tm.options.Title))
+	}
+
+	area := rect{
+		x: float64(tm.padding),
+		y: float64(tm.padding),
+		w: float64(width - tm.padding*2),
+		h: float64(height - tm.padding*2),
+	}
+
+	// Each top level branch gets its own colour, shared by everything below it
+	colors := []string{"#4285F4", "#34A853", "#FBBC05", "#EA4335", "#673AB7", "#3F51B5", "#2196F3", "#03A9F4"}
+
+	var draw func(n *TreeNode, r rect, color string)
+	draw = func(n *TreeNode, r rect, color string) {
+		if r.w < 1 || r.h < 1 {
+			return
+		}
+
+		svg += fmt.Sprintf(`<rect x="%f" y="%f" width="%f" height="%f" fill="%s" class="cell">
+			<title>%s: %s</title>
+		</rect>`, r.x, r.y, r.w, r.h, color, escapeText(n.Label), formatNumber(n.Value))
+
+		// Label cells that are large enough to hold some text
+		if r.w > 40 && r.h > 16 {
+			svg += fmt.Sprintf(`<text x="%f" y="%f" class="label">%s (%s)</text>`,
+				r.x+4, r.y+12, escapeText(n.Label), formatNumber(n.Value))
+		}
+
+		// Children are laid out below the parent's label, leaving any value the
+		// parent holds itself as uncovered space
+		inner := rect{x: r.x + 2, y: r.y + 16, w: r.w - 4, h: r.h - 18}
+		if len(n.Children) == 0 || inner.w < 1 || inner.h < 1 {
+			return
+		}
+		for i, cr := range squarify(n.Children, n.Value, inner) {
+			childColor := color
+			if n == tm.root {
+				childColor = colors[i%len(colors)]
+			}
+			draw(n.Children[i], cr, childColor)
+		}
+	}
+	draw(tm.root, area, "#90a4ae")
+
+	// Close SVG
+	svg += "</svg>"
+
+	return svg
+}
+
+// squarify divides r between children in proportion to their values, where
+// total is the value represented by the whole of r. The returned rectangles are
+// in the same order as children; children without a value get an empty one.
+func squarify(children []*TreeNode, total float64, r rect) []rect {
+	result := make([]rect, len(children))
+
+	sum := 0.0
+	for _, c := range children {
+		sum += math.Max(c.Value, 0)
+	}
+	if sum <= 0 {
+		return result
+	}
+	if total < sum {
+		total = sum
+	}
+
+	// Lay out the largest values first, keeping the order stable for ties
+	order := make([]int, 0, len(children))
+	for i, c := range children {
+		if c.Value > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return children[order[a]].Value > children[order[b]].Value
+	})
+
+	// Areas in square pixels; the children only cover sum/total of r
+	scale := r.w * r.h / total
+	free := r
+	if r.w >= r.h {
+		free.w = r.w * sum / total
+	} else {
+		free.h = r.h * sum / total
+	}
+
+	var row []int
+	rowArea := 0.0
+	for k := 0; k < len(order); {
+		i := order[k]
+		a := children[i].Value * scale
+		side := math.Min(free.w, free.h)
+
+		if len(row) == 0 || worstRatio(row, rowArea, children, scale, side) >= worstRatio(append(row, i), rowArea+a, children, scale, side) {
+			row = append(row, i)
+			rowArea += a
+			k++
+			continue
+		}
+
+		free = layoutRow(row, rowArea, children, scale, free, result)
+		row, rowArea = nil, 0
+	}
+	if len(row) > 0 {
+		layoutRow(row, rowArea, children, scale, free, result)
+	}
+
+	return result
+}
+
+// worstRatio returns the largest aspect ratio of the rectangles of row when
+// placed along a side of the given length
+func worstRatio(row []int, rowArea float64, children []*TreeNode, scale, side float64) float64 {
+	worst := 0.0
+	for _, i := range row {
+		a := children[i].Value * scale
+		ratio := math.Max(side*side*a/(rowArea*rowArea), rowArea*rowArea/(side*side*a))
+		worst = math.Max(worst, ratio)
+	}
+	return worst
+}
+
+// layoutRow places row along the shorter side of free, stores the rectangles
+// in result and returns the space that is left over
+func layoutRow(row []int, rowArea float64, children []*TreeNode, scale float64, free rect, result []rect) rect {
+	if free.w >= free.h {
+		// Column on the left
+		colWidth := rowArea / free.h
+		y := free.y
+		for _, i := range row {
+			h := children[i].Value * scale / colWidth
+			result[i] = rect{x: free.x, y: y, w: colWidth, h: h}
+			y += h
+		}
+		return rect{x: free.x + colWidth, y: free.y, w: free.w - colWidth, h: free.h}
+	}
+
+	// Row along the top
+	rowHeight := rowArea / free.w
+	x := free.x
+	for _, i := range row {
+		w := children[i].Value * scale / rowHeight
+		result[i] = rect{x: x, y: free.y, w: w, h: rowHeight}
+		x += w
+	}
+	return rect{x: free.x, y: free.y + rowHeight, w: free.w, h: free.h - rowHeight}
+}
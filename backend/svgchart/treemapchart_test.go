@@ -0,0 +1,86 @@
+package svgchart
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSquarify(t *testing.T) {
+	children := []*TreeNode{
+		{Label: "A", Value: 6}, {Label: "B", Value: 6}, {Label: "C", Value: 4},
+		{Label: "D", Value: 3}, {Label: "E", Value: 2}, {Label: "F", Value: 2}, {Label: "G", Value: 1},
+	}
+	area := rect{x: 0, y: 0, w: 600, h: 400}
+
+	tests := []struct {
+		name  string
+		total float64
+	}{
+		{name: "Children fill the parent", total: 24},
+		{name: "Parent holds values of its own", total: 48},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rects := squarify(children, tt.total, area)
+			for i, r := range rects {
+				want := children[i].Value / tt.total * area.w * area.h
+				if math.Abs(r.w*r.h-want) > 0.01 {
+					t.Errorf("%s area = %f, want %f", children[i].Label, r.w*r.h, want)
+				}
+				if r.x < area.x || r.y < area.y || r.x+r.w > area.w+0.01 || r.y+r.h > area.h+0.01 {
+					t.Errorf("%s falls outside the parent: %+v", children[i].Label, r)
+				}
+			}
+		})
+	}
+}
+
+func TestTreemapChart(t *testing.T) {
+	outline := &TreeNode{Label: "Document", Value: 10, Children: []*TreeNode{
+		{Label: "Air", Value: 6, Children: []*TreeNode{{Label: "PM2.5", Value: 4}}},
+		{Label: "Water", Value: 4},
+		{Label: "Appendix", Value: 0},
+	}}
+
+	tests := []struct {
+		name   string
+		root   *TreeNode
+		checks func(t *testing.T, svg string)
+	}{
+		{
+			name: "Empty tree",
+			root: &TreeNode{Label: "Document"},
+			checks: func(t *testing.T, svg string) {
+				if !strings.Contains(svg, "No data available") {
+					t.Error("Empty treemap should contain an empty message")
+				}
+			},
+		},
+		{
+			name: "Nested cells",
+			root: outline,
+			checks: func(t *testing.T, svg string) {
+				if strings.Count(svg, `class="cell"`) != 4 {
+					t.Error("Treemap should draw one cell per node with a value")
+				}
+				if !strings.Contains(svg, "Air (6)") {
+					t.Error("Cells should be labelled with their counts")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svg := newTreemapChart(tt.root, DefaultOptions()).Generate()
+			if svg == "" {
+				t.Error("Generate() returned empty string")
+			}
+			if tt.checks != nil {
+				tt.checks(t, svg)
+			}
+		})
+	}
+}
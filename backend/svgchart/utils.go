@@ -2,6 +2,7 @@ package svgchart
 
 import (
 	"fmt"
+	"html"
 	"math"
 	"strings"
 )
@@ -55,3 +56,74 @@ func generateEmptyChart(options Options, message string) string {
 	)
 	return sb.String()
 }
+
+// valueRange returns the padded min and max of the data, widened to include
+// any reference lines so they stay visible
+func valueRange(data ChartData, lines []ReferenceLine, floorAtZero bool) (float64, float64) {
+	min, max := getYMinMax(data)
+	for _, l := range lines {
+		min = math.Min(min, l.Value)
+		max = math.Max(max, l.Value)
+	}
+
+	spread := max - min
+	if spread == 0 {
+		spread = math.Max(math.Abs(max), 1)
+	}
+	min -= spread * 0.1
+	max += spread * 0.1
+	if floorAtZero {
+		min = math.Max(0, min)
+	}
+	return min, max
+}
+
+// exceedanceColor returns the colour of the highest reference line value
+// exceeds, if any
+func exceedanceColor(value float64, lines []ReferenceLine) (string, bool) {
+	color, best, found := "", 0.0, false
+	for _, l := range lines {
+		if value > l.Value && (!found || l.Value > best) {
+			color, best, found = l.Color, l.Value, true
+		}
+	}
+	return color, found
+}
+
+// referenceLinesSVG draws the reference lines between x1 and x2 using y to map
+// values onto the chart
+func referenceLinesSVG(lines []ReferenceLine, x1, x2 int, y func(float64) float64) string {
+	var sb strings.Builder
+	for _, l := range lines {
+		ly := y(l.Value)
+		sb.WriteString(fmt.Sprintf(`<line x1="%d" y1="%f" x2="%d" y2="%f" class="reference" stroke="%s" stroke-width="1.5" stroke-dasharray="6 3"/>`,
+			x1, ly, x2, ly, escapeText(l.Color)))
+		label := l.Label
+		if label == "" {
+			label = formatNumber(l.Value)
+		}
+		sb.WriteString(fmt.Sprintf(`<text x="%d" y="%f" text-anchor="end" class="label" fill="%s">%s</text>`,
+			x2, ly-4, escapeText(l.Color), escapeText(label)))
+	}
+	return sb.String()
+}
+
+// axisLabelsSVG draws the X and Y axis labels when they are set
+func axisLabelsSVG(options Options) string {
+	var sb strings.Builder
+	if options.XLabel != "" {
+		sb.WriteString(fmt.Sprintf(`<text x="%d" y="%d" text-anchor="middle" class="label">%s</text>`,
+			options.Width/2, options.Height-5, escapeText(options.XLabel)))
+	}
+	if options.YLabel != "" {
+		sb.WriteString(fmt.Sprintf(`<text x="%d" y="%d" text-anchor="middle" transform="rotate(-90 %d,%d)" class="label">%s</text>`,
+			12, options.Height/2, 12, options.Height/2, escapeText(options.YLabel)))
+	}
+	return sb.String()
+}
+
+// escapeText escapes text taken from documents or requests, such as labels
+// and titles, for use as SVG text or attribute values
+func escapeText(s string) string {
+	return html.EscapeString(s)
+}
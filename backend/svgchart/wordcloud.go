@@ -0,0 +1,153 @@
+package svgchart
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"unicode/utf8"
+)
+
+const (
+	minFontSize = 11.0
+	maxFontSize = 48.0
+)
+
+// wordCloud implements the Chart interface for word clouds. Words are placed
+// largest first along an Archimedean spiral from the centre of the chart and
+// never rotated, so the same data always gives the same picture.
+type wordCloud struct {
+	data    ChartData
+	options Options
+	padding int
+}
+
+// newWordCloud creates a new word cloud instance
+func newWordCloud(data ChartData, options Options) Chart {
+	return &wordCloud{
+		data:    data,
+		options: options,
+		padding: 40,
+	}
+}
+
+// Generate produces the SVG string for the word cloud
+func (wc *wordCloud) Generate() string {
+	if len(wc.data) == 0 {
+		return generateEmptyChart(wc.options, "No data available")
+	}
+
+	width := wc.options.Width
+	height := wc.options.Height
+
+	// Order by weight, then alphabetically, so placement does not depend on
+	// the order the data arrived in
+	words := make(ChartData, len(wc.data))
+	copy(words, wc.data)
+	sort.SliceStable(words, func(i, j int) bool {
+		if words[i].Value != words[j].Value {
+			return words[i].Value > words[j].Value
+		}
+		return words[i].Label < words[j].Label
+	})
+
+	minValue, maxValue := getYMinMax(words)
+
+	// Create SVG with styles
+	svg := fmt.Sprintf(`<svg width="%d" height="%d" xmlns="http://www.w3.org/2000/svg">
+		<style>
+			.word { font-family: Arial; font-weight: bold; }
+			.word:hover { opacity: 0.7; }
+			.title { font-family: Arial; font-size: 16px; font-weight: bold; }
+		</style>`, width, height)
+
+	// Add title if present
+	if wc.options.Title != "" {
+		svg += fmt.Sprintf(`<text x="%d" y="%d" text-anchor="middle" class="title">%s</text>`,
+			width/2, wc.padding/2, escapeText(wc.options.Title))
+	}
+
+	area := rect{
+		x: float64(wc.padding),
+		y: float64(wc.padding),
+		w: float64(width - wc.padding*2),
+		h: float64(height - wc.padding*2),
+	}
+	cx, cy := area.x+area.w/2, area.y+area.h/2
+
+	colors := []string{"#4285F4", "#34A853", "#FBBC05", "#EA4335", "#673AB7", "#3F51B5", "#2196F3", "#03A9F4"}
+
+	var placed []rect
+	for i, word := range words {
+		fontSize := minFontSize
+		if maxValue > minValue {
+			fontSize += (maxFontSize - minFontSize) * math.Sqrt((word.Value-minValue)/(maxValue-minValue))
+		} else {
+			fontSize = maxFontSize
+		}
+
+		// Approximate the text box from the font size
+		w := 0.6 * fontSize * float64(utf8.RuneCountInString(word.Label))
+		h := fontSize
+
+		box, ok := spiralPlace(w, h, cx, cy, area, placed)
+		if !ok {
+			// No room left for this word
+			continue
+		}
+		placed = append(placed, box)
+
+		color := escapeText(word.Color)
+		if color == "" {
+			color = colors[i%len(colors)]
+		}
+		svg += fmt.Sprintf(`<text x="%f" y="%f" font-size="%.1f" fill="%s" text-anchor="middle" dominant-baseline="central" class="word">%s
+			<title>%s: %s</title>
+		</text>`, box.x+box.w/2, box.y+box.h/2, fontSize, color, escapeText(word.Label), escapeText(word.Label), formatNumber(word.Value))
+	}
+
+	// Close SVG
+	svg += "</svg>"
+
+	return svg
+}
+
+// spiralPlace walks an Archimedean spiral out from (cx, cy) and returns the
+// first w×h box that fits inside area without overlapping any placed box
+func spiralPlace(w, h, cx, cy float64, area rect, placed []rect) (rect, bool) {
+	if w > area.w || h > area.h {
+		return rect{}, false
+	}
+
+	// Stretch the spiral to the shape of the chart; at a radius of h√2 it
+	// has passed the corners of the area
+	aspect := area.w / area.h
+	maxRadius := area.h * math.Sqrt2
+
+	for t := 0.0; ; t += 0.1 {
+		r := 2 * t
+		if r > maxRadius {
+			return rect{}, false
+		}
+
+		box := rect{
+			x: cx + r*math.Cos(t)*aspect/2 - w/2,
+			y: cy + r*math.Sin(t)/2 - h/2,
+			w: w,
+			h: h,
+		}
+		if box.x < area.x || box.y < area.y || box.x+box.w > area.x+area.w || box.y+box.h > area.y+area.h {
+			continue
+		}
+
+		overlaps := false
+		for _, p := range placed {
+			if box.x < p.x+p.w && p.x < box.x+box.w && box.y < p.y+p.h && p.y < box.y+box.h {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			return box, true
+		}
+	}
+}
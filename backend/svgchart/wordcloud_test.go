@@ -0,0 +1,65 @@
+package svgchart
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWordCloud(t *testing.T) {
+	words := ChartData{
+		{Label: "wetland", Value: 9},
+		{Label: "river", Value: 5},
+		{Label: "sediment", Value: 5},
+		{Label: "heron", Value: 1},
+	}
+	reversed := ChartData{words[3], words[2], words[1], words[0]}
+
+	tests := []struct {
+		name   string
+		data   ChartData
+		checks func(t *testing.T, svg string)
+	}{
+		{
+			name: "Empty data",
+			data: ChartData{},
+			checks: func(t *testing.T, svg string) {
+				if !strings.Contains(svg, "No data available") {
+					t.Error("Empty word cloud should contain an empty message")
+				}
+			},
+		},
+		{
+			name: "Every word placed",
+			data: words,
+			checks: func(t *testing.T, svg string) {
+				if strings.Count(svg, `class="word"`) != len(words) {
+					t.Error("Word cloud should place every word when there is room")
+				}
+				if !strings.Contains(svg, `font-size="48.0"`) || !strings.Contains(svg, `font-size="11.0"`) {
+					t.Error("Largest and smallest words should use the extreme font sizes")
+				}
+			},
+		},
+		{
+			name: "Layout does not depend on input order",
+			data: reversed,
+			checks: func(t *testing.T, svg string) {
+				if svg != newWordCloud(words, DefaultOptions()).Generate() {
+					t.Error("Word cloud should be the same whatever the order of the data")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svg := newWordCloud(tt.data, DefaultOptions()).Generate()
+			if svg == "" {
+				t.Error("Generate() returned empty string")
+			}
+			if tt.checks != nil {
+				tt.checks(t, svg)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package util
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html"
+	"sort"
+	"strings"
+)
+
+// unitPalette is the set of highlight colours assigned to units
+var unitPalette = []string{
+	"#fde68a", "#bfdbfe", "#bbf7d0", "#fecaca", "#ddd6fe",
+	"#fbcfe8", "#a5f3fc", "#fed7aa", "#d9f99d", "#e5e7eb",
+}
+
+// Annotation is the source text of a document rendered as HTML with every
+// stored data point highlighted
+type Annotation struct {
+	HTML     string               `json:"html"`
+	Colors   map[string]string    `json:"colors"`
+	Points   map[string]DataPoint `json:"points"`
+	Linked   int                  `json:"linked"`
+	Unlinked int                  `json:"unlinked"`
+}
+
+// UnitColor returns the highlight colour used for unit
+func UnitColor(unit string) string {
+	h := fnv.New32a()
+	h.Write([]byte(unit))
+	return unitPalette[h.Sum32()%uint32(len(unitPalette))]
+}
+
+// Annotate escapes text as HTML and wraps the span of every data point that has
+// provenance in a <mark> element linked to the point ID. Points without an ID or
+// without offsets that fit the text cannot be placed and are counted as unlinked.
+func Annotate(text string, points []DataPoint) Annotation {
+	result := Annotation{
+		Colors: make(map[string]string),
+		Points: make(map[string]DataPoint),
+	}
+
+	var placed []DataPoint
+	for _, dp := range points {
+		if dp.ID == "" || dp.Source == nil || dp.Source.Offset < 0 ||
+			dp.Source.Offset+dp.Source.Length > len(text) {
+			result.Unlinked++
+			continue
+		}
+		placed = append(placed, dp)
+	}
+
+	sort.SliceStable(placed, func(i, j int) bool {
+		return placed[i].Source.Offset < placed[j].Source.Offset
+	})
+
+	var sb strings.Builder
+	sb.WriteString(`<pre class="annotated-source">`)
+
+	pos := 0
+	for _, dp := range placed {
+		start, end := dp.Source.Offset, dp.Source.Offset+dp.Source.Length
+		if start < pos {
+			// Overlaps a span that was already highlighted
+			result.Unlinked++
+			continue
+		}
+
+		color := UnitColor(dp.Unit)
+		result.Colors[dp.Unit] = color
+		result.Points[dp.ID] = dp
+		result.Linked++
+
+		sb.WriteString(escapeText(text[pos:start]))
+		sb.WriteString(fmt.Sprintf(
+			`<mark class="extraction" data-point-id="%s" data-unit="%s" style="background-color: %s" title="%s">`,
+			html.EscapeString(dp.ID), html.EscapeString(dp.Unit), color,
+			html.EscapeString(fmt.Sprintf("%s: %g %s", dp.ID, dp.Value, dp.Unit)),
+		))
+		sb.WriteString(escapeText(text[start:end]))
+		sb.WriteString("</mark>")
+		pos = end
+	}
+
+	sb.WriteString(escapeText(text[pos:]))
+	sb.WriteString("</pre>")
+
+	result.HTML = sb.String()
+	return result
+}
+
+// escapeText makes an arbitrary byte range safe to embed in HTML
+func escapeText(s string) string {
+	return html.EscapeString(strings.ToValidUTF8(s, "�"))
+}
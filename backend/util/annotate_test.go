@@ -0,0 +1,83 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnnotate(t *testing.T) {
+	const text = "Rainfall was 5 mm & <b>12 °C</b>"
+	at := func(id string, offset, length int) DataPoint {
+		return DataPoint{ID: id, Value: 1, Unit: "mm", Source: &Provenance{Offset: offset, Length: length}}
+	}
+
+	tests := []struct {
+		name         string
+		points       []DataPoint
+		wantLinked   int
+		wantUnlinked int
+		wantHTML     []string
+	}{
+		{
+			"no points",
+			nil, 0, 0,
+			[]string{`<pre class="annotated-source">Rainfall was 5 mm &amp; &lt;b&gt;12 °C&lt;/b&gt;</pre>`},
+		},
+		{
+			"one point",
+			[]DataPoint{at("p1", 13, 4)}, 1, 0,
+			[]string{`data-point-id="p1"`, `>5 mm</mark> &amp; &lt;b&gt;`},
+		},
+		{
+			"out of document order",
+			[]DataPoint{at("p2", 23, len("12 °C")), at("p1", 13, 4)}, 2, 0,
+			[]string{`>5 mm</mark>`, `>12 °C</mark>`},
+		},
+		{
+			"without ID or provenance",
+			[]DataPoint{{Value: 1, Unit: "mm", Source: &Provenance{Offset: 13, Length: 4}}, {ID: "p1", Value: 1, Unit: "mm"}}, 0, 2,
+			[]string{"<pre", "</pre>"},
+		},
+		{
+			"past the end of the text",
+			[]DataPoint{at("p1", 30, 10)}, 0, 1,
+			[]string{"</pre>"},
+		},
+		{
+			"overlapping spans",
+			[]DataPoint{at("p1", 13, 4), at("p2", 15, 4)}, 1, 1,
+			[]string{`data-point-id="p1"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Annotate(text, tt.points)
+			if got.Linked != tt.wantLinked || got.Unlinked != tt.wantUnlinked {
+				t.Errorf("linked %d, unlinked %d, want %d and %d", got.Linked, got.Unlinked, tt.wantLinked, tt.wantUnlinked)
+			}
+			if len(got.Points) != tt.wantLinked {
+				t.Errorf("points = %v, want %d", got.Points, tt.wantLinked)
+			}
+			for _, want := range tt.wantHTML {
+				if !strings.Contains(got.HTML, want) {
+					t.Errorf("HTML = %s, want it to contain %s", got.HTML, want)
+				}
+			}
+			if strings.Contains(got.HTML, "<b>") {
+				t.Errorf("HTML = %s, source markup not escaped", got.HTML)
+			}
+		})
+	}
+}
+
+func TestAnnotateEscapesPointFields(t *testing.T) {
+	points := []DataPoint{{ID: `p1" onclick="x`, Value: 5, Unit: `<mm>`, Source: &Provenance{Offset: 0, Length: 4}}}
+	got := Annotate("5 mm", points)
+	if strings.Contains(got.HTML, `" onclick`) || strings.Contains(got.HTML, "<mm>") {
+		t.Errorf("HTML = %s, point fields not escaped", got.HTML)
+	}
+	if got.Colors["<mm>"] != UnitColor("<mm>") {
+		t.Errorf("colors = %v, want the colour of <mm>", got.Colors)
+	}
+}
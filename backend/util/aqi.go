@@ -0,0 +1,257 @@
+package util
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+//go:embed config/aqi.json
+var defaultAQIConfig []byte
+
+// AQIConfig holds the air quality index standards used to derive AQI values
+type AQIConfig struct {
+	Standards []AQIStandard `json:"standards"`
+}
+
+// AQIStandard is a national or agency AQI definition
+type AQIStandard struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	Categories []AQICategory  `json:"categories"`
+	Pollutants []AQIPollutant `json:"pollutants"`
+}
+
+// AQICategory is a named index band with its display colour
+type AQICategory struct {
+	Name  string  `json:"name"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Color string  `json:"color"`
+}
+
+// AQIPollutant holds the breakpoint table of one pollutant
+type AQIPollutant struct {
+	ID              string          `json:"id"`
+	Unit            string          `json:"unit"`
+	Precision       int             `json:"precision"`
+	MolecularWeight float64         `json:"molecular_weight,omitempty"`
+	Breakpoints     []AQIBreakpoint `json:"breakpoints"`
+}
+
+// AQIBreakpoint maps a concentration range onto an index range
+type AQIBreakpoint struct {
+	CLow  float64 `json:"c_low"`
+	CHigh float64 `json:"c_high"`
+	ILow  float64 `json:"i_low"`
+	IHigh float64 `json:"i_high"`
+}
+
+// pollutantPatterns recognise pollutant names written before a concentration
+var pollutantPatterns = []struct {
+	id      string
+	pattern *regexp.Regexp
+}{
+	{"pm2.5", regexp.MustCompile(`(?i)\bPM\s?2\.5`)},
+	{"pm10", regexp.MustCompile(`(?i)\bPM\s?10\b`)},
+	{"o3", regexp.MustCompile(`\bO3\b|O₃|(?i:\bozone\b)`)},
+	{"no2", regexp.MustCompile(`\bNO2\b|NO₂|(?i:\bnitrogen dioxide\b)`)},
+	{"co", regexp.MustCompile(`\bCO\b|(?i:\bcarbon monoxide\b)`)},
+}
+
+var (
+	aqiConfigOnce sync.Once
+	aqiConfig     AQIConfig
+)
+
+func init() {
+	RegisterDerivation(aqiDerivation{})
+}
+
+// LoadAQIConfig parses an AQI configuration file
+func LoadAQIConfig(path string) (AQIConfig, error) {
+	configBytes, err := os.ReadFile(path)
+	if err != nil {
+		return AQIConfig{}, fmt.Errorf("reading AQI config: %w", err)
+	}
+	return parseAQIConfig(configBytes)
+}
+
+func parseAQIConfig(configBytes []byte) (AQIConfig, error) {
+	var config AQIConfig
+	if err := json.Unmarshal(configBytes, &config); err != nil {
+		return AQIConfig{}, fmt.Errorf("unmarshaling AQI config: %w", err)
+	}
+	return config, nil
+}
+
+// CurrentAQIConfig returns the AQI configuration in use. It is read from the
+// file named by BIOTREE_AQI_CONFIG, falling back to the built-in tables.
+func CurrentAQIConfig() AQIConfig {
+	aqiConfigOnce.Do(func() {
+		if path := os.Getenv("BIOTREE_AQI_CONFIG"); path != "" {
+			config, err := LoadAQIConfig(path)
+			if err == nil {
+				aqiConfig = config
+				return
+			}
+			log.Printf("Failed to load AQI config %s, using defaults: %v", path, err)
+		}
+
+		config, err := parseAQIConfig(defaultAQIConfig)
+		if err != nil {
+			log.Printf("Failed to parse built-in AQI config: %v", err)
+		}
+		aqiConfig = config
+	})
+	return aqiConfig
+}
+
+// SubIndex computes the AQI sub-index of a concentration given in unit.
+// It reports false when the unit cannot be converted or the value is negative.
+func (p AQIPollutant) SubIndex(value float64, unit string) (float64, bool) {
+	c, ok := convertConcentration(value, unit, p.Unit, p.MolecularWeight)
+	if !ok || c < 0 || len(p.Breakpoints) == 0 {
+		return 0, false
+	}
+
+	// Concentrations are truncated to the precision of the table
+	scale := math.Pow(10, float64(p.Precision))
+	c = math.Floor(c*scale+1e-9) / scale
+
+	for _, bp := range p.Breakpoints {
+		if c <= bp.CHigh {
+			if c < bp.CLow {
+				c = bp.CLow
+			}
+			return math.Round((bp.IHigh-bp.ILow)/(bp.CHigh-bp.CLow)*(c-bp.CLow) + bp.ILow), true
+		}
+	}
+
+	// Beyond the table, report the top of the scale
+	return p.Breakpoints[len(p.Breakpoints)-1].IHigh, true
+}
+
+// Category returns the band an index value falls into
+func (s AQIStandard) Category(index float64) (AQICategory, bool) {
+	for _, c := range s.Categories {
+		if index <= c.Max {
+			return c, true
+		}
+	}
+	if len(s.Categories) > 0 {
+		return s.Categories[len(s.Categories)-1], true
+	}
+	return AQICategory{}, false
+}
+
+// concentrationUnit spells a concentration unit as the AQI tables do. Units
+// are extracted as written, so "PPM" and "μg/m³" with a Greek mu are folded
+// onto "ppm" and "µg/m³".
+func concentrationUnit(unit string) string {
+	return strings.ReplaceAll(strings.ToLower(unit), "μ", "µ")
+}
+
+// convertConcentration converts between mass (µg/m³, mg/m³) and volume (ppm,
+// ppb) concentrations, using the molecular weight at 25 °C and 1 atm
+func convertConcentration(value float64, from, to string, molecularWeight float64) (float64, bool) {
+	toMicrograms := map[string]float64{"µg/m³": 1, "μg/m³": 1, "mg/m³": 1000}
+	toPPB := map[string]float64{"ppb": 1, "ppm": 1000}
+
+	if from == to {
+		return value, true
+	}
+	if f, ok := toMicrograms[from]; ok {
+		if t, ok := toMicrograms[to]; ok {
+			return value * f / t, true
+		}
+		if t, ok := toPPB[to]; ok && molecularWeight > 0 {
+			return value * f * 24.45 / molecularWeight / t, true
+		}
+	}
+	if f, ok := toPPB[from]; ok {
+		if t, ok := toPPB[to]; ok {
+			return value * f / t, true
+		}
+		if t, ok := toMicrograms[to]; ok && molecularWeight > 0 {
+			return value * f * molecularWeight / 24.45 / t, true
+		}
+	}
+	return 0, false
+}
+
+// detectPollutant returns the pollutant named closest before a point
+func detectPollutant(text string, dp DataPoint) string {
+	before := precedingText(text, dp, 60)
+
+	best, bestPos := "", -1
+	for _, p := range pollutantPatterns {
+		locs := p.pattern.FindAllStringIndex(before, -1)
+		if len(locs) == 0 {
+			continue
+		}
+		if pos := locs[len(locs)-1][0]; pos > bestPos {
+			best, bestPos = p.id, pos
+		}
+	}
+	return best
+}
+
+// aqiDerivation computes AQI sub-indices from pollutant concentrations
+type aqiDerivation struct{}
+
+func (aqiDerivation) Name() string { return "aqi" }
+
+func (aqiDerivation) Derive(text string, points []DataPoint) []DataPoint {
+	config := CurrentAQIConfig()
+
+	var derived []DataPoint
+	for _, dp := range points {
+		unit := concentrationUnit(dp.Unit)
+		if unit != "µg/m³" && unit != "ppm" {
+			continue
+		}
+
+		pollutant := detectPollutant(text, dp)
+		if pollutant == "" {
+			continue
+		}
+
+		for _, standard := range config.Standards {
+			for _, p := range standard.Pollutants {
+				if p.ID != pollutant {
+					continue
+				}
+
+				index, ok := p.SubIndex(dp.Value, unit)
+				if !ok {
+					continue
+				}
+
+				point := DataPoint{
+					Value:    index,
+					Unit:     "AQI",
+					Label:    fmt.Sprintf("%s AQI (%s)", strings.ToUpper(pollutant), standard.Name),
+					Series:   fmt.Sprintf("aqi.%s.%s", standard.ID, pollutant),
+					Inputs:   []string{dp.ID},
+					Section:  dp.Section,
+					Location: dp.Location,
+					Source:   dp.Source,
+				}
+				if category, ok := standard.Category(index); ok {
+					point.Level = category.Name
+					point.Color = category.Color
+				}
+				derived = append(derived, point)
+			}
+		}
+	}
+
+	return derived
+}
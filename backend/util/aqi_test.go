@@ -0,0 +1,63 @@
+package util
+
+import "testing"
+
+func TestAQISubIndex(t *testing.T) {
+	config := CurrentAQIConfig()
+
+	var epa AQIStandard
+	for _, s := range config.Standards {
+		if s.ID == "us-epa" {
+			epa = s
+		}
+	}
+	pollutant := func(id string) AQIPollutant {
+		for _, p := range epa.Pollutants {
+			if p.ID == id {
+				return p
+			}
+		}
+		t.Fatalf("pollutant %s not configured", id)
+		return AQIPollutant{}
+	}
+
+	tests := []struct {
+		name      string
+		pollutant string
+		value     float64
+		unit      string
+		want      float64
+		category  string
+	}{
+		{"PM2.5 good", "pm2.5", 5.0, "µg/m³", 28, "Good"},
+		{"PM2.5 sensitive groups", "pm2.5", 40.0, "µg/m³", 112, "Unhealthy for Sensitive Groups"},
+		{"PM10 moderate", "pm10", 100, "µg/m³", 73, "Moderate"},
+		{"CO converted from µg/m³", "co", 5000, "µg/m³", 49, "Good"},
+		{"Above the table", "pm2.5", 900, "µg/m³", 500, "Hazardous"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := pollutant(tt.pollutant).SubIndex(tt.value, tt.unit)
+			if !ok {
+				t.Fatalf("SubIndex() could not compute index")
+			}
+			if got != tt.want {
+				t.Errorf("SubIndex() = %v, want %v", got, tt.want)
+			}
+			category, _ := epa.Category(got)
+			if category.Name != tt.category {
+				t.Errorf("Category() = %q, want %q", category.Name, tt.category)
+			}
+		})
+	}
+}
+
+func TestAQIDerivationFoldsUnitCase(t *testing.T) {
+	for _, text := range []string{"PM2.5 was 40 µg/m³", "PM2.5 was 40 µG/M³", "PM2.5 was 40 ΜG/M³"} {
+		derived := Extract(text).Derived
+		if len(derived) == 0 || derived[0].Unit != "AQI" || derived[0].Value != 112 {
+			t.Errorf("Extract(%q).Derived = %+v, want an AQI of 112", text, derived)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package util
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+const (
+	// BackupFormatVersion is the version of the archive layout
+	BackupFormatVersion = 1
+	// backupManifestName is the name of the manifest inside an archive
+	backupManifestName = "manifest.json"
+	// maxBackupFileSize caps the uncompressed size of a file of an archive,
+	// so a crafted archive cannot expand without bound
+	maxBackupFileSize = 1 << 30
+)
+
+// Conflict modes of a restore, for objects that exist with other contents
+const (
+	ConflictSkip      = "skip"      // keep the existing object
+	ConflictOverwrite = "overwrite" // replace it with the archived one
+	ConflictFail      = "fail"      // restore nothing
+)
+
+var (
+	// ErrInvalidBackup is returned for archives that are damaged or were not
+	// written by Backup
+	ErrInvalidBackup = errors.New("invalid backup archive")
+	// ErrRestoreConflict is returned by a restore in ConflictFail mode when
+	// objects of the archive exist with other contents
+	ErrRestoreConflict = errors.New("archive conflicts with existing objects")
+)
+
+// backupKinds are the kinds of object archived. The listing and search
+// indexes are left out; they are rebuilt from the datasets after a restore.
+// Charts are rendered from the datasets on request, so there are none to keep.
+var backupKinds = []store.Kind{
+	store.Documents, store.Datasets, store.Reports, store.Keywords, store.Meta,
+	store.Hashes, store.Versions, store.Audit, store.Projects,
+}
+
+// BackupManifest lists the contents of a backup archive
+type BackupManifest struct {
+	FormatVersion    int              `json:"format_version"`
+	CreatedAt        time.Time        `json:"created_at"`
+	ExtractorVersion string           `json:"extractor_version"`
+	Objects          []BackupEntry    `json:"objects"`
+	Rules            []BackupRuleFile `json:"rules"`
+}
+
+// BackupEntry describes an archived object
+type BackupEntry struct {
+	Kind     store.Kind `json:"kind"`
+	Name     string     `json:"name"`
+	Size     int64      `json:"size"`
+	SHA256   string     `json:"sha256"`
+	Modified time.Time  `json:"modified"`
+}
+
+// BackupRuleFile describes an archived rule set
+type BackupRuleFile struct {
+	Name   string `json:"name"`
+	Env    string `json:"env"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// RestoreResult reports what a restore did
+type RestoreResult struct {
+	Restored  int `json:"restored"`
+	Unchanged int `json:"unchanged"`
+	// Conflicts are the objects that existed with other contents, as kind/name
+	Conflicts []string `json:"conflicts"`
+	// Skipped counts the objects left as they were, or not written, because
+	// the dataset they belong to conflicts
+	Skipped int `json:"skipped"`
+	// Rules are the archived rule sets that differ from the ones in use. They
+	// take effect once written out and named by their environment variable.
+	Rules []RuleSet `json:"rules"`
+}
+
+// objectPath returns where an object is kept inside an archive
+func objectPath(kind store.Kind, name string) string {
+	return path.Join("objects", string(kind), name)
+}
+
+// rulePath returns where a rule set is kept inside an archive
+func rulePath(name string) string {
+	return path.Join("rules", name+".json")
+}
+
+// Backup writes every object of st and the rule sets in use to w as a zip
+// archive. Objects are copied one at a time; the manifest with their
+// checksums comes last.
+func Backup(st store.Store, w io.Writer) (BackupManifest, error) {
+	manifest := BackupManifest{
+		FormatVersion:    BackupFormatVersion,
+		CreatedAt:        time.Now().UTC(),
+		ExtractorVersion: ExtractorVersion,
+		Objects:          []BackupEntry{},
+	}
+	zw := zip.NewWriter(w)
+
+	for _, kind := range backupKinds {
+		objects, err := st.List(kind)
+		if err != nil {
+			return manifest, fmt.Errorf("listing %s: %w", kind, err)
+		}
+		for _, obj := range objects {
+			data, err := st.Get(kind, obj.Name)
+			if err != nil {
+				return manifest, fmt.Errorf("reading %s/%s: %w", kind, obj.Name, err)
+			}
+			if err := writeZipFile(zw, objectPath(kind, obj.Name), obj.Modified, data); err != nil {
+				return manifest, err
+			}
+			manifest.Objects = append(manifest.Objects, BackupEntry{
+				Kind:     kind,
+				Name:     obj.Name,
+				Size:     int64(len(data)),
+				SHA256:   ContentHash(data),
+				Modified: obj.Modified,
+			})
+		}
+	}
+
+	for _, set := range RuleSets() {
+		if err := writeZipFile(zw, rulePath(set.Name), manifest.CreatedAt, set.Data); err != nil {
+			return manifest, err
+		}
+		manifest.Rules = append(manifest.Rules, BackupRuleFile{
+			Name:   set.Name,
+			Env:    set.Env,
+			Size:   int64(len(set.Data)),
+			SHA256: ContentHash(set.Data),
+		})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, fmt.Errorf("marshaling manifest: %w", err)
+	}
+	if err := writeZipFile(zw, backupManifestName, manifest.CreatedAt, data); err != nil {
+		return manifest, err
+	}
+	return manifest, zw.Close()
+}
+
+// writeZipFile adds a file to an archive
+func writeZipFile(zw *zip.Writer, name string, modified time.Time, data []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return fmt.Errorf("adding %s: %w", name, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
+
+// ReadBackupManifest reads the manifest of an archive
+func ReadBackupManifest(zr *zip.Reader) (BackupManifest, error) {
+	var manifest BackupManifest
+	data, err := readZipFile(zr, backupManifestName)
+	if err != nil {
+		return manifest, err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("%w: unreadable manifest: %v", ErrInvalidBackup, err)
+	}
+	if manifest.FormatVersion != BackupFormatVersion {
+		return manifest, fmt.Errorf("%w: format version %d is not supported", ErrInvalidBackup, manifest.FormatVersion)
+	}
+	return manifest, nil
+}
+
+// readZipFile returns the contents of a file of an archive. Files larger than
+// maxBackupFileSize are rejected, whatever size their header claims.
+func readZipFile(zr *zip.Reader, name string) ([]byte, error) {
+	return readZipFileLimit(zr, name, maxBackupFileSize)
+}
+
+// readZipFileLimit returns the contents of a file of an archive of at most
+// limit bytes
+func readZipFileLimit(zr *zip.Reader, name string, limit int64) ([]byte, error) {
+	f, err := zr.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidBackup, name)
+	}
+	defer f.Close()
+
+	// The size in the header of the file is its UncompressedSize64
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading %s: %v", ErrInvalidBackup, name, err)
+	}
+	if info.Size() > limit {
+		return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrInvalidBackup, name, limit)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading %s: %v", ErrInvalidBackup, name, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrInvalidBackup, name, limit)
+	}
+	return data, nil
+}
+
+// Restore copies the objects of a backup archive into st. Every checksum is
+// verified before anything is written. Objects that exist with the same
+// contents are left alone. When any object of a dataset exists with other
+// contents, the dataset and all of its objects are handled by conflict.
+// Afterwards the listing and search indexes are brought up to date for the
+// restored datasets.
+func Restore(st store.Store, zr *zip.Reader, conflict string) (RestoreResult, error) {
+	result := RestoreResult{Conflicts: []string{}, Rules: []RuleSet{}}
+	if conflict != ConflictSkip && conflict != ConflictOverwrite && conflict != ConflictFail {
+		return result, fmt.Errorf("invalid conflict mode: %s", conflict)
+	}
+
+	manifest, err := ReadBackupManifest(zr)
+	if err != nil {
+		return result, err
+	}
+
+	known := make(map[store.Kind]bool)
+	for _, kind := range backupKinds {
+		known[kind] = true
+	}
+
+	// Verify the whole archive and find the conflicts before writing.
+	// Conflicts are decided per dataset: a dataset is restored or left alone
+	// together with its document and every record kept about it. Only the
+	// entries are remembered; their contents are read again when written.
+	var pending []restoreWrite
+	conflicted := make(map[string]bool)
+	for _, entry := range manifest.Objects {
+		if !known[entry.Kind] || !store.ValidName(entry.Name) {
+			return result, fmt.Errorf("%w: unexpected object %s/%s", ErrInvalidBackup, entry.Kind, entry.Name)
+		}
+		data, err := readZipFile(zr, objectPath(entry.Kind, entry.Name))
+		if err != nil {
+			return result, err
+		}
+		if ContentHash(data) != entry.SHA256 {
+			return result, fmt.Errorf("%w: checksum mismatch for %s/%s", ErrInvalidBackup, entry.Kind, entry.Name)
+		}
+
+		owner := backupOwner(entry, data)
+		existing, err := st.Get(entry.Kind, entry.Name)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			pending = append(pending, restoreWrite{entry: entry, owner: owner})
+		case err != nil:
+			return result, err
+		case bytes.Equal(existing, data):
+			result.Unchanged++
+		default:
+			result.Conflicts = append(result.Conflicts, string(entry.Kind)+"/"+entry.Name)
+			conflicted[owner] = true
+			pending = append(pending, restoreWrite{entry: entry, owner: owner})
+		}
+	}
+
+	for _, rule := range manifest.Rules {
+		data, err := readZipFile(zr, rulePath(rule.Name))
+		if err != nil {
+			return result, err
+		}
+		if ContentHash(data) != rule.SHA256 {
+			return result, fmt.Errorf("%w: checksum mismatch for rule set %s", ErrInvalidBackup, rule.Name)
+		}
+		for _, current := range RuleSets() {
+			if current.Name == rule.Name && !bytes.Equal(current.Data, data) {
+				result.Rules = append(result.Rules, RuleSet{Name: rule.Name, Env: rule.Env, Data: data})
+			}
+		}
+	}
+
+	if conflict == ConflictFail && len(result.Conflicts) > 0 {
+		return result, ErrRestoreConflict
+	}
+
+	var writes []restoreWrite
+	for _, w := range pending {
+		if conflict == ConflictSkip && conflicted[w.owner] {
+			result.Skipped++
+			continue
+		}
+		writes = append(writes, w)
+	}
+
+	// Datasets go in before their sidecars so the sidecars never describe a
+	// dataset that is not there. Objects keep the time they were last written
+	// where the store can record it, as they do when migrated.
+	timed, hasTimes := st.(store.TimedStore)
+	restored := make(map[string]bool)
+	for _, kind := range backupKinds {
+		for _, w := range writes {
+			entry := w.entry
+			if entry.Kind != kind {
+				continue
+			}
+			data, err := readZipFile(zr, objectPath(entry.Kind, entry.Name))
+			if err != nil {
+				return result, err
+			}
+			if hasTimes && !entry.Modified.IsZero() {
+				err = timed.PutModified(entry.Kind, entry.Name, data, entry.Modified)
+			} else {
+				err = st.Put(entry.Kind, entry.Name, data)
+			}
+			if err != nil {
+				return result, fmt.Errorf("restoring %s/%s: %w", entry.Kind, entry.Name, err)
+			}
+			result.Restored++
+			if kind == store.Datasets || kind == store.Meta || kind == store.Documents {
+				restored[w.owner] = true
+			}
+		}
+	}
+
+	for dataset := range restored {
+		refreshIndexes(st, dataset)
+	}
+	return result, nil
+}
+
+// restoreWrite is an archived object to be written and the dataset it
+// belongs to
+type restoreWrite struct {
+	entry BackupEntry
+	owner string
+}
+
+// backupOwner returns the dataset an archived object belongs to. Objects kept
+// for no particular dataset, such as projects, stand on their own.
+func backupOwner(entry BackupEntry, data []byte) string {
+	switch entry.Kind {
+	case store.Datasets, store.Meta, store.Reports, store.Keywords, store.Audit:
+		return entry.Name
+	case store.Documents:
+		return entry.Name + ".json"
+	case store.Versions:
+		dataset, _, _ := strings.Cut(entry.Name, "@")
+		return dataset
+	case store.Hashes:
+		if entry.Name != hashesBackfilledMarker {
+			return string(data)
+		}
+	}
+	return string(entry.Kind) + "/" + entry.Name
+}
+
+// refreshIndexes brings the listing and search entries of a restored dataset
+// up to date. Failures are logged.
+func refreshIndexes(st store.Store, dataset string) {
+	if _, err := st.Stat(store.Datasets, dataset); err != nil {
+		return
+	}
+	if err := IndexDataset(st, dataset); err != nil {
+		log.Printf("Failed to index restored dataset %s: %v", dataset, err)
+	}
+	if content, err := st.Get(store.Documents, SourceName(dataset)); err == nil {
+		if err := IndexDocument(st, dataset, string(content)); err != nil {
+			log.Printf("Failed to index restored document of %s: %v", dataset, err)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package util
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+// backupArchive backs up st and opens the archive for reading
+func backupArchive(t *testing.T, st store.Store) *zip.Reader {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if _, err := Backup(st, &buf); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
+
+func TestBackupRestore(t *testing.T) {
+	st := store.NewMemory()
+	dataset := storeDataset(t, st, "report.txt", "Rainfall was 5 mm and the temperature reached 21 °C", time.Now())
+	if err := SaveProject(st, &Project{ID: "survey", Name: "Survey", Datasets: []string{dataset}}); err != nil {
+		t.Fatal(err)
+	}
+
+	zr := backupArchive(t, st)
+	manifest, err := ReadBackupManifest(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.FormatVersion != BackupFormatVersion {
+		t.Errorf("format version = %d, want %d", manifest.FormatVersion, BackupFormatVersion)
+	}
+	if len(manifest.Rules) != len(RuleSets()) {
+		t.Errorf("got %d rule sets, want %d", len(manifest.Rules), len(RuleSets()))
+	}
+	for _, entry := range manifest.Objects {
+		if entry.Kind == store.Index || entry.Kind == store.Search {
+			t.Errorf("index object %s/%s archived", entry.Kind, entry.Name)
+		}
+	}
+
+	restored := store.NewMemory()
+	result, err := Restore(restored, zr, ConflictSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Restored != len(manifest.Objects) || len(result.Conflicts) != 0 {
+		t.Errorf("result = %+v, want %d restored and no conflicts", result, len(manifest.Objects))
+	}
+
+	for _, kind := range backupKinds {
+		names, err := st.List(kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, obj := range names {
+			want, _ := st.Get(kind, obj.Name)
+			got, err := restored.Get(kind, obj.Name)
+			if err != nil || !bytes.Equal(got, want) {
+				t.Errorf("%s/%s not restored: %v", kind, obj.Name, err)
+			}
+		}
+	}
+
+	entries, err := LoadIndex(restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := entries[dataset]; !ok || len(entries) != 1 {
+		t.Errorf("index = %+v, want one entry for %s", entries, dataset)
+	}
+	if hits, err := Search(restored, "rainfall", 10); err != nil || len(hits) != 1 {
+		t.Errorf("search after restore = %v, %v, want one hit", hits, err)
+	}
+
+	// Restoring again changes nothing
+	again, err := Restore(restored, zr, ConflictSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Restored != 0 || again.Unchanged != len(manifest.Objects) {
+		t.Errorf("second restore = %+v, want everything unchanged", again)
+	}
+}
+
+func TestRestoreConflicts(t *testing.T) {
+	st := store.NewMemory()
+	dataset := storeDataset(t, st, "report.txt", "Rainfall was 5 mm", time.Now())
+	zr := backupArchive(t, st)
+
+	original, _ := st.Get(store.Documents, "report.txt")
+	edited := []byte("Rainfall was 9 mm")
+	if err := st.Put(store.Documents, "report.txt", edited); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Restore(st, zr, ConflictFail)
+	if !errors.Is(err, ErrRestoreConflict) {
+		t.Fatalf("fail mode error = %v, want ErrRestoreConflict", err)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0] != "files/report.txt" {
+		t.Errorf("conflicts = %v, want files/report.txt", result.Conflicts)
+	}
+
+	result, err = Restore(st, zr, ConflictSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Skipped != 1 || result.Restored != 0 {
+		t.Errorf("skip mode result = %+v, want one skipped", result)
+	}
+	if got, _ := st.Get(store.Documents, "report.txt"); !bytes.Equal(got, edited) {
+		t.Errorf("skip mode replaced the document: %q", got)
+	}
+
+	result, err = Restore(st, zr, ConflictOverwrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Restored != 1 {
+		t.Errorf("overwrite mode result = %+v, want one restored", result)
+	}
+	if got, _ := st.Get(store.Documents, "report.txt"); !bytes.Equal(got, original) {
+		t.Errorf("overwrite mode kept the document: %q", got)
+	}
+
+	if _, err := Restore(st, zr, "merge"); err == nil {
+		t.Errorf("Restore accepted conflict mode merge for %s", dataset)
+	}
+}
+
+func TestRestoreSkipsConflictingDatasetTogether(t *testing.T) {
+	st := store.NewMemory()
+	dataset := storeDataset(t, st, "report.txt", "Rainfall was 5 mm", time.Now())
+	other := storeDataset(t, st, "survey.txt", "Wind reached 12 km/h", time.Now())
+	zr := backupArchive(t, st)
+
+	// The first dataset was renamed and lost its keywords; the second lost
+	// its keywords too
+	if _, err := RenameDataset(st, dataset, "Renamed"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Delete(store.Keywords, dataset); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Delete(store.Keywords, other); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Restore(st, zr, ConflictSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0] != string(store.Meta)+"/"+dataset {
+		t.Errorf("conflicts = %v, want %s/%s", result.Conflicts, store.Meta, dataset)
+	}
+	if result.Skipped != 2 || result.Restored != 1 {
+		t.Errorf("result = %+v, want two skipped and one restored", result)
+	}
+	if _, err := st.Stat(store.Keywords, dataset); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("keywords of the conflicting dataset restored: %v", err)
+	}
+	if _, err := st.Stat(store.Keywords, other); err != nil {
+		t.Errorf("keywords of the other dataset not restored: %v", err)
+	}
+}
+
+func TestRestoreKeepsModificationTimes(t *testing.T) {
+	st := store.NewMemory()
+	storeDataset(t, st, "report.txt", "Rainfall was 5 mm", time.Now())
+	written := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := st.PutModified(store.Documents, "report.txt", []byte("Rainfall was 5 mm"), written); err != nil {
+		t.Fatal(err)
+	}
+	zr := backupArchive(t, st)
+
+	restored := store.NewMemory()
+	if _, err := Restore(restored, zr, ConflictSkip); err != nil {
+		t.Fatal(err)
+	}
+	info, err := restored.Stat(store.Documents, "report.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Modified.Equal(written) {
+		t.Errorf("modified = %v, want %v", info.Modified, written)
+	}
+}
+
+func TestRestoreRejectsDamagedArchive(t *testing.T) {
+	st := store.NewMemory()
+	storeDataset(t, st, "report.txt", "Rainfall was 5 mm", time.Now())
+	zr := backupArchive(t, st)
+
+	// Copy the archive, replacing the contents of the document
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range zr.File {
+		w, err := zw.Create(f.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Name == objectPath(store.Documents, "report.txt") {
+			w.Write([]byte("Rainfall was 500 mm"))
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(w, r)
+		r.Close()
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	damaged, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := store.NewMemory()
+	if _, err := Restore(restored, damaged, ConflictOverwrite); !errors.Is(err, ErrInvalidBackup) {
+		t.Fatalf("error = %v, want ErrInvalidBackup", err)
+	}
+	if names, _ := restored.List(store.Datasets); len(names) != 0 {
+		t.Errorf("damaged archive restored %v", names)
+	}
+}
+
+func TestReadZipFileLimit(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(bytes.Repeat([]byte("a"), 4096))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := readZipFileLimit(zr, "big", 1024); !errors.Is(err, ErrInvalidBackup) {
+		t.Errorf("readZipFileLimit() over the limit error = %v, want ErrInvalidBackup", err)
+	}
+	if data, err := readZipFileLimit(zr, "big", 4096); err != nil || len(data) != 4096 {
+		t.Errorf("readZipFileLimit() = %d bytes, %v, want 4096", len(data), err)
+	}
+}
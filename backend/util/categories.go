@@ -0,0 +1,148 @@
+package util
+
+import (
+	"sort"
+	"strings"
+)
+
+// Unit categories, in the order they are presented
+const (
+	CategoryAir     = "air"
+	CategoryClimate = "climate"
+	CategoryWater   = "water"
+	CategoryEnergy  = "energy"
+	CategoryTraffic = "traffic"
+	CategoryLand    = "land"
+	CategoryOther   = "other"
+)
+
+// Categories lists every unit category in display order
+var Categories = []string{
+	CategoryAir, CategoryClimate, CategoryWater, CategoryEnergy,
+	CategoryTraffic, CategoryLand, CategoryOther,
+}
+
+// unitCategories maps every unit GetData recognises, and the units produced by
+// derivations, onto its category
+var unitCategories = map[string]string{
+	"µg/m³": CategoryAir,
+	"ppm":   CategoryAir,
+	"AQI":   CategoryAir,
+	"Bq/m³": CategoryAir,
+
+	"°C":   CategoryClimate,
+	"°F":   CategoryClimate,
+	"°C·d": CategoryClimate,
+	"%":    CategoryClimate,
+	"mm":   CategoryClimate,
+	"hPa":  CategoryClimate,
+	"Pa":   CategoryClimate,
+	"mmHg": CategoryClimate,
+	"bar":  CategoryClimate,
+	"psi":  CategoryClimate,
+
+	"mg/L":  CategoryWater,
+	"μS/cm": CategoryWater,
+	"NTU":   CategoryWater,
+	"L":     CategoryWater,
+	"mL":    CategoryWater,
+
+	"kWh": CategoryEnergy,
+	"W":   CategoryEnergy,
+	"MW":  CategoryEnergy,
+
+	"vehicles/hr": CategoryTraffic,
+	"vehicles":    CategoryTraffic,
+	"count/month": CategoryTraffic,
+	"dB":          CategoryTraffic,
+
+	"ha":      CategoryLand,
+	"acres":   CategoryLand,
+	"km²":     CategoryLand,
+	"m²":      CategoryLand,
+	"km":      CategoryLand,
+	"m":       CategoryLand,
+	"cm":      CategoryLand,
+	"mi":      CategoryLand,
+	"ft":      CategoryLand,
+	"yd":      CategoryLand,
+	"in":      CategoryLand,
+	"permits": CategoryLand,
+}
+
+// UnitGroup is the set of units that belong to one category
+type UnitGroup struct {
+	Category string   `json:"category"`
+	Units    []string `json:"units"`
+}
+
+// UnitCategory returns the category of unit. Units are matched exactly first
+// and then case-insensitively, since GetData keeps the case used in the text.
+func UnitCategory(unit string) string {
+	if category, ok := unitCategories[unit]; ok {
+		return category
+	}
+	for known, category := range unitCategories {
+		if strings.EqualFold(known, unit) {
+			return category
+		}
+	}
+	return CategoryOther
+}
+
+// IsCategory reports whether name is a known unit category
+func IsCategory(name string) bool {
+	for _, c := range Categories {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SortUnits orders units by category and then alphabetically
+func SortUnits(units []string) {
+	rank := make(map[string]int, len(Categories))
+	for i, c := range Categories {
+		rank[c] = i
+	}
+	sort.Slice(units, func(i, j int) bool {
+		ci, cj := rank[UnitCategory(units[i])], rank[UnitCategory(units[j])]
+		if ci != cj {
+			return ci < cj
+		}
+		return units[i] < units[j]
+	})
+}
+
+// GroupUnitsByCategory groups units by category, in category display order.
+// Categories without units are left out.
+func GroupUnitsByCategory(units []string) []UnitGroup {
+	grouped := make(map[string][]string)
+	for _, unit := range units {
+		category := UnitCategory(unit)
+		grouped[category] = append(grouped[category], unit)
+	}
+
+	groups := []UnitGroup{}
+	for _, category := range Categories {
+		if len(grouped[category]) == 0 {
+			continue
+		}
+		sort.Strings(grouped[category])
+		groups = append(groups, UnitGroup{Category: category, Units: grouped[category]})
+	}
+
+	return groups
+}
+
+// FilterByCategory returns the points whose unit belongs to category
+func FilterByCategory(points []DataPoint, category string) []DataPoint {
+	var filtered []DataPoint
+	for _, dp := range points {
+		if UnitCategory(dp.Unit) == category {
+			filtered = append(filtered, dp)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,104 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnitCategory(t *testing.T) {
+	tests := []struct {
+		unit string
+		want string
+	}{
+		{"µg/m³", CategoryAir},
+		{"AQI", CategoryAir},
+		{"°C", CategoryClimate},
+		{"NTU", CategoryWater},
+		{"kWh", CategoryEnergy},
+		{"KWH", CategoryEnergy},
+		{"vehicles/hr", CategoryTraffic},
+		{"ha", CategoryLand},
+		{"furlongs", CategoryOther},
+		{"", CategoryOther},
+	}
+
+	for _, tt := range tests {
+		if got := UnitCategory(tt.unit); got != tt.want {
+			t.Errorf("UnitCategory(%q) = %s, want %s", tt.unit, got, tt.want)
+		}
+	}
+}
+
+func TestGroupUnitsByCategory(t *testing.T) {
+	tests := []struct {
+		name  string
+		units []string
+		want  []UnitGroup
+	}{
+		{"no units", nil, []UnitGroup{}},
+		{
+			"display order",
+			[]string{"kWh", "ha", "dB", "mm", "°C", "ppm", "furlongs"},
+			[]UnitGroup{
+				{CategoryAir, []string{"ppm"}},
+				{CategoryClimate, []string{"mm", "°C"}},
+				{CategoryEnergy, []string{"kWh"}},
+				{CategoryTraffic, []string{"dB"}},
+				{CategoryLand, []string{"ha"}},
+				{CategoryOther, []string{"furlongs"}},
+			},
+		},
+		{
+			"units sorted within a category",
+			[]string{"NTU", "mg/L", "L"},
+			[]UnitGroup{{CategoryWater, []string{"L", "NTU", "mg/L"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GroupUnitsByCategory(tt.units); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GroupUnitsByCategory(%v) = %v, want %v", tt.units, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterByCategory(t *testing.T) {
+	points := []DataPoint{
+		{ID: "p1", Value: 5, Unit: "mm"},
+		{ID: "p2", Value: 40, Unit: "µg/m³"},
+		{ID: "p3", Value: 21, Unit: "°C"},
+		{ID: "p4", Value: 3, Unit: "furlongs"},
+	}
+
+	tests := []struct {
+		category string
+		want     []string
+	}{
+		{CategoryClimate, []string{"p1", "p3"}},
+		{CategoryAir, []string{"p2"}},
+		{CategoryOther, []string{"p4"}},
+		{CategoryEnergy, nil},
+		{"space", nil},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, dp := range FilterByCategory(points, tt.category) {
+			got = append(got, dp.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FilterByCategory(%s) = %v, want %v", tt.category, got, tt.want)
+		}
+	}
+}
+
+func TestSortUnits(t *testing.T) {
+	units := []string{"ha", "mm", "ppm", "°C", "furlongs", "kWh"}
+	SortUnits(units)
+	want := []string{"ppm", "mm", "°C", "kWh", "ha", "furlongs"}
+	if !reflect.DeepEqual(units, want) {
+		t.Errorf("SortUnits() = %v, want %v", units, want)
+	}
+}
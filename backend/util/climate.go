@@ -0,0 +1,248 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+)
+
+const (
+	// pairingDistance is how many bytes may separate a temperature from the
+	// relative humidity it is paired with
+	pairingDistance = 120
+
+	// Base temperatures for heating and cooling degree-days
+	degreeDayBaseC = 18.0
+)
+
+var humidityPattern = regexp.MustCompile(`(?i)humid|\bRH\b`)
+
+func init() {
+	RegisterDerivation(heatIndexDerivation{})
+	RegisterDerivation(dewPointDerivation{})
+	RegisterDerivation(degreeDaysDerivation{})
+}
+
+// climatePair is a temperature with the relative humidity written next to it
+type climatePair struct {
+	temperature DataPoint
+	humidity    DataPoint
+}
+
+// pairTemperatureHumidity pairs every temperature with the nearest percentage
+// that is described as relative humidity
+func pairTemperatureHumidity(text string, points []DataPoint) []climatePair {
+	var temperatures, humidities []DataPoint
+	for _, dp := range points {
+		if dp.Source == nil {
+			continue
+		}
+		switch {
+		case dp.Unit == "°C" || dp.Unit == "°F":
+			temperatures = append(temperatures, dp)
+		case dp.Unit == "%" && dp.Value >= 0 && dp.Value <= 100 && isHumidity(text, dp):
+			humidities = append(humidities, dp)
+		}
+	}
+
+	var pairs []climatePair
+	for _, t := range temperatures {
+		best, bestDistance := DataPoint{}, -1
+		for _, h := range humidities {
+			distance := h.Source.Offset - t.Source.Offset
+			if distance < 0 {
+				distance = -distance
+			}
+			if distance <= pairingDistance && (bestDistance < 0 || distance < bestDistance) {
+				best, bestDistance = h, distance
+			}
+		}
+		if bestDistance >= 0 {
+			pairs = append(pairs, climatePair{temperature: t, humidity: best})
+		}
+	}
+
+	return pairs
+}
+
+// isHumidity reports whether a percentage is described as humidity either
+// just before or just after it
+func isHumidity(text string, dp DataPoint) bool {
+	if humidityPattern.MatchString(precedingText(text, dp, 40)) {
+		return true
+	}
+	end := dp.Source.Offset + dp.Source.Length
+	after := text[end:min(end+40, len(text))]
+	return humidityPattern.MatchString(after)
+}
+
+// toCelsius converts a °C or °F value to °C
+func toCelsius(value float64, unit string) float64 {
+	if unit == "°F" {
+		return (value - 32) * 5 / 9
+	}
+	return value
+}
+
+// fromCelsius converts a °C value to unit
+func fromCelsius(value float64, unit string) float64 {
+	if unit == "°F" {
+		return value*9/5 + 32
+	}
+	return value
+}
+
+// HeatIndex returns the NWS heat index in °F for a temperature in °F and a
+// relative humidity in percent
+func HeatIndex(tempF, rh float64) float64 {
+	simple := 0.5 * (tempF + 61.0 + (tempF-68.0)*1.2 + rh*0.094)
+	if (simple+tempF)/2 < 80 {
+		return simple
+	}
+
+	hi := -42.379 + 2.04901523*tempF + 10.14333127*rh -
+		0.22475541*tempF*rh - 0.00683783*tempF*tempF -
+		0.05481717*rh*rh + 0.00122874*tempF*tempF*rh +
+		0.00085282*tempF*rh*rh - 0.00000199*tempF*tempF*rh*rh
+
+	switch {
+	case rh < 13 && tempF >= 80 && tempF <= 112:
+		hi -= ((13 - rh) / 4) * math.Sqrt((17-math.Abs(tempF-95))/17)
+	case rh > 85 && tempF >= 80 && tempF <= 87:
+		hi += ((rh - 85) / 10) * ((87 - tempF) / 5)
+	}
+
+	return hi
+}
+
+// DewPoint returns the dew point in °C for a temperature in °C and a relative
+// humidity in percent, using the Magnus formula
+func DewPoint(tempC, rh float64) float64 {
+	const a, b = 17.625, 243.04
+	gamma := math.Log(rh/100) + a*tempC/(b+tempC)
+	return b * gamma / (a - gamma)
+}
+
+// heatIndexDerivation computes the heat index of paired temperature and humidity
+type heatIndexDerivation struct{}
+
+func (heatIndexDerivation) Name() string { return "heat_index" }
+
+func (heatIndexDerivation) Derive(text string, points []DataPoint) []DataPoint {
+	var derived []DataPoint
+	for _, p := range pairTemperatureHumidity(text, points) {
+		tempF := fromCelsius(toCelsius(p.temperature.Value, p.temperature.Unit), "°F")
+		hiF := HeatIndex(tempF, p.humidity.Value)
+		value := fromCelsius(toCelsius(hiF, "°F"), p.temperature.Unit)
+
+		derived = append(derived, DataPoint{
+			Value:    math.Round(value*10) / 10,
+			Unit:     p.temperature.Unit,
+			Label:    "Heat index",
+			Date:     p.temperature.Date,
+			Section:  p.temperature.Section,
+			Series:   "heat_index",
+			Inputs:   []string{p.temperature.ID, p.humidity.ID},
+			Location: p.temperature.Location,
+			Source:   p.temperature.Source,
+		})
+	}
+	return derived
+}
+
+// dewPointDerivation computes the dew point of paired temperature and humidity
+type dewPointDerivation struct{}
+
+func (dewPointDerivation) Name() string { return "dew_point" }
+
+func (dewPointDerivation) Derive(text string, points []DataPoint) []DataPoint {
+	var derived []DataPoint
+	for _, p := range pairTemperatureHumidity(text, points) {
+		if p.humidity.Value <= 0 {
+			continue
+		}
+		dewC := DewPoint(toCelsius(p.temperature.Value, p.temperature.Unit), p.humidity.Value)
+		value := fromCelsius(dewC, p.temperature.Unit)
+
+		derived = append(derived, DataPoint{
+			Value:    math.Round(value*10) / 10,
+			Unit:     p.temperature.Unit,
+			Label:    "Dew point",
+			Date:     p.temperature.Date,
+			Section:  p.temperature.Section,
+			Series:   "dew_point",
+			Inputs:   []string{p.temperature.ID, p.humidity.ID},
+			Location: p.temperature.Location,
+			Source:   p.temperature.Source,
+		})
+	}
+	return derived
+}
+
+// degreeDaysDerivation computes heating and cooling degree-days from dated
+// temperatures. Temperatures on the same date are averaged into a daily mean
+// after converting them to °C, so mixed °C and °F series aggregate correctly.
+type degreeDaysDerivation struct{}
+
+func (degreeDaysDerivation) Name() string { return "degree_days" }
+
+func (degreeDaysDerivation) Derive(text string, points []DataPoint) []DataPoint {
+	type day struct {
+		sum    float64
+		count  int
+		inputs []string
+	}
+	days := make(map[string]*day)
+
+	for _, dp := range points {
+		if dp.Date == "" || (dp.Unit != "°C" && dp.Unit != "°F") {
+			continue
+		}
+		d, ok := days[dp.Date]
+		if !ok {
+			d = &day{}
+			days[dp.Date] = d
+		}
+		d.sum += toCelsius(dp.Value, dp.Unit)
+		d.count++
+		d.inputs = append(d.inputs, dp.ID)
+	}
+
+	// A single dated temperature is not a daily series
+	if len(days) < 2 {
+		return nil
+	}
+
+	dates := make([]string, 0, len(days))
+	for date := range days {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	var derived []DataPoint
+	for _, date := range dates {
+		d := days[date]
+		mean := d.sum / float64(d.count)
+
+		derived = append(derived,
+			DataPoint{
+				Value:  math.Round(math.Max(0, degreeDayBaseC-mean)*10) / 10,
+				Unit:   "°C·d",
+				Label:  fmt.Sprintf("Heating degree-days %s", date),
+				Date:   date,
+				Series: "degree_days.heating",
+				Inputs: d.inputs,
+			},
+			DataPoint{
+				Value:  math.Round(math.Max(0, mean-degreeDayBaseC)*10) / 10,
+				Unit:   "°C·d",
+				Label:  fmt.Sprintf("Cooling degree-days %s", date),
+				Date:   date,
+				Series: "degree_days.cooling",
+				Inputs: d.inputs,
+			},
+		)
+	}
+	return derived
+}
@@ -0,0 +1,107 @@
+package util
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestHeatIndex(t *testing.T) {
+	// Values from the NWS heat index chart, which rounds to whole degrees
+	tests := []struct {
+		tempF, rh, want float64
+	}{
+		{80, 40, 80},
+		{90, 60, 100},
+		{96, 50, 108},
+		{100, 40, 109},
+		{86, 90, 105},
+		{70, 50, 69},
+	}
+
+	for _, tt := range tests {
+		if got := HeatIndex(tt.tempF, tt.rh); math.Abs(got-tt.want) > 1 {
+			t.Errorf("HeatIndex(%v, %v) = %.1f, want %v", tt.tempF, tt.rh, got, tt.want)
+		}
+	}
+}
+
+func TestDewPoint(t *testing.T) {
+	tests := []struct {
+		tempC, rh, want float64
+	}{
+		{25, 60, 16.7},
+		{20, 50, 9.3},
+		{30, 100, 30},
+		{0, 80, -3.0},
+	}
+
+	for _, tt := range tests {
+		if got := DewPoint(tt.tempC, tt.rh); math.Abs(got-tt.want) > 0.1 {
+			t.Errorf("DewPoint(%v, %v) = %.2f, want %v", tt.tempC, tt.rh, got, tt.want)
+		}
+	}
+}
+
+func TestDegreeDays(t *testing.T) {
+	points := []DataPoint{
+		{ID: "p1", Value: 8, Unit: "°C", Date: "2024-01-01"},
+		{ID: "p2", Value: 12, Unit: "°C", Date: "2024-01-01"},
+		{ID: "p3", Value: 77, Unit: "°F", Date: "2024-07-01"},
+		{ID: "p4", Value: 25, Unit: "°C", Date: "2024-07-01"},
+		{ID: "p5", Value: 30, Unit: "°C"},
+	}
+
+	got := make(map[string]float64)
+	for _, dp := range (degreeDaysDerivation{}).Derive("", points) {
+		got[dp.Series+" "+dp.Date] = dp.Value
+	}
+	want := map[string]float64{
+		"degree_days.heating 2024-01-01": 8,
+		"degree_days.cooling 2024-01-01": 0,
+		"degree_days.heating 2024-07-01": 0,
+		"degree_days.cooling 2024-07-01": 7,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s = %v, want %v", key, got[key], value)
+		}
+	}
+
+	if derived := (degreeDaysDerivation{}).Derive("", points[:2]); derived != nil {
+		t.Errorf("a single day gave %d degree-day points, want none", len(derived))
+	}
+}
+
+func TestDateNear(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		value string // the text of the point, located in text
+		want  string
+	}{
+		{"ISO date before", "On 2024-03-12 it reached 31 °C", "31 °C", "2024-03-12"},
+		{"Day month year", "Rainfall of 5 mm on 12 March 2024", "5 mm", "2024-03-12"},
+		{"Month day year", "Rainfall of 5 mm on Mar. 5, 2024", "5 mm", "2024-03-05"},
+		{"Nearest of two", "2024-01-01 was cold; 2024-01-02 had 4 mm", "4 mm", "2024-01-02"},
+		{"Other line", "Measured 2024-03-12\nRainfall of 5 mm", "5 mm", ""},
+		{"Invalid month", "On 2024-13-01 it reached 31 °C", "31 °C", ""},
+		{"No date", "Rainfall of 5 mm", "5 mm", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dp := DataPoint{Source: &Provenance{Offset: strings.Index(tt.text, tt.value), Length: len(tt.value)}}
+			if got := dateNear(tt.text, dp); got != tt.want {
+				t.Errorf("dateNear() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := dateNear("On 2024-03-12", DataPoint{}); got != "" {
+		t.Errorf("dateNear() without a source = %q, want empty", got)
+	}
+}
@@ -0,0 +1,360 @@
+package util
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed config/standards.json
+var defaultStandardsCatalogue []byte
+
+// Averaging periods understood by the compliance evaluator
+const (
+	PeriodInstant = "instant"
+	Period1h      = "1h"
+	Period8h      = "8h"
+	Period24h     = "24h"
+	PeriodAnnual  = "annual"
+)
+
+// rollingReadings is how many consecutive readings make up an 8h mean. Points
+// carry a date but no time of day, so readings are taken to be hourly.
+const rollingReadings = 8
+
+// StandardsCatalogue lists the regulatory limits data is checked against
+type StandardsCatalogue struct {
+	Standards []Standard `json:"standards"`
+}
+
+// Standard is a single regulatory limit for one parameter
+type Standard struct {
+	ID              string   `json:"id"`
+	Parameter       string   `json:"parameter"`
+	Keywords        []string `json:"keywords,omitempty"`
+	Unit            string   `json:"unit"`
+	Limit           float64  `json:"limit"`
+	AveragingPeriod string   `json:"averaging_period"`
+	Jurisdiction    string   `json:"jurisdiction"`
+}
+
+// Exceedance is a value, or an average over the standard's period, above a limit
+type Exceedance struct {
+	Standard     string   `json:"standard"`
+	Parameter    string   `json:"parameter"`
+	Jurisdiction string   `json:"jurisdiction"`
+	Limit        float64  `json:"limit"`
+	Period       string   `json:"period"`
+	Window       string   `json:"window,omitempty"`
+	Value        float64  `json:"value"`
+	PointIDs     []string `json:"point_ids"`
+}
+
+// UnitCompliance is the outcome of the compliance checks for one unit
+type UnitCompliance struct {
+	Checked     int          `json:"checked"`
+	Standards   []string     `json:"standards"`
+	Exceedances []Exceedance `json:"exceedances"`
+}
+
+var (
+	standardsOnce      sync.Once
+	standardsCatalogue StandardsCatalogue
+)
+
+// LoadStandardsCatalogue parses a standards catalogue file
+func LoadStandardsCatalogue(path string) (StandardsCatalogue, error) {
+	catalogueBytes, err := os.ReadFile(path)
+	if err != nil {
+		return StandardsCatalogue{}, fmt.Errorf("reading standards catalogue: %w", err)
+	}
+	return parseStandardsCatalogue(catalogueBytes)
+}
+
+func parseStandardsCatalogue(catalogueBytes []byte) (StandardsCatalogue, error) {
+	var catalogue StandardsCatalogue
+	if err := json.Unmarshal(catalogueBytes, &catalogue); err != nil {
+		return StandardsCatalogue{}, fmt.Errorf("unmarshaling standards catalogue: %w", err)
+	}
+	for _, s := range catalogue.Standards {
+		switch s.AveragingPeriod {
+		case PeriodInstant, Period1h, Period8h, Period24h, PeriodAnnual:
+		default:
+			return StandardsCatalogue{}, fmt.Errorf("standard %s: unsupported averaging period %q", s.ID, s.AveragingPeriod)
+		}
+	}
+	return catalogue, nil
+}
+
+// CurrentStandardsCatalogue returns the catalogue in use. It is read from the
+// file named by BIOTREE_STANDARDS_CONFIG, falling back to the built-in limits.
+func CurrentStandardsCatalogue() StandardsCatalogue {
+	standardsOnce.Do(func() {
+		if path := os.Getenv("BIOTREE_STANDARDS_CONFIG"); path != "" {
+			catalogue, err := LoadStandardsCatalogue(path)
+			if err == nil {
+				standardsCatalogue = catalogue
+				return
+			}
+			log.Printf("Failed to load standards catalogue %s, using defaults: %v", path, err)
+		}
+
+		catalogue, err := parseStandardsCatalogue(defaultStandardsCatalogue)
+		if err != nil {
+			log.Printf("Failed to parse built-in standards catalogue: %v", err)
+		}
+		standardsCatalogue = catalogue
+	})
+	return standardsCatalogue
+}
+
+// Find returns the standards for unit, optionally restricted to a jurisdiction
+// or a single standard ID
+func (c StandardsCatalogue) Find(unit, jurisdiction string) []Standard {
+	var found []Standard
+	for _, s := range c.Standards {
+		if s.Unit != unit {
+			continue
+		}
+		if jurisdiction != "" && !strings.EqualFold(s.Jurisdiction, jurisdiction) && s.ID != jurisdiction {
+			continue
+		}
+		found = append(found, s)
+	}
+	return found
+}
+
+// Applies reports whether the standard covers a data point. Standards with
+// keywords only apply to points whose label or context mentions one of them.
+// Chemical symbols such as "CO" or "O3" are matched case-sensitively as whole
+// words, so they don't match inside "CO2", "recorded" or "NO3"; plain words
+// are matched anywhere, ignoring case.
+func (s Standard) Applies(dp DataPoint) bool {
+	if dp.Unit != s.Unit {
+		return false
+	}
+	if len(s.Keywords) == 0 {
+		return true
+	}
+
+	described := dp.Label
+	if dp.Source != nil {
+		described += " " + dp.Source.Context
+	}
+	lowered := strings.ToLower(described)
+	for _, k := range s.Keywords {
+		if isSymbolKeyword(k) {
+			if containsWord(described, k) {
+				return true
+			}
+		} else if strings.Contains(lowered, strings.ToLower(k)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSymbolKeyword reports whether a keyword is a symbol or abbreviation, i.e.
+// contains an upper-case letter or a digit
+func isSymbolKeyword(keyword string) bool {
+	for _, r := range keyword {
+		if unicode.IsUpper(r) || unicode.IsNumber(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsWord reports whether word occurs in text with no letter or digit
+// directly before or after it
+func containsWord(text, word string) bool {
+	for start := 0; start < len(text); {
+		i := strings.Index(text[start:], word)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(word)
+
+		before, _ := utf8.DecodeLastRuneInString(text[:i])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if (i == 0 || !isWordRune(before)) && (end == len(text) || !isWordRune(after)) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		start = i + size
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// EvaluateCompliance checks points against the catalogue and returns the
+// results per unit. Points are compared individually for instant and 1h
+// limits. For 8h limits the highest mean of 8 consecutive readings on each
+// day is compared, and days with fewer readings are not evaluated. For 24h
+// limits dated points are averaged per day and for annual limits per year,
+// with undated points averaged together. Derived points,
+// such as AQI sub-indices, are not measurements and are left out.
+func EvaluateCompliance(points []DataPoint, catalogue StandardsCatalogue, jurisdiction string) map[string]UnitCompliance {
+	results := make(map[string]UnitCompliance)
+	points = MeasuredPoints(points)
+
+	units := make(map[string]struct{})
+	for _, dp := range points {
+		units[dp.Unit] = struct{}{}
+	}
+
+	for unit := range units {
+		standards := catalogue.Find(unit, jurisdiction)
+		if len(standards) == 0 {
+			continue
+		}
+
+		result := UnitCompliance{Standards: []string{}, Exceedances: []Exceedance{}}
+		checked := make(map[string]struct{})
+
+		for _, s := range standards {
+			result.Standards = append(result.Standards, s.ID)
+
+			var applicable []DataPoint
+			for _, dp := range points {
+				if s.Applies(dp) {
+					applicable = append(applicable, dp)
+					checked[dp.ID] = struct{}{}
+				}
+			}
+
+			for _, window := range averagingWindows(applicable, s.AveragingPeriod) {
+				if window.value > s.Limit {
+					result.Exceedances = append(result.Exceedances, Exceedance{
+						Standard:     s.ID,
+						Parameter:    s.Parameter,
+						Jurisdiction: s.Jurisdiction,
+						Limit:        s.Limit,
+						Period:       s.AveragingPeriod,
+						Window:       window.name,
+						Value:        window.value,
+						PointIDs:     window.ids,
+					})
+				}
+			}
+		}
+
+		result.Checked = len(checked)
+		results[unit] = result
+	}
+
+	return results
+}
+
+type averagingWindow struct {
+	name  string
+	value float64
+	ids   []string
+}
+
+// averagingWindows groups points by the averaging period of a standard
+func averagingWindows(points []DataPoint, period string) []averagingWindow {
+	if period == Period8h {
+		return rollingWindows(points, rollingReadings)
+	}
+
+	keyOf := func(dp DataPoint) (string, bool) {
+		switch period {
+		case Period24h:
+			if dp.Date != "" {
+				return dp.Date, true
+			}
+			return "", false
+		case PeriodAnnual:
+			if len(dp.Date) >= 4 {
+				return dp.Date[:4], true
+			}
+			return "all", true
+		default:
+			return "", false
+		}
+	}
+
+	var windows []averagingWindow
+	groups := make(map[string]*averagingWindow)
+	var order []string
+	counts := make(map[string]int)
+
+	for _, dp := range points {
+		key, grouped := keyOf(dp)
+		if !grouped {
+			windows = append(windows, averagingWindow{value: dp.Value, ids: []string{dp.ID}})
+			continue
+		}
+		w, ok := groups[key]
+		if !ok {
+			w = &averagingWindow{name: key}
+			groups[key] = w
+			order = append(order, key)
+		}
+		w.value += dp.Value
+		w.ids = append(w.ids, dp.ID)
+		counts[key]++
+	}
+
+	sort.Strings(order)
+	for _, key := range order {
+		w := groups[key]
+		w.value /= float64(counts[key])
+		windows = append(windows, *w)
+	}
+
+	return windows
+}
+
+// rollingWindows returns, for each day, the window of n consecutive readings
+// with the highest mean. Undated readings are treated as one day.
+func rollingWindows(points []DataPoint, n int) []averagingWindow {
+	days := make(map[string][]DataPoint)
+	var order []string
+	for _, dp := range points {
+		if _, ok := days[dp.Date]; !ok {
+			order = append(order, dp.Date)
+		}
+		days[dp.Date] = append(days[dp.Date], dp)
+	}
+	sort.Strings(order)
+
+	var windows []averagingWindow
+	for _, day := range order {
+		readings := days[day]
+		if len(readings) < n {
+			continue
+		}
+
+		var sum float64
+		best, bestStart := 0.0, -1
+		for i, dp := range readings {
+			sum += dp.Value
+			if i >= n {
+				sum -= readings[i-n].Value
+			}
+			if i >= n-1 && (bestStart < 0 || sum > best) {
+				best, bestStart = sum, i-n+1
+			}
+		}
+
+		w := averagingWindow{name: day, value: best / float64(n)}
+		for _, dp := range readings[bestStart : bestStart+n] {
+			w.ids = append(w.ids, dp.ID)
+		}
+		windows = append(windows, w)
+	}
+	return windows
+}
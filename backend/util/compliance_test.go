@@ -0,0 +1,129 @@
+package util
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestStandardApplies(t *testing.T) {
+	catalogue := CurrentStandardsCatalogue()
+	standard := func(id string) Standard {
+		for _, s := range catalogue.Standards {
+			if s.ID == id {
+				return s
+			}
+		}
+		t.Fatalf("standard %s not configured", id)
+		return Standard{}
+	}
+
+	tests := []struct {
+		name     string
+		standard string
+		point    DataPoint
+		want     bool
+	}{
+		{"CO symbol", "us-naaqs-co-8h", DataPoint{Unit: "ppm", Label: "CO"}, true},
+		{"Carbon monoxide in context", "us-naaqs-co-8h", DataPoint{Unit: "ppm", Source: &Provenance{Context: "Carbon monoxide peaked at 12 ppm"}}, true},
+		{"CO2 is not CO", "us-naaqs-co-8h", DataPoint{Unit: "ppm", Label: "CO2"}, false},
+		{"CO₂ is not CO", "us-naaqs-co-8h", DataPoint{Unit: "ppm", Source: &Provenance{Context: "CO₂ concentration recorded at 415 ppm"}}, false},
+		{"Lower-case co inside words", "us-naaqs-co-8h", DataPoint{Unit: "ppm", Source: &Provenance{Context: "the concentration recorded was 10 ppm"}}, false},
+		{"O3 symbol", "us-naaqs-o3-8h", DataPoint{Unit: "ppm", Source: &Provenance{Context: "O3 (8-hour) reached 0.08 ppm"}}, true},
+		{"NO3 is not O3", "us-naaqs-o3-8h", DataPoint{Unit: "ppm", Label: "NO3"}, false},
+		{"Nitrate word ignores case", "who-water-nitrate", DataPoint{Unit: "mg/L", Label: "Nitrate"}, true},
+		{"Other unit", "us-naaqs-co-8h", DataPoint{Unit: "mg/L", Label: "CO"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := standard(tt.standard).Applies(tt.point); got != tt.want {
+				t.Errorf("Applies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateComplianceIgnoresCO2(t *testing.T) {
+	points := []DataPoint{
+		{ID: "p1", Value: 415, Unit: "ppm", Source: &Provenance{Context: "CO₂ concentration recorded at 415 ppm"}},
+	}
+	for i := 2; i <= 9; i++ {
+		points = append(points, DataPoint{ID: fmt.Sprintf("p%d", i), Value: 12, Unit: "ppm", Source: &Provenance{Context: "CO levels reached 12 ppm"}})
+	}
+
+	result := EvaluateCompliance(points, CurrentStandardsCatalogue(), "US")["ppm"]
+	if len(result.Exceedances) != 1 {
+		t.Fatalf("got %d exceedances, want 1: %+v", len(result.Exceedances), result.Exceedances)
+	}
+	got := result.Exceedances[0]
+	if got.Standard != "us-naaqs-co-8h" || len(got.PointIDs) != 8 || slices.Contains(got.PointIDs, "p1") {
+		t.Errorf("exceedance = %+v, want us-naaqs-co-8h for the CO readings only", got)
+	}
+}
+
+func TestEvaluateCompliance8hWindows(t *testing.T) {
+	hourly := func(date string, values ...float64) []DataPoint {
+		var points []DataPoint
+		for _, v := range values {
+			points = append(points, DataPoint{ID: fmt.Sprintf("%s-%d", date, len(points)), Value: v, Unit: "ppm", Date: date, Label: "CO"})
+		}
+		return points
+	}
+
+	tests := []struct {
+		name    string
+		points  []DataPoint
+		want    float64
+		wantIDs []string
+	}{
+		{"single reading", hourly("2024-01-01", 50), 0, nil},
+		{"seven readings", hourly("2024-01-01", 50, 50, 50, 50, 50, 50, 50), 0, nil},
+		{"below the limit", hourly("2024-01-01", 8, 8, 8, 8, 8, 8, 8, 8, 8), 0, nil},
+		{
+			"highest window",
+			hourly("2024-01-01", 1, 10, 10, 10, 10, 10, 10, 10, 10, 1),
+			10,
+			[]string{"2024-01-01-1", "2024-01-01-2", "2024-01-01-3", "2024-01-01-4", "2024-01-01-5", "2024-01-01-6", "2024-01-01-7", "2024-01-01-8"},
+		},
+		{"readings split across days", append(hourly("2024-01-01", 20, 20, 20, 20), hourly("2024-01-02", 20, 20, 20, 20)...), 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EvaluateCompliance(tt.points, CurrentStandardsCatalogue(), "us-naaqs-co-8h")["ppm"].Exceedances
+			if tt.wantIDs == nil {
+				if len(got) != 0 {
+					t.Errorf("got exceedances %+v, want none", got)
+				}
+				return
+			}
+			if len(got) != 1 || got[0].Value != tt.want || !slices.Equal(got[0].PointIDs, tt.wantIDs) {
+				t.Errorf("got exceedances %+v, want one of %v over %v", got, tt.want, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestParseStandardsCatalogueRejectsUnknownPeriod(t *testing.T) {
+	_, err := parseStandardsCatalogue([]byte(`{"standards": [{"id": "x", "unit": "ppm", "limit": 1, "averaging_period": "15min"}]}`))
+	if err == nil {
+		t.Fatal("expected an error for an unsupported averaging period")
+	}
+	if _, err := parseStandardsCatalogue(defaultStandardsCatalogue); err != nil {
+		t.Fatalf("built-in catalogue: %v", err)
+	}
+}
+
+func TestEvaluateComplianceIgnoresDerivedPoints(t *testing.T) {
+	points := []DataPoint{
+		{ID: "p1", Value: 3, Unit: "µg/m³", Source: &Provenance{Context: "PM2.5 was 3 µg/m³"}},
+		{ID: "d1", Value: 400, Unit: "µg/m³", Series: "derived.test", Source: &Provenance{Context: "PM2.5 was 3 µg/m³"}},
+	}
+
+	for unit, result := range EvaluateCompliance(points, CurrentStandardsCatalogue(), "") {
+		for _, e := range result.Exceedances {
+			t.Errorf("%s exceedance %+v from a derived point", unit, e)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package util
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxLinkDistance is how many bytes may separate a measurement from the
+// coordinate it is linked to
+const maxLinkDistance = 200
+
+var (
+	// Decimal degrees need at least three decimals or a hemisphere letter so
+	// that ordinary comma-separated values are not read as coordinates
+	decimalCoordinatePattern = regexp.MustCompile(`(-?\d{1,2}(?:\.\d+)?)\s*°?\s*([NSns])?\s*,\s*(-?\d{1,3}(?:\.\d+)?)\s*°?\s*([EWew])?`)
+	dmsCoordinatePattern     = regexp.MustCompile(`(\d{1,2})\s*°\s*(?:(\d{1,2}(?:\.\d+)?)\s*[′'])?\s*(?:(\d{1,2}(?:\.\d+)?)\s*(?:″|"|′′|''))?\s*([NSns])[\s,;]*(\d{1,3})\s*°\s*(?:(\d{1,2}(?:\.\d+)?)\s*[′'])?\s*(?:(\d{1,2}(?:\.\d+)?)\s*(?:″|"|′′|''))?\s*([EWew])`)
+)
+
+// Coordinate is a latitude and longitude in decimal degrees found in a text
+type Coordinate struct {
+	Lat    float64     `json:"lat"`
+	Lon    float64     `json:"lon"`
+	Source *Provenance `json:"source,omitempty"`
+}
+
+// FindCoordinates returns every coordinate pair written in text, in decimal
+// ("-1.2921, 36.8219") or degrees-minutes-seconds ("1°17′S 36°49′E") form,
+// ordered by position
+func FindCoordinates(text string) []Coordinate {
+	var coords []Coordinate
+	taken := func(start, end int) bool {
+		for _, c := range coords {
+			if start < c.Source.Offset+c.Source.Length && end > c.Source.Offset {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, m := range dmsCoordinatePattern.FindAllStringSubmatchIndex(text, -1) {
+		lat := dmsToDecimal(group(text, m, 1), group(text, m, 2), group(text, m, 3), group(text, m, 4))
+		lon := dmsToDecimal(group(text, m, 5), group(text, m, 6), group(text, m, 7), group(text, m, 8))
+		if validCoordinate(lat, lon) {
+			coords = append(coords, newCoordinate(text, lat, lon, m[0], m[1]))
+		}
+	}
+
+	for _, m := range decimalCoordinatePattern.FindAllStringSubmatchIndex(text, -1) {
+		if taken(m[0], m[1]) {
+			continue
+		}
+		latText, latHemisphere := group(text, m, 1), group(text, m, 2)
+		lonText, lonHemisphere := group(text, m, 3), group(text, m, 4)
+
+		precise := decimals(latText) >= 3 && decimals(lonText) >= 3
+		if !precise && (latHemisphere == "" || lonHemisphere == "") {
+			continue
+		}
+		// A number glued to a preceding letter or digit is not a coordinate
+		if m[0] > 0 && isWordByte(text[m[0]-1]) {
+			continue
+		}
+
+		lat, _ := strconv.ParseFloat(latText, 64)
+		lon, _ := strconv.ParseFloat(lonText, 64)
+		lat = applyHemisphere(lat, latHemisphere)
+		lon = applyHemisphere(lon, lonHemisphere)
+		if validCoordinate(lat, lon) {
+			coords = append(coords, newCoordinate(text, lat, lon, m[0], m[1]))
+		}
+	}
+
+	sort.Slice(coords, func(i, j int) bool {
+		return coords[i].Source.Offset < coords[j].Source.Offset
+	})
+	return coords
+}
+
+// nearestCoordinate returns the coordinate closest to the point, within
+// maxLinkDistance bytes, preferring coordinates on the same line
+func nearestCoordinate(text string, coords []Coordinate, dp DataPoint) *Coordinate {
+	if dp.Source == nil {
+		return nil
+	}
+
+	var best *Coordinate
+	bestDistance, bestSameLine := 0, false
+	for i := range coords {
+		c := &coords[i]
+		distance := 0
+		switch {
+		case c.Source.Offset+c.Source.Length <= dp.Source.Offset:
+			distance = dp.Source.Offset - (c.Source.Offset + c.Source.Length)
+		case c.Source.Offset >= dp.Source.Offset+dp.Source.Length:
+			distance = c.Source.Offset - (dp.Source.Offset + dp.Source.Length)
+		default:
+			// The point is part of the coordinate itself
+			continue
+		}
+		if distance > maxLinkDistance {
+			continue
+		}
+
+		sameLine := c.Source.Line == dp.Source.Line
+		if best == nil || (sameLine && !bestSameLine) || (sameLine == bestSameLine && distance < bestDistance) {
+			best, bestDistance, bestSameLine = c, distance, sameLine
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+	return &Coordinate{Lat: best.Lat, Lon: best.Lon}
+}
+
+// insideCoordinate reports whether the point lies within a coordinate span
+func insideCoordinate(coords []Coordinate, dp DataPoint) bool {
+	if dp.Source == nil {
+		return false
+	}
+	for _, c := range coords {
+		if dp.Source.Offset >= c.Source.Offset && dp.Source.Offset < c.Source.Offset+c.Source.Length {
+			return true
+		}
+	}
+	return false
+}
+
+func newCoordinate(text string, lat, lon float64, start, end int) Coordinate {
+	return Coordinate{
+		Lat: math.Round(lat*1e6) / 1e6,
+		Lon: math.Round(lon*1e6) / 1e6,
+		Source: &Provenance{
+			Offset:  start,
+			Length:  end - start,
+			Line:    strings.Count(text[:start], "\n") + 1,
+			Context: contextAround(text, start, end),
+		},
+	}
+}
+
+func group(text string, m []int, i int) string {
+	if m[2*i] < 0 {
+		return ""
+	}
+	return text[m[2*i]:m[2*i+1]]
+}
+
+func dmsToDecimal(degrees, minutes, seconds, hemisphere string) float64 {
+	d, _ := strconv.ParseFloat(degrees, 64)
+	m, _ := strconv.ParseFloat(minutes, 64)
+	s, _ := strconv.ParseFloat(seconds, 64)
+	return applyHemisphere(d+m/60+s/3600, hemisphere)
+}
+
+func applyHemisphere(value float64, hemisphere string) float64 {
+	switch strings.ToUpper(hemisphere) {
+	case "S", "W":
+		return -math.Abs(value)
+	}
+	return value
+}
+
+func decimals(number string) int {
+	if i := strings.IndexByte(number, '.'); i >= 0 {
+		return len(number) - i - 1
+	}
+	return 0
+}
+
+func validCoordinate(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b == '.' || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
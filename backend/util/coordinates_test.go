@@ -0,0 +1,94 @@
+package util
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFindCoordinates(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Coordinate // only Lat and Lon are compared
+	}{
+		{"Signed decimal", "Station at -1.2921, 36.8219 recorded", []Coordinate{{Lat: -1.2921, Lon: 36.8219}}},
+		{"Decimal with S and E", "Sampled at 1.2921 S, 36.8219 E", []Coordinate{{Lat: -1.2921, Lon: 36.8219}}},
+		{"Decimal with degrees and W", "New York, 40.7128° N, 74.0060° W", []Coordinate{{Lat: 40.7128, Lon: -74.006}}},
+		{"Hemisphere without decimals", "Grid 3 S, 37 E", []Coordinate{{Lat: -3, Lon: 37}}},
+		{"DMS", "Nairobi lies at 1°17′S 36°49′E", []Coordinate{{Lat: -1.283333, Lon: 36.816667}}},
+		{"DMS with seconds and W", `Site 33°52'4"N 118°12'26"W`, []Coordinate{{Lat: 33.867778, Lon: -118.207222}}},
+		{"Two coordinates in order", "From 10.1234, 20.5678 to 11.1234, 21.5678", []Coordinate{{Lat: 10.1234, Lon: 20.5678}, {Lat: 11.1234, Lon: 21.5678}}},
+		{"Ordinary values", "Readings were 12.5, 30.2 and 14.1", nil},
+		{"Latitude out of range", "Point 95.1234, 36.8219", nil},
+		{"Longitude out of range", "Point 45.1234, 190.4567", nil},
+		{"DMS out of range", "Point 95°10′N 10°00′E", nil},
+		{"Glued to a word", "version v1.2921, 36.8219", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FindCoordinates(tt.text)
+			if len(got) != len(tt.want) {
+				t.Fatalf("FindCoordinates() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if math.Abs(got[i].Lat-tt.want[i].Lat) > 1e-6 || math.Abs(got[i].Lon-tt.want[i].Lon) > 1e-6 {
+					t.Errorf("coordinate %d = (%v, %v), want (%v, %v)", i, got[i].Lat, got[i].Lon, tt.want[i].Lat, tt.want[i].Lon)
+				}
+				if got[i].Source == nil || got[i].Source.Length == 0 {
+					t.Errorf("coordinate %d has no source span", i)
+				}
+			}
+		})
+	}
+}
+
+func TestNearestCoordinate(t *testing.T) {
+	text := "Site A at -1.2921, 36.8219\n" +
+		"Rainfall was 5 mm at 0.5123, 35.2698 today\n" +
+		"Temperature was 21 °C\n" +
+		strings.Repeat("filler ", 40) + "\nHumidity was 80 %"
+	coords := FindCoordinates(text)
+	if len(coords) != 2 {
+		t.Fatalf("found %d coordinates, want 2", len(coords))
+	}
+
+	point := func(value string) DataPoint {
+		offset := strings.Index(text, value)
+		return DataPoint{Source: &Provenance{
+			Offset: offset,
+			Length: len(value),
+			Line:   strings.Count(text[:offset], "\n") + 1,
+		}}
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  *Coordinate
+	}{
+		{"Same line wins over a closer line", "5 mm", &Coordinate{Lat: 0.5123, Lon: 35.2698}},
+		{"Nearest on another line", "21 °C", &Coordinate{Lat: 0.5123, Lon: 35.2698}},
+		{"Too far away", "80 %", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nearestCoordinate(text, coords, point(tt.value))
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("nearestCoordinate() = %+v, want none", got)
+			case tt.want != nil && (got == nil || got.Lat != tt.want.Lat || got.Lon != tt.want.Lon):
+				t.Errorf("nearestCoordinate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if !insideCoordinate(coords, point("36.8219")) {
+		t.Error("insideCoordinate() = false for a number inside a coordinate")
+	}
+	if insideCoordinate(coords, point("5 mm")) {
+		t.Error("insideCoordinate() = true for a measurement")
+	}
+}
@@ -0,0 +1,299 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+// Audit actions
+const (
+	AuditEdit   = "edit"
+	AuditDelete = "delete"
+	AuditAdd    = "add"
+)
+
+var (
+	// ErrPointNotFound is returned when a dataset has no point with the given ID
+	ErrPointNotFound = errors.New("data point not found")
+	// ErrInvalidPoint is returned for corrections that would store a bad point
+	ErrInvalidPoint = errors.New("invalid data point")
+	// ErrHasCorrections is returned when re-extracting a dataset would discard
+	// manual corrections made since its last extraction
+	ErrHasCorrections = errors.New("dataset has manual corrections")
+)
+
+// maxLabelLength caps the length of a point label set by a correction
+const maxLabelLength = 200
+
+// correctionsMu serialises corrections so concurrent edits of a dataset do not
+// overwrite each other
+var correctionsMu sync.Mutex
+
+// PointEdit holds the fields of a point to change; nil fields are kept
+type PointEdit struct {
+	Value *float64 `json:"value,omitempty"`
+	Unit  *string  `json:"unit,omitempty"`
+	Label *string  `json:"label,omitempty"`
+}
+
+// AuditEntry records one manual correction of a dataset
+type AuditEntry struct {
+	Action  string     `json:"action"`
+	PointID string     `json:"point_id"`
+	User    string     `json:"user"`
+	At      time.Time  `json:"at"`
+	Old     *DataPoint `json:"old,omitempty"`
+	New     *DataPoint `json:"new,omitempty"`
+}
+
+// EditPoint changes the value, unit or label of a point of a dataset
+func EditPoint(st store.Store, dataset, id string, edit PointEdit, user string) (DataPoint, error) {
+	if edit.Value != nil && (math.IsNaN(*edit.Value) || math.IsInf(*edit.Value, 0)) {
+		return DataPoint{}, fmt.Errorf("%w: value must be a finite number", ErrInvalidPoint)
+	}
+	if edit.Unit != nil && strings.TrimSpace(*edit.Unit) == "" {
+		return DataPoint{}, fmt.Errorf("%w: unit must not be empty", ErrInvalidPoint)
+	}
+	if edit.Label != nil {
+		if err := validateLabel(*edit.Label); err != nil {
+			return DataPoint{}, err
+		}
+	}
+
+	correctionsMu.Lock()
+	defer correctionsMu.Unlock()
+
+	points, err := ReadDataset(st, dataset)
+	if err != nil {
+		return DataPoint{}, err
+	}
+	AssignPointIDs(points)
+	i := indexOfPoint(points, id)
+	if i < 0 {
+		return DataPoint{}, ErrPointNotFound
+	}
+
+	old := points[i]
+	if edit.Value != nil {
+		points[i].Value = *edit.Value
+	}
+	if edit.Unit != nil {
+		points[i].Unit = strings.TrimSpace(*edit.Unit)
+	}
+	if edit.Label != nil {
+		points[i].Label = *edit.Label
+	}
+	updated := points[i]
+
+	if err := saveCorrection(st, dataset, points, AuditEntry{Action: AuditEdit, PointID: id, User: user, Old: &old, New: &updated}); err != nil {
+		return DataPoint{}, err
+	}
+	return updated, nil
+}
+
+// DeletePoint removes a point from a dataset
+func DeletePoint(st store.Store, dataset, id, user string) error {
+	correctionsMu.Lock()
+	defer correctionsMu.Unlock()
+
+	points, err := ReadDataset(st, dataset)
+	if err != nil {
+		return err
+	}
+	AssignPointIDs(points)
+	i := indexOfPoint(points, id)
+	if i < 0 {
+		return ErrPointNotFound
+	}
+
+	old := points[i]
+	points = append(points[:i], points[i+1:]...)
+
+	return saveCorrection(st, dataset, points, AuditEntry{Action: AuditDelete, PointID: id, User: user, Old: &old})
+}
+
+// AddPoint adds a point missed by the extraction to a dataset. It gets an ID
+// of the form m<n>; provenance and derived fields are not taken from the caller.
+func AddPoint(st store.Store, dataset string, point DataPoint, user string) (DataPoint, error) {
+	if math.IsNaN(point.Value) || math.IsInf(point.Value, 0) {
+		return DataPoint{}, fmt.Errorf("%w: value must be a finite number", ErrInvalidPoint)
+	}
+	point.Unit = strings.TrimSpace(point.Unit)
+	if point.Unit == "" {
+		return DataPoint{}, fmt.Errorf("%w: unit is required", ErrInvalidPoint)
+	}
+	if err := validateLabel(point.Label); err != nil {
+		return DataPoint{}, err
+	}
+	if point.Date != "" {
+		if _, err := time.Parse("2006-01-02", point.Date); err != nil {
+			return DataPoint{}, fmt.Errorf("%w: date must be YYYY-MM-DD", ErrInvalidPoint)
+		}
+	}
+
+	correctionsMu.Lock()
+	defer correctionsMu.Unlock()
+
+	points, err := ReadDataset(st, dataset)
+	if err != nil {
+		return DataPoint{}, err
+	}
+	AssignPointIDs(points)
+
+	added := DataPoint{
+		Value:    point.Value,
+		Unit:     point.Unit,
+		Label:    point.Label,
+		Date:     point.Date,
+		Section:  point.Section,
+		Location: point.Location,
+	}
+	for n := 1; ; n++ {
+		added.ID = fmt.Sprintf("m%d", n)
+		if indexOfPoint(points, added.ID) < 0 {
+			break
+		}
+	}
+	points = append(points, added)
+
+	if err := saveCorrection(st, dataset, points, AuditEntry{Action: AuditAdd, PointID: added.ID, User: user, New: &added}); err != nil {
+		return DataPoint{}, err
+	}
+	return added, nil
+}
+
+// LoadAuditTrail returns the corrections made to a dataset, oldest first
+func LoadAuditTrail(st store.Store, dataset string) ([]AuditEntry, error) {
+	trail := []AuditEntry{}
+	if err := loadJSON(st, store.Audit, dataset, &trail); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+	return trail, nil
+}
+
+// validateLabel checks that a label is short and plain text. Labels are drawn
+// into charts, so markup characters and control characters are refused.
+func validateLabel(label string) error {
+	if utf8.RuneCountInString(label) > maxLabelLength {
+		return fmt.Errorf("%w: label is longer than %d characters", ErrInvalidPoint, maxLabelLength)
+	}
+	for _, r := range label {
+		if r == '<' || r == '>' || !unicode.IsPrint(r) {
+			return fmt.Errorf("%w: label contains %q", ErrInvalidPoint, r)
+		}
+	}
+	return nil
+}
+
+// BackfillPointIDs gives the points of a dataset extracted before points had
+// IDs the IDs the extraction gives them now, so they can be corrected
+func BackfillPointIDs(st store.Store, dataset string) error {
+	correctionsMu.Lock()
+	defer correctionsMu.Unlock()
+
+	points, err := ReadDataset(st, dataset)
+	if err != nil {
+		return err
+	}
+	if !AssignPointIDs(points) {
+		return nil
+	}
+	return WriteDataset(st, dataset, points)
+}
+
+// AssignPointIDs gives every point without an ID one in the scheme of the
+// extraction: p<n> for extracted points and d<n> for derived ones, counted
+// in order and skipping IDs already in use. It reports whether any point
+// got an ID.
+func AssignPointIDs(points []DataPoint) bool {
+	used := map[string]bool{}
+	for _, dp := range points {
+		used[dp.ID] = true
+	}
+
+	changed := false
+	extracted, derived := 0, 0
+	for i := range points {
+		prefix, n := "p", &extracted
+		if points[i].Series != "" {
+			prefix, n = "d", &derived
+		}
+		*n++
+		if points[i].ID != "" {
+			continue
+		}
+		id := fmt.Sprintf("%s%d", prefix, *n)
+		for used[id] {
+			*n++
+			id = fmt.Sprintf("%s%d", prefix, *n)
+		}
+		points[i].ID = id
+		used[id] = true
+		changed = true
+	}
+	return changed
+}
+
+// pendingCorrections reports whether a dataset was corrected after its
+// latest extraction
+func pendingCorrections(st store.Store, dataset string, versions []DatasetVersion) (bool, error) {
+	trail, err := LoadAuditTrail(st, dataset)
+	if err != nil || len(trail) == 0 {
+		return false, err
+	}
+	if len(versions) == 0 {
+		return true, nil
+	}
+	latest := versions[len(versions)-1].ExtractedAt
+	return trail[len(trail)-1].At.After(latest), nil
+}
+
+// saveCorrection writes the corrected points of a dataset, appends entry to
+// its audit trail and brings the point count and units of its metadata up to date
+func saveCorrection(st store.Store, dataset string, points []DataPoint, entry AuditEntry) error {
+	if err := WriteDataset(st, dataset, points); err != nil {
+		return err
+	}
+
+	trail, err := LoadAuditTrail(st, dataset)
+	if err != nil {
+		return err
+	}
+	entry.At = time.Now()
+	if err := saveJSON(st, store.Audit, dataset, append(trail, entry)); err != nil {
+		return err
+	}
+
+	meta, err := LoadMetadata(st, dataset)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	units, err := GetUnitsFromDataset(st, dataset)
+	if err != nil {
+		return err
+	}
+	meta.PointCount = len(points)
+	meta.Units = units
+	return SaveMetadata(st, dataset, *meta)
+}
+
+// indexOfPoint returns the index of the point with id, or -1
+func indexOfPoint(points []DataPoint, id string) int {
+	for i, dp := range points {
+		if dp.ID == id && id != "" {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,119 @@
+package util
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+func TestCorrections(t *testing.T) {
+	st := store.NewMemory()
+	dataset := storeDataset(t, st, "report.txt", "Rainfall was 5 mm and 12 °C", time.Now())
+
+	value, label := 6.0, "Rainfall"
+	edited, err := EditPoint(st, dataset, "p1", PointEdit{Value: &value, Label: &label}, "ana")
+	if err != nil {
+		t.Fatalf("EditPoint() error = %v", err)
+	}
+	if edited.Value != 6 || edited.Unit != "mm" || edited.Label != "Rainfall" {
+		t.Errorf("EditPoint() = %+v", edited)
+	}
+
+	added, err := AddPoint(st, dataset, DataPoint{Value: 3, Unit: "NTU", Source: &Provenance{Line: 9}}, "ben")
+	if err != nil {
+		t.Fatalf("AddPoint() error = %v", err)
+	}
+	if added.ID != "m1" || added.Source != nil {
+		t.Errorf("AddPoint() = %+v, want m1 without provenance", added)
+	}
+
+	if err := DeletePoint(st, dataset, "p2", "ana"); err != nil {
+		t.Fatalf("DeletePoint() error = %v", err)
+	}
+	if err := DeletePoint(st, dataset, "p2", "ana"); !errors.Is(err, ErrPointNotFound) {
+		t.Errorf("DeletePoint() twice error = %v, want ErrPointNotFound", err)
+	}
+	if _, err := AddPoint(st, dataset, DataPoint{Value: 1}, "ben"); !errors.Is(err, ErrInvalidPoint) {
+		t.Errorf("AddPoint() without unit error = %v, want ErrInvalidPoint", err)
+	}
+	for _, label := range []string{`<img src=x onerror="alert(1)">`, "Rain\x00fall", strings.Repeat("a", 201)} {
+		if _, err := EditPoint(st, dataset, "p1", PointEdit{Label: &label}, "ana"); !errors.Is(err, ErrInvalidPoint) {
+			t.Errorf("EditPoint() with label %.20q error = %v, want ErrInvalidPoint", label, err)
+		}
+	}
+	if _, err := AddPoint(st, dataset, DataPoint{Value: 1, Unit: "mm", Label: "a > b"}, "ben"); !errors.Is(err, ErrInvalidPoint) {
+		t.Errorf("AddPoint() with label a > b error = %v, want ErrInvalidPoint", err)
+	}
+	if _, err := AddPoint(st, dataset, DataPoint{Value: 1, Unit: "mm", Date: "12/03/2024"}, "ben"); !errors.Is(err, ErrInvalidPoint) {
+		t.Errorf("AddPoint() with date 12/03/2024 error = %v, want ErrInvalidPoint", err)
+	}
+
+	points, _ := ReadDataset(st, dataset)
+	if len(points) != 2 || points[0].Value != 6 || points[1].ID != "m1" {
+		t.Errorf("points = %+v", points)
+	}
+	if meta, _ := LoadMetadata(st, dataset); meta.PointCount != 2 || len(meta.Units) != 2 {
+		t.Errorf("metadata = %+v, want 2 points in mm and NTU", meta)
+	}
+
+	trail, err := LoadAuditTrail(st, dataset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trail) != 3 {
+		t.Fatalf("audit trail has %d entries, want 3", len(trail))
+	}
+	if e := trail[0]; e.Action != AuditEdit || e.User != "ana" || e.Old.Value != 5 || e.New.Value != 6 || e.At.IsZero() {
+		t.Errorf("edit entry = %+v", e)
+	}
+	if e := trail[2]; e.Action != AuditDelete || e.Old == nil || e.Old.Unit != "°C" || e.New != nil {
+		t.Errorf("delete entry = %+v", e)
+	}
+}
+
+func TestCorrectionsAssignLegacyIDs(t *testing.T) {
+	st := store.NewMemory()
+	legacy := []DataPoint{
+		{Value: 5, Unit: "mm"},
+		{Value: 12, Unit: "°C"},
+		{Value: 14, Unit: "°C", Series: "heat_index"},
+	}
+	if err := WriteDataset(st, "legacy.json", legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DeletePoint(st, "legacy.json", "p2", "ana"); err != nil {
+		t.Fatalf("DeletePoint() of a legacy point error = %v", err)
+	}
+	points, _ := ReadDataset(st, "legacy.json")
+	if len(points) != 2 || points[0].ID != "p1" || points[1].ID != "d1" {
+		t.Errorf("points = %+v, want p1 and d1", points)
+	}
+
+	if err := WriteDataset(st, "legacy.json", legacy); err != nil {
+		t.Fatal(err)
+	}
+	if err := BackfillPointIDs(st, "legacy.json"); err != nil {
+		t.Fatal(err)
+	}
+	points, _ = ReadDataset(st, "legacy.json")
+	if len(points) != 3 || points[0].ID != "p1" || points[1].ID != "p2" || points[2].ID != "d1" {
+		t.Errorf("backfilled points = %+v, want p1, p2 and d1", points)
+	}
+}
+
+func TestAssignPointIDsSkipsUsedIDs(t *testing.T) {
+	points := []DataPoint{{ID: "p2"}, {}, {ID: "m1"}}
+	if !AssignPointIDs(points) {
+		t.Fatal("AssignPointIDs() = false, want true")
+	}
+	if points[1].ID != "p3" {
+		t.Errorf("ID = %q, want p3", points[1].ID)
+	}
+	if AssignPointIDs(points) {
+		t.Error("AssignPointIDs() of points with IDs = true, want false")
+	}
+}
@@ -3,11 +3,41 @@ package util
 import (
 	"regexp"
 	"strconv"
+	"strings"
 )
 
+// contextRadius is the number of bytes kept on each side of a match as context
+const contextRadius = 40
+
+// Longer units are listed before their prefixes ("mg/L" before "mg", "km²"
+// before "km") so the alternation picks the full unit
+var dataPattern = regexp.MustCompile(`(?i)(-?\d+(?:\.\d+)?)\s*(µg/m³|ppm|°C|°F|mmHg|mm|cm|km²|km|m²|mg/L|mL|mg|mi|m|in|ft|yd|ha|acres|kg|g|lb|oz|L|vehicles/hr|count/month|permits|vehicles|kWh|MW|W|dB|%|μS/cm|NTU|Bq/m³|AQI|hPa|Pa|bar|psi)?`)
+
 type DataPoint struct {
-	Value float64 `json:"value"`
-	Unit  string  `json:"unit"`
+	ID       string      `json:"id,omitempty"`
+	Value    float64     `json:"value"`
+	Unit     string      `json:"unit"`
+	Label    string      `json:"label,omitempty"`
+	Date     string      `json:"date,omitempty"`
+	Section  string      `json:"section,omitempty"`
+	Location *Coordinate `json:"location,omitempty"`
+	Source   *Provenance `json:"source,omitempty"`
+	Origin   string      `json:"origin,omitempty"` // dataset a merged point came from
+
+	// Derived points record the series they belong to and the IDs of the
+	// points they were computed from
+	Series string   `json:"series,omitempty"`
+	Inputs []string `json:"inputs,omitempty"`
+	Level  string   `json:"level,omitempty"`
+	Color  string   `json:"color,omitempty"`
+}
+
+// Provenance records where in the source text a data point was found
+type Provenance struct {
+	Offset  int    `json:"offset"`
+	Length  int    `json:"length"`
+	Line    int    `json:"line"`
+	Context string `json:"context"`
 }
 
 /*
@@ -22,6 +52,9 @@ Supported units include:
 
 If a unit is not found next to a number, the unit will be recorded as "(none)".
 
+Each DataPoint carries its provenance: the byte offset and length of the match
+within text, the 1-based line number and a short snippet of surrounding context.
+
 Parameters:
 - text: a string containing text with embedded data values.
 
@@ -40,25 +73,62 @@ Example:
 func GetData(text string) []DataPoint {
 	var results []DataPoint
 
-	pattern := regexp.MustCompile(`(?i)(-?\d+(?:\.\d+)?)\s*(µg/m³|ppm|°C|°F|mm|cm|m|km|in|ft|yd|mi|ha|km²|m²|acres|g|kg|mg|lb|oz|L|mL|vehicles/hr|count/month|permits|vehicles|kWh|W|MW|dB|%|mg/L|μS/cm|NTU|Bq/m³|AQI|mmHg|hPa|Pa|bar|psi)?`)
-
-	matches := pattern.FindAllStringSubmatch(text, -1)
+	matches := dataPattern.FindAllStringSubmatchIndex(text, -1)
 
+	line, lineStart := 1, 0
 	for _, match := range matches {
-		value, err := strconv.ParseFloat(match[1], 64)
+		value, err := strconv.ParseFloat(text[match[2]:match[3]], 64)
 		if err != nil {
 			continue
 		}
-		unit := match[2]
+		unit := ""
+		if match[4] >= 0 {
+			unit = text[match[4]:match[5]]
+		}
 		if unit == "" {
 			unit = "(none)"
 		}
 
+		line += strings.Count(text[lineStart:match[0]], "\n")
+		lineStart = match[0]
+
 		results = append(results, DataPoint{
 			Value: value,
 			Unit:  unit,
+			Source: &Provenance{
+				Offset:  match[0],
+				Length:  match[1] - match[0],
+				Line:    line,
+				Context: contextAround(text, match[0], match[1]),
+			},
 		})
 	}
 
 	return results
 }
+
+// contextAround returns the text surrounding [start, end) with whitespace collapsed
+func contextAround(text string, start, end int) string {
+	from := start - contextRadius
+	if from < 0 {
+		from = 0
+	}
+	to := end + contextRadius
+	if to > len(text) {
+		to = len(text)
+	}
+
+	// Avoid cutting multi-byte characters in half
+	for from > 0 && !isRuneStart(text[from]) {
+		from--
+	}
+	for to < len(text) && !isRuneStart(text[to]) {
+		to++
+	}
+
+	return strings.Join(strings.Fields(text[from:to]), " ")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
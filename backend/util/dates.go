@@ -0,0 +1,82 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	isoDatePattern      = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	dayMonthDatePattern = regexp.MustCompile(`(?i)\b(\d{1,2})\s+(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?,?\s+(\d{4})\b`)
+	monthDayDatePattern = regexp.MustCompile(`(?i)\b(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?\s+(\d{1,2}),?\s+(\d{4})\b`)
+	monthAbbreviations  = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+)
+
+// dateNear returns the date, as YYYY-MM-DD, written on the same line as the
+// point and closest to it, or "" when the line has no date
+func dateNear(text string, dp DataPoint) string {
+	if dp.Source == nil || dp.Source.Offset > len(text) {
+		return ""
+	}
+
+	lineStart := strings.LastIndexByte(text[:dp.Source.Offset], '\n') + 1
+	lineEnd := len(text)
+	if i := strings.IndexByte(text[dp.Source.Offset:], '\n'); i >= 0 {
+		lineEnd = dp.Source.Offset + i
+	}
+	line := text[lineStart:lineEnd]
+	pos := dp.Source.Offset - lineStart
+
+	best, bestDistance := "", -1
+	consider := func(start, end int, year, month, day string) {
+		date, ok := normalizeDate(year, month, day)
+		if !ok {
+			return
+		}
+		distance := pos - end
+		if start > pos {
+			distance = start - pos
+		}
+		if distance < 0 {
+			// The point is inside the date itself
+			return
+		}
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = date, distance
+		}
+	}
+
+	for _, m := range isoDatePattern.FindAllStringSubmatchIndex(line, -1) {
+		consider(m[0], m[1], line[m[2]:m[3]], line[m[4]:m[5]], line[m[6]:m[7]])
+	}
+	for _, m := range dayMonthDatePattern.FindAllStringSubmatchIndex(line, -1) {
+		consider(m[0], m[1], line[m[6]:m[7]], monthNumber(line[m[4]:m[5]]), line[m[2]:m[3]])
+	}
+	for _, m := range monthDayDatePattern.FindAllStringSubmatchIndex(line, -1) {
+		consider(m[0], m[1], line[m[6]:m[7]], monthNumber(line[m[2]:m[3]]), line[m[4]:m[5]])
+	}
+
+	return best
+}
+
+func monthNumber(name string) string {
+	name = strings.ToLower(name)
+	for i, abbr := range monthAbbreviations {
+		if strings.HasPrefix(name, abbr) {
+			return strconv.Itoa(i + 1)
+		}
+	}
+	return ""
+}
+
+func normalizeDate(year, month, day string) (string, bool) {
+	y, err1 := strconv.Atoi(year)
+	m, err2 := strconv.Atoi(month)
+	d, err3 := strconv.Atoi(day)
+	if err1 != nil || err2 != nil || err3 != nil || m < 1 || m > 12 || d < 1 || d > 31 {
+		return "", false
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", y, m, d), true
+}
@@ -0,0 +1,108 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+// ContentHash returns the hex encoded SHA-256 of an uploaded document
+func ContentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// hashesBackfilledMarker names the object in Hashes recording that the hashes
+// of datasets uploaded before hashes were indexed have been added
+const hashesBackfilledMarker = "backfilled"
+
+// hashesBackfilled remembers the stores whose hashes are known to be complete
+var hashesBackfilled sync.Map
+
+// FindDatasetByHash returns the dataset extracted from the document with the
+// given content hash. The first lookup in a store without the backfill marker
+// indexes the hashes of older datasets from their metadata; after that a
+// lookup reads only the hash entry and checks that its dataset exists.
+func FindDatasetByHash(st store.Store, hash string) (string, bool, error) {
+	if err := backfillHashes(st); err != nil {
+		return "", false, err
+	}
+
+	name, err := st.Get(store.Hashes, hash)
+	if errors.Is(err, store.ErrNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	_, err = st.Stat(store.Datasets, string(name))
+	if errors.Is(err, store.ErrNotFound) {
+		// The dataset is gone, so the content is new again
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return string(name), true, nil
+}
+
+// backfillHashes records the content hash of every dataset with metadata that
+// has none recorded yet, once per store
+func backfillHashes(st store.Store) error {
+	if _, done := hashesBackfilled.Load(st); done {
+		return nil
+	}
+
+	_, err := st.Stat(store.Hashes, hashesBackfilledMarker)
+	if err == nil {
+		hashesBackfilled.Store(st, true)
+		return nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+
+	metas, err := st.List(store.Meta)
+	if err != nil {
+		return err
+	}
+	for _, obj := range metas {
+		meta, err := LoadMetadata(st, obj.Name)
+		if err != nil || meta.SHA256 == "" {
+			continue
+		}
+		if _, err := st.Stat(store.Hashes, meta.SHA256); err == nil {
+			continue
+		}
+		if err := RecordContentHash(st, meta.SHA256, obj.Name); err != nil {
+			return err
+		}
+	}
+
+	if err := st.Put(store.Hashes, hashesBackfilledMarker, []byte(time.Now().UTC().Format(time.RFC3339))); err != nil {
+		return err
+	}
+	hashesBackfilled.Store(st, true)
+	return nil
+}
+
+// RecordContentHash makes dataset the one returned for content with hash
+func RecordContentHash(st store.Store, hash, dataset string) error {
+	return st.Put(store.Hashes, hash, []byte(dataset))
+}
+
+// AddAlias records that a document called originalName was uploaded again and
+// linked to dataset
+func AddAlias(st store.Store, dataset, originalName string) error {
+	meta, err := LoadMetadata(st, dataset)
+	if err != nil {
+		return err
+	}
+	meta.Aliases = append(meta.Aliases, Alias{OriginalName: originalName, UploadedAt: time.Now()})
+	return SaveMetadata(st, dataset, *meta)
+}
@@ -0,0 +1,41 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+func TestFindDatasetByHashBackfills(t *testing.T) {
+	st := store.NewMemory()
+	dataset := storeDataset(t, st, "report.txt", "Rainfall was 5 mm", time.Now())
+	meta, err := LoadMetadata(st, dataset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A dataset from before hashes were recorded has only its metadata
+	if err := st.Delete(store.Hashes, meta.SHA256); err != nil {
+		t.Fatal(err)
+	}
+
+	found, ok, err := FindDatasetByHash(st, meta.SHA256)
+	if err != nil || !ok || found != dataset {
+		t.Fatalf("FindDatasetByHash() = %q, %v, %v, want %s", found, ok, err, dataset)
+	}
+	if _, err := st.Stat(store.Hashes, hashesBackfilledMarker); err != nil {
+		t.Errorf("backfill marker not written: %v", err)
+	}
+
+	// Later lookups only read the hash entry
+	if _, ok, _ := FindDatasetByHash(st, ContentHash([]byte("new content"))); ok {
+		t.Error("FindDatasetByHash() found a dataset for new content")
+	}
+	if err := st.Delete(store.Datasets, dataset); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := FindDatasetByHash(st, meta.SHA256); ok {
+		t.Error("FindDatasetByHash() found a deleted dataset")
+	}
+}
@@ -0,0 +1,94 @@
+package util
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Derivation computes derived data points from the points extracted from a text
+type Derivation interface {
+	// Name identifies the derivation in logs and series names
+	Name() string
+	// Derive returns new points computed from text and its extracted points.
+	// Returned points should set Series and Inputs; IDs are assigned by Derive.
+	Derive(text string, points []DataPoint) []DataPoint
+}
+
+var (
+	derivationsMu sync.RWMutex
+	derivations   []Derivation
+)
+
+// RegisterDerivation adds d to the set of derivations run after extraction
+func RegisterDerivation(d Derivation) {
+	derivationsMu.Lock()
+	defer derivationsMu.Unlock()
+	derivations = append(derivations, d)
+}
+
+// Derive runs every registered derivation over the extracted points and
+// returns the derived points with sequential "d" IDs
+func Derive(text string, points []DataPoint) []DataPoint {
+	derivationsMu.RLock()
+	defer derivationsMu.RUnlock()
+
+	derived := []DataPoint{}
+	for _, d := range derivations {
+		for _, dp := range d.Derive(text, points) {
+			dp.ID = fmt.Sprintf("d%d", len(derived)+1)
+			derived = append(derived, dp)
+		}
+	}
+
+	return derived
+}
+
+// DerivationNames returns the names of the registered derivations in the
+// order they run
+func DerivationNames() []string {
+	derivationsMu.RLock()
+	defer derivationsMu.RUnlock()
+
+	names := make([]string, len(derivations))
+	for i, d := range derivations {
+		names[i] = d.Name()
+	}
+	return names
+}
+
+// precedingText returns up to n bytes of text before the point on the same line
+func precedingText(text string, dp DataPoint, n int) string {
+	if dp.Source == nil || dp.Source.Offset > len(text) {
+		return ""
+	}
+
+	start := dp.Source.Offset - n
+	if start < 0 {
+		start = 0
+	}
+	for start > 0 && !isRuneStart(text[start]) {
+		start--
+	}
+
+	before := text[start:dp.Source.Offset]
+	for i := len(before) - 1; i >= 0; i-- {
+		if before[i] == '\n' {
+			return before[i+1:]
+		}
+	}
+
+	return before
+}
+
+// MeasuredPoints returns the points that were extracted rather than derived.
+// Derived points reuse the units of measurements, such as °C for the heat
+// index, so filters by unit leave them out unless a series is asked for.
+func MeasuredPoints(points []DataPoint) []DataPoint {
+	var measured []DataPoint
+	for _, dp := range points {
+		if dp.Series == "" {
+			measured = append(measured, dp)
+		}
+	}
+	return measured
+}
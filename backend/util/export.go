@@ -0,0 +1,269 @@
+package util
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+// ExportFormat describes a format datasets can be exported to
+type ExportFormat struct {
+	ContentType string
+	Extension   string
+	new         func(w io.Writer) ExportWriter
+}
+
+// ExportFormats lists the export formats by name
+var ExportFormats = map[string]ExportFormat{
+	"csv": {
+		ContentType: "text/csv; charset=utf-8",
+		Extension:   ".csv",
+		new:         func(w io.Writer) ExportWriter { return &csvExport{w: csv.NewWriter(w)} },
+	},
+	"ndjson": {
+		ContentType: "application/x-ndjson",
+		Extension:   ".ndjson",
+		new:         func(w io.Writer) ExportWriter { return &ndjsonExport{enc: json.NewEncoder(w)} },
+	},
+	"xlsx": {
+		ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		Extension:   ".xlsx",
+		new:         func(w io.Writer) ExportWriter { return &xlsxExport{zw: zip.NewWriter(w)} },
+	},
+}
+
+// ExportColumns are the columns of tabular exports, one per point field
+var ExportColumns = []string{
+	"id", "value", "unit", "label", "date", "section", "latitude", "longitude",
+	"source_offset", "source_length", "source_line", "source_context",
+	"origin", "series", "inputs", "level", "color",
+}
+
+// ExportWriter writes data points in an export format. Points are written as
+// they come, so the export never holds the whole dataset.
+type ExportWriter interface {
+	WritePoint(dp DataPoint) error
+	// Close finishes the export; it must be called even without points
+	Close() error
+}
+
+// NewExportWriter returns a writer of format to w
+func NewExportWriter(format string, w io.Writer) (ExportWriter, error) {
+	f, ok := ExportFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown export format: %s", format)
+	}
+	return f.new(w), nil
+}
+
+// StreamDataset decodes the points of a dataset one at a time and passes them to fn
+func StreamDataset(st store.Store, dataset string, fn func(DataPoint) error) error {
+	r, err := st.Open(store.Datasets, dataset)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	dec := json.NewDecoder(r)
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("decoding %s: %w", dataset, err)
+	}
+	for dec.More() {
+		var dp DataPoint
+		if err := dec.Decode(&dp); err != nil {
+			return fmt.Errorf("decoding %s: %w", dataset, err)
+		}
+		if err := fn(dp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportRow returns the cells of a point in the order of ExportColumns, with
+// the numeric cells flagged
+func exportRow(dp DataPoint) ([]string, []bool) {
+	row := make([]string, len(ExportColumns))
+	numeric := make([]bool, len(ExportColumns))
+
+	row[0] = dp.ID
+	row[1], numeric[1] = strconv.FormatFloat(dp.Value, 'f', -1, 64), true
+	row[2] = dp.Unit
+	row[3] = dp.Label
+	row[4] = dp.Date
+	row[5] = dp.Section
+	if dp.Location != nil {
+		row[6], numeric[6] = strconv.FormatFloat(dp.Location.Lat, 'f', -1, 64), true
+		row[7], numeric[7] = strconv.FormatFloat(dp.Location.Lon, 'f', -1, 64), true
+	}
+	if dp.Source != nil {
+		row[8], numeric[8] = strconv.Itoa(dp.Source.Offset), true
+		row[9], numeric[9] = strconv.Itoa(dp.Source.Length), true
+		row[10], numeric[10] = strconv.Itoa(dp.Source.Line), true
+		row[11] = dp.Source.Context
+	}
+	row[12] = dp.Origin
+	row[13] = dp.Series
+	row[14] = strings.Join(dp.Inputs, ";")
+	row[15] = dp.Level
+	row[16] = dp.Color
+	return row, numeric
+}
+
+type csvExport struct {
+	w       *csv.Writer
+	started bool
+}
+
+func (e *csvExport) WritePoint(dp DataPoint) error {
+	if !e.started {
+		e.started = true
+		if err := e.w.Write(ExportColumns); err != nil {
+			return err
+		}
+	}
+	row, _ := exportRow(dp)
+	return e.w.Write(row)
+}
+
+func (e *csvExport) Close() error {
+	if !e.started {
+		e.started = true
+		e.w.Write(ExportColumns)
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonExport struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonExport) WritePoint(dp DataPoint) error {
+	return e.enc.Encode(dp)
+}
+
+func (e *ndjsonExport) Close() error {
+	return nil
+}
+
+// xlsxExport writes a workbook with a single sheet. The sheet is streamed
+// into the zip archive; the other parts are small and fixed.
+type xlsxExport struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Data points" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// start writes the fixed parts and opens the sheet with its header row
+func (e *xlsxExport) start() error {
+	for _, part := range []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	} {
+		f, err := e.zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	f, err := e.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	e.sheet = bufio.NewWriter(f)
+	e.sheet.WriteString(xlsxSheetStart)
+	return e.writeRow(ExportColumns, make([]bool, len(ExportColumns)))
+}
+
+// writeRow writes a row of cells, numbers as numbers and the rest as inline strings
+func (e *xlsxExport) writeRow(cells []string, numeric []bool) error {
+	e.rows++
+	fmt.Fprintf(e.sheet, `<row r="%d">`, e.rows)
+	for i, cell := range cells {
+		if cell == "" {
+			continue
+		}
+		ref := xlsxColumn(i) + strconv.Itoa(e.rows)
+		if numeric[i] {
+			fmt.Fprintf(e.sheet, `<c r="%s"><v>%s</v></c>`, ref, cell)
+			continue
+		}
+		fmt.Fprintf(e.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+		if err := xml.EscapeText(e.sheet, []byte(cell)); err != nil {
+			return err
+		}
+		e.sheet.WriteString(`</t></is></c>`)
+	}
+	_, err := e.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (e *xlsxExport) WritePoint(dp DataPoint) error {
+	if e.sheet == nil {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+	row, numeric := exportRow(dp)
+	return e.writeRow(row, numeric)
+}
+
+func (e *xlsxExport) Close() error {
+	if e.sheet == nil {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+	e.sheet.WriteString(xlsxSheetEnd)
+	if err := e.sheet.Flush(); err != nil {
+		return err
+	}
+	return e.zw.Close()
+}
+
+// xlsxColumn returns the letters of a zero-based column index: A, B, …, AA
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,94 @@
+package util
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+)
+
+var exportPoints = []DataPoint{
+	{ID: "p1", Value: 5.5, Unit: "mm", Label: "rain <daily>", Source: &Provenance{Offset: 3, Length: 6, Line: 1, Context: "was 5.5 mm"}},
+	{ID: "d1", Value: 42, Unit: "AQI", Series: "aqi.us-epa", Inputs: []string{"p2", "p3"}, Level: "Good", Location: &Coordinate{Lat: -1.29, Lon: 36.82}},
+}
+
+func export(t *testing.T, format string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewExportWriter(format, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dp := range exportPoints {
+		if err := w.WritePoint(dp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExportCSV(t *testing.T) {
+	rows, err := csv.NewReader(bytes.NewReader(export(t, "csv"))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || strings.Join(rows[0], ",") != strings.Join(ExportColumns, ",") {
+		t.Fatalf("rows = %v", rows)
+	}
+	if got := strings.Join(rows[1], ","); got != "p1,5.5,mm,rain <daily>,,,,,3,6,1,was 5.5 mm,,,,," {
+		t.Errorf("first row = %s", got)
+	}
+	if got := strings.Join(rows[2], ","); got != "d1,42,AQI,,,,-1.29,36.82,,,,,,aqi.us-epa,p2;p3,Good," {
+		t.Errorf("second row = %s", got)
+	}
+}
+
+func TestExportNDJSON(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(string(export(t, "ndjson"))), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"series":"aqi.us-epa"`) {
+		t.Errorf("lines = %q", lines)
+	}
+}
+
+func TestExportXLSX(t *testing.T) {
+	data := export(t, "xlsx")
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("not a zip archive: %v", err)
+	}
+
+	var sheet string
+	for _, f := range zr.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		sheet = string(content)
+	}
+	if len(zr.File) != 5 || sheet == "" {
+		t.Fatalf("archive has %d parts, sheet %q", len(zr.File), sheet)
+	}
+	for _, want := range []string{`<c r="B2"><v>5.5</v></c>`, `rain &lt;daily&gt;`, `<row r="3">`, `<c r="G3"><v>-1.29</v></c>`} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet lacks %s", want)
+		}
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumn(i); got != want {
+			t.Errorf("xlsxColumn(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
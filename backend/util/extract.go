@@ -0,0 +1,107 @@
+package util
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Reasons a candidate number is discarded during extraction
+const (
+	ReasonUnitless      = "unitless"
+	ReasonLowConfidence = "low_confidence"
+	ReasonNoise         = "noise"
+	ReasonCoordinate    = "coordinate"
+)
+
+// Candidate is a number found in the text that was not kept as a data point
+type Candidate struct {
+	Value  float64     `json:"value"`
+	Unit   string      `json:"unit"`
+	Reason string      `json:"reason"`
+	Source *Provenance `json:"source,omitempty"`
+}
+
+// Extraction is the full result of running the extraction pipeline on a text
+type Extraction struct {
+	Points      []DataPoint    `json:"points"`
+	Discarded   []Candidate    `json:"discarded"`
+	UnitCounts  map[string]int `json:"unit_counts"`
+	Derived     []DataPoint    `json:"derived"`
+	Coordinates []Coordinate   `json:"coordinates"`
+	Headings    []Heading      `json:"headings"`
+}
+
+// Extract runs the extraction pipeline on text and returns the kept data points,
+// the discarded candidates with the reason they were dropped and the number of
+// points found per unit. Kept points are given sequential IDs in document order
+// and dated from the line they appear on. Numbers that are part of a geographic
+// coordinate are discarded and every kept point is linked to the nearest
+// coordinate and to the heading of the section it appears in, then the
+// registered derivations are run over them. Nothing is
+// written to disk.
+func Extract(text string) Extraction {
+	result := Extraction{
+		Points:      []DataPoint{},
+		Discarded:   []Candidate{},
+		UnitCounts:  make(map[string]int),
+		Coordinates: FindCoordinates(text),
+		Headings:    FindHeadings(text),
+	}
+
+	for _, dp := range GetData(text) {
+		reason := classify(text, dp)
+		if insideCoordinate(result.Coordinates, dp) {
+			reason = ReasonCoordinate
+		}
+		if reason != "" {
+			result.Discarded = append(result.Discarded, Candidate{
+				Value:  dp.Value,
+				Unit:   dp.Unit,
+				Reason: reason,
+				Source: dp.Source,
+			})
+			continue
+		}
+
+		dp.ID = fmt.Sprintf("p%d", len(result.Points)+1)
+		dp.Date = dateNear(text, dp)
+		dp.Location = nearestCoordinate(text, result.Coordinates, dp)
+		dp.Section = sectionTitle(result.Headings, dp.Source.Offset)
+		result.Points = append(result.Points, dp)
+		result.UnitCounts[dp.Unit]++
+	}
+
+	result.Derived = Derive(text, result.Points)
+
+	return result
+}
+
+// classify returns the reason a candidate should be discarded, or "" to keep it.
+//
+// A number glued to a preceding letter ("PM2.5", "H2O") is part of an identifier
+// and treated as noise. An alphabetic unit immediately followed by another letter
+// ("5 mice" matching "m") is only a prefix of a longer word and has low confidence.
+func classify(text string, dp DataPoint) string {
+	if dp.Source != nil {
+		start, end := dp.Source.Offset, dp.Source.Offset+dp.Source.Length
+
+		if r, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && unicode.IsLetter(r) {
+			return ReasonNoise
+		}
+
+		if dp.Unit != "(none)" && end < len(text) {
+			last, _ := utf8.DecodeLastRuneInString(dp.Unit)
+			next, _ := utf8.DecodeRuneInString(text[end:])
+			if unicode.IsLetter(last) && unicode.IsLetter(next) {
+				return ReasonLowConfidence
+			}
+		}
+	}
+
+	if dp.Unit == "(none)" {
+		return ReasonUnitless
+	}
+
+	return ""
+}
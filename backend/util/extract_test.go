@@ -0,0 +1,73 @@
+package util
+
+import "testing"
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		wantKept  int
+		wantUnits map[string]int
+		reasons   map[string]int
+	}{
+		{
+			name:      "Values with units",
+			text:      "Air quality is 23.5 µg/m³ and temperature is 30.2 °C",
+			wantKept:  2,
+			wantUnits: map[string]int{"µg/m³": 1, "°C": 1},
+			reasons:   map[string]int{},
+		},
+		{
+			name:      "Unitless number",
+			text:      "In 2024 rainfall was 5 mm",
+			wantKept:  1,
+			wantUnits: map[string]int{"mm": 1},
+			reasons:   map[string]int{ReasonUnitless: 1},
+		},
+		{
+			name:      "Number inside identifier",
+			text:      "PM2.5 stayed high",
+			wantKept:  0,
+			wantUnits: map[string]int{},
+			reasons:   map[string]int{ReasonNoise: 1},
+		},
+		{
+			name:      "Unit is prefix of a word",
+			text:      "We counted 5 mice",
+			wantKept:  0,
+			wantUnits: map[string]int{},
+			reasons:   map[string]int{ReasonLowConfidence: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Extract(tt.text)
+			if len(got.Points) != tt.wantKept {
+				t.Fatalf("Extract() kept %d points, want %d", len(got.Points), tt.wantKept)
+			}
+			for unit, count := range tt.wantUnits {
+				if got.UnitCounts[unit] != count {
+					t.Errorf("UnitCounts[%q] = %d, want %d", unit, got.UnitCounts[unit], count)
+				}
+			}
+			reasons := make(map[string]int)
+			for _, c := range got.Discarded {
+				reasons[c.Reason]++
+			}
+			for reason, count := range tt.reasons {
+				if reasons[reason] != count {
+					t.Errorf("discarded for %q = %d, want %d", reason, reasons[reason], count)
+				}
+			}
+			for i, dp := range got.Points {
+				if dp.Source == nil {
+					t.Fatalf("point %d has no provenance", i)
+				}
+				if tt.text[dp.Source.Offset:dp.Source.Offset+dp.Source.Length] == "" {
+					t.Errorf("point %d has an empty source span", i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package util
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+// datasetIndexName is the name of the listing index object
+const datasetIndexName = "datasets"
+
+// Sort orders of a dataset listing
+const (
+	SortModified = "modified"
+	SortSize     = "size"
+	SortPoints   = "points"
+	SortName     = "name"
+)
+
+// ErrInvalidCursor is returned for cursors that were not issued by ListIndex
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// indexMu serialises updates of the listing index
+var indexMu sync.Mutex
+
+// IndexEntry is what the listing index keeps about a dataset, enough to
+// filter and sort datasets without reading them. Size is that of the uploaded
+// document; DataSize and Modified are those of the stored dataset.
+type IndexEntry struct {
+	Dataset    string    `json:"dataset"`
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	DataSize   int64     `json:"data_size"`
+	UploadedAt time.Time `json:"uploaded_at"`
+	Modified   time.Time `json:"modified"`
+	PointCount int       `json:"point_count"`
+	Units      []string  `json:"units"`
+}
+
+// ListQuery selects, orders and pages a dataset listing. Zero fields do not filter.
+type ListQuery struct {
+	Unit     string
+	Category string
+	Name     string // matched case-insensitively against the dataset ID and names
+	Dataset  string // exact dataset ID
+	From     time.Time
+	To       time.Time
+	Only     map[string]bool // restrict to these datasets, e.g. those of a project
+	Sort     string
+	Desc     bool
+	Cursor   string
+	Limit    int
+}
+
+// ListPage is one page of a dataset listing
+type ListPage struct {
+	Entries    []IndexEntry
+	Total      int
+	NextCursor string
+}
+
+// NewIndexEntry builds the index entry of a dataset from its metadata. The
+// size and modification time of the dataset are left for the caller to fill in.
+func NewIndexEntry(meta Metadata) IndexEntry {
+	return IndexEntry{
+		Dataset:    meta.Dataset,
+		Name:       datasetName(&meta),
+		Size:       meta.Size,
+		UploadedAt: meta.UploadedAt,
+		PointCount: meta.PointCount,
+		Units:      meta.Units,
+	}
+}
+
+// IndexDataset brings the listing entry of a dataset up to date from its
+// metadata and the stored dataset. Datasets without metadata are left out.
+func IndexDataset(st store.Store, dataset string) error {
+	meta, err := LoadMetadata(st, dataset)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return indexDataset(st, dataset, *meta)
+}
+
+// indexDataset stores the listing entry of a dataset with the given metadata
+func indexDataset(st store.Store, dataset string, meta Metadata) error {
+	entry := NewIndexEntry(meta)
+	entry.Dataset = dataset
+	obj, err := st.Stat(store.Datasets, dataset)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+	entry.DataSize, entry.Modified = obj.Size, obj.Modified
+	return UpdateIndex(st, entry)
+}
+
+// LoadIndex reads the listing index, keyed by dataset. A store without one
+// returns an empty index.
+func LoadIndex(st store.Store) (map[string]IndexEntry, error) {
+	var entries []IndexEntry
+	if err := loadJSON(st, store.Index, datasetIndexName, &entries); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+	index := make(map[string]IndexEntry, len(entries))
+	for _, e := range entries {
+		index[e.Dataset] = e
+	}
+	return index, nil
+}
+
+// UpdateIndex adds or replaces the entries of datasets in the listing index
+func UpdateIndex(st store.Store, entries ...IndexEntry) error {
+	return changeIndex(st, func(index map[string]IndexEntry) {
+		for _, entry := range entries {
+			index[entry.Dataset] = entry
+		}
+	})
+}
+
+// RemoveFromIndex drops datasets from the listing index
+func RemoveFromIndex(st store.Store, datasets ...string) error {
+	return changeIndex(st, func(index map[string]IndexEntry) {
+		for _, dataset := range datasets {
+			delete(index, dataset)
+		}
+	})
+}
+
+// changeIndex applies change to the stored listing index
+func changeIndex(st store.Store, change func(map[string]IndexEntry)) error {
+	indexMu.Lock()
+	defer indexMu.Unlock()
+
+	index, err := LoadIndex(st)
+	if err != nil {
+		return err
+	}
+	change(index)
+
+	entries := make([]IndexEntry, 0, len(index))
+	for _, e := range index {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Dataset < entries[j].Dataset })
+	return saveJSON(st, store.Index, datasetIndexName, entries)
+}
+
+// ListIndex filters, sorts and pages the entries of the listing index.
+// Cursors point after the last entry of a page, so a page does not shift when
+// datasets are added or removed before it.
+func ListIndex(index map[string]IndexEntry, q ListQuery) (ListPage, error) {
+	var matched []IndexEntry
+	for _, e := range index {
+		if q.matches(e) {
+			matched = append(matched, e)
+		}
+	}
+
+	less := q.less()
+	sort.Slice(matched, func(i, j int) bool { return less(matched[i], matched[j]) })
+
+	start := 0
+	if q.Cursor != "" {
+		after, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return ListPage{}, err
+		}
+		start = sort.Search(len(matched), func(i int) bool { return less(after, matched[i]) })
+	}
+
+	end := len(matched)
+	if q.Limit > 0 && start+q.Limit < end {
+		end = start + q.Limit
+	}
+
+	page := ListPage{Entries: matched[start:end], Total: len(matched)}
+	if end < len(matched) {
+		page.NextCursor = encodeCursor(matched[end-1])
+	}
+	return page, nil
+}
+
+// matches reports whether an entry passes the filters of the query
+func (q ListQuery) matches(e IndexEntry) bool {
+	if q.Dataset != "" && e.Dataset != q.Dataset {
+		return false
+	}
+	if q.Only != nil && !q.Only[e.Dataset] {
+		return false
+	}
+	if q.Name != "" {
+		name := strings.ToLower(q.Name)
+		if !strings.Contains(strings.ToLower(e.Name), name) && !strings.Contains(strings.ToLower(e.Dataset), name) {
+			return false
+		}
+	}
+	if !q.From.IsZero() && e.UploadedAt.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !e.UploadedAt.Before(q.To) {
+		return false
+	}
+	if q.Unit != "" || q.Category != "" {
+		found := false
+		for _, unit := range e.Units {
+			if (q.Unit == "" || unit == q.Unit) && (q.Category == "" || UnitCategory(unit) == q.Category) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// less returns the order of the listing, with the dataset ID breaking ties so
+// the order is total
+func (q ListQuery) less() func(a, b IndexEntry) bool {
+	compare := func(a, b IndexEntry) int {
+		switch q.Sort {
+		case SortSize:
+			return compareInts(a.DataSize, b.DataSize)
+		case SortPoints:
+			return compareInts(int64(a.PointCount), int64(b.PointCount))
+		case SortName:
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		default:
+			return a.Modified.Compare(b.Modified)
+		}
+	}
+	return func(a, b IndexEntry) bool {
+		c := compare(a, b)
+		if c == 0 {
+			c = strings.Compare(a.Dataset, b.Dataset)
+		}
+		if q.Desc {
+			return c > 0
+		}
+		return c < 0
+	}
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// encodeCursor returns the cursor pointing after e
+func encodeCursor(e IndexEntry) string {
+	e.Units = nil
+	data, _ := json.Marshal(e)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor reads back the entry a cursor points after
+func decodeCursor(cursor string) (IndexEntry, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return IndexEntry{}, ErrInvalidCursor
+	}
+	var e IndexEntry
+	if err := json.Unmarshal(data, &e); err != nil || e.Dataset == "" {
+		return IndexEntry{}, ErrInvalidCursor
+	}
+	return e, nil
+}
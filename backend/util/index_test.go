@@ -0,0 +1,148 @@
+package util
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+func TestListIndex(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	index := map[string]IndexEntry{
+		"a.json": {Dataset: "a.json", Name: "Rivers March", Size: 30, DataSize: 300, UploadedAt: day, Modified: day.AddDate(0, 3, 0), PointCount: 4, Units: []string{"mm", "NTU"}},
+		"b.json": {Dataset: "b.json", Name: "Air", Size: 10, DataSize: 100, UploadedAt: day.AddDate(0, 0, 1), Modified: day.AddDate(0, 0, 1), PointCount: 9, Units: []string{"µg/m³"}},
+		"c.json": {Dataset: "c.json", Name: "Rivers April", Size: 20, DataSize: 200, UploadedAt: day.AddDate(0, 1, 0), Modified: day.AddDate(0, 1, 0), PointCount: 1, Units: []string{"mm"}},
+		"d.json": {Dataset: "d.json", Name: "Noise", Size: 20, DataSize: 200, UploadedAt: day.AddDate(0, 2, 0), Modified: day.AddDate(0, 2, 0), PointCount: 2, Units: []string{"dB"}},
+	}
+
+	names := func(page ListPage) []string {
+		var names []string
+		for _, e := range page.Entries {
+			names = append(names, e.Dataset)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name  string
+		query ListQuery
+		want  []string
+	}{
+		// a.json was uploaded first but edited last
+		{"recently modified first", ListQuery{Desc: true}, []string{"a.json", "d.json", "c.json", "b.json"}},
+		{"size with ties by ID", ListQuery{Sort: SortSize}, []string{"b.json", "c.json", "d.json", "a.json"}},
+		{"points", ListQuery{Sort: SortPoints, Desc: true}, []string{"b.json", "a.json", "d.json", "c.json"}},
+		{"unit", ListQuery{Unit: "mm"}, []string{"c.json", "a.json"}},
+		{"category", ListQuery{Category: CategoryTraffic}, []string{"d.json"}},
+		{"name", ListQuery{Name: "rivers", Sort: SortName}, []string{"c.json", "a.json"}},
+		{"date range", ListQuery{From: day.AddDate(0, 0, 1), To: day.AddDate(0, 2, 0)}, []string{"b.json", "c.json"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := ListIndex(index, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(page); !equalStrings(got, tt.want) {
+				t.Errorf("ListIndex() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Pages continue after the last entry even when it was removed meanwhile
+	first, _ := ListIndex(index, ListQuery{Sort: SortSize, Limit: 2})
+	if got := names(first); !equalStrings(got, []string{"b.json", "c.json"}) || first.Total != 4 || first.NextCursor == "" {
+		t.Fatalf("first page = %v (total %d, cursor %q)", got, first.Total, first.NextCursor)
+	}
+	delete(index, "c.json")
+	second, _ := ListIndex(index, ListQuery{Sort: SortSize, Limit: 2, Cursor: first.NextCursor})
+	if got := names(second); !equalStrings(got, []string{"d.json", "a.json"}) || second.NextCursor != "" {
+		t.Errorf("second page = %v (cursor %q)", got, second.NextCursor)
+	}
+
+	if _, err := ListIndex(index, ListQuery{Cursor: "not a cursor"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("ListIndex() with bad cursor error = %v", err)
+	}
+}
+
+func TestUpdateIndex(t *testing.T) {
+	st := store.NewMemory()
+	if err := UpdateIndex(st, IndexEntry{Dataset: "a.json"}, IndexEntry{Dataset: "b.json"}, IndexEntry{Dataset: "c.json"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateIndex(st, IndexEntry{Dataset: "b.json", PointCount: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveFromIndex(st, "c.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := LoadIndex(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index) != 2 || index["b.json"].PointCount != 4 {
+		t.Errorf("index = %+v, want a.json and b.json with 4 points", index)
+	}
+}
+
+func TestWritesKeepIndexUpToDate(t *testing.T) {
+	st := store.NewMemory()
+	dataset := storeDataset(t, st, "report.txt", "Rainfall was 5 mm and 12 °C", time.Now())
+
+	check := func(when string) {
+		t.Helper()
+		obj, err := st.Stat(store.Datasets, dataset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		index, err := LoadIndex(st)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := index[dataset]
+		if entry.DataSize != obj.Size || !entry.Modified.Equal(obj.Modified) {
+			t.Errorf("%s: entry has size %d modified %v, want %d and %v", when, entry.DataSize, entry.Modified, obj.Size, obj.Modified)
+		}
+	}
+	check("after upload")
+
+	points, err := ReadDataset(st, dataset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteDataset(st, dataset, points[:1]); err != nil {
+		t.Fatal(err)
+	}
+	check("after writing the dataset")
+
+	if _, err := RenameDataset(st, dataset, "Rainfall"); err != nil {
+		t.Fatal(err)
+	}
+	check("after renaming")
+	if index, _ := LoadIndex(st); index[dataset].Name != "Rainfall" {
+		t.Errorf("entry = %+v, want it named Rainfall", index[dataset])
+	}
+
+	// Datasets without metadata are not indexed
+	if err := WriteDataset(st, "loose.json", points); err != nil {
+		t.Fatal(err)
+	}
+	if index, _ := LoadIndex(st); len(index) != 1 {
+		t.Errorf("index = %+v, want only %s", index, dataset)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,256 @@
+package util
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+//go:embed config/stopwords.json
+var defaultStopWords []byte
+
+const (
+	// minTokenLength is the shortest word that can start or end a term, which
+	// also drops most unit symbols and initials
+	minTokenLength = 3
+
+	// maxNGram is the longest phrase counted as a term
+	maxNGram = 3
+)
+
+// StopWordConfig holds named lists of words that are never keywords
+type StopWordConfig struct {
+	Lists map[string][]string `json:"lists"`
+}
+
+// StopWords is the set of words ignored by the keyword pipeline
+type StopWords map[string]bool
+
+// TermFrequencies is the number of times each term occurs in one document
+type TermFrequencies struct {
+	Terms       map[string]int `json:"terms"`
+	TotalTerms  int            `json:"total_terms"`
+	GeneratedAt time.Time      `json:"generated_at"`
+}
+
+// Keyword is a term of a document ranked by TF-IDF against the other stored
+// documents
+type Keyword struct {
+	Term  string  `json:"term"`
+	Count int     `json:"count"`
+	TF    float64 `json:"tf"`
+	IDF   float64 `json:"idf"`
+	Score float64 `json:"score"`
+}
+
+var (
+	stopWordsOnce sync.Once
+	stopWords     StopWords
+)
+
+// LoadStopWords parses a stop word configuration file
+func LoadStopWords(path string) (StopWords, error) {
+	configBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading stop words: %w", err)
+	}
+	return parseStopWords(configBytes)
+}
+
+func parseStopWords(configBytes []byte) (StopWords, error) {
+	var config StopWordConfig
+	if err := json.Unmarshal(configBytes, &config); err != nil {
+		return nil, fmt.Errorf("unmarshaling stop words: %w", err)
+	}
+
+	words := make(StopWords)
+	for _, list := range config.Lists {
+		for _, w := range list {
+			words[strings.ToLower(w)] = true
+		}
+	}
+	return words, nil
+}
+
+// CurrentStopWords returns the stop words in use. They are read from the file
+// named by BIOTREE_STOPWORDS_CONFIG, falling back to the built-in lists.
+func CurrentStopWords() StopWords {
+	stopWordsOnce.Do(func() {
+		if path := os.Getenv("BIOTREE_STOPWORDS_CONFIG"); path != "" {
+			words, err := LoadStopWords(path)
+			if err == nil {
+				stopWords = words
+				return
+			}
+			log.Printf("Failed to load stop words %s, using defaults: %v", path, err)
+		}
+
+		words, err := parseStopWords(defaultStopWords)
+		if err != nil {
+			log.Printf("Failed to parse built-in stop words: %v", err)
+		}
+		stopWords = words
+	})
+	return stopWords
+}
+
+// Tokenize splits text into phrases at punctuation and line breaks and each
+// phrase into lower case words. Numbers and anything glued to a digit are
+// dropped, so "PM2.5" and "30.2" produce no tokens.
+func Tokenize(text string) [][]string {
+	var phrases [][]string
+
+	isBreak := func(r rune) bool {
+		return r == '\n' || (unicode.IsPunct(r) && r != '-' && r != '\'')
+	}
+
+	for _, phrase := range strings.FieldsFunc(text, isBreak) {
+		var tokens []string
+		for _, word := range strings.Fields(phrase) {
+			word = strings.Trim(strings.ToLower(word), "-'")
+			if word == "" || strings.IndexFunc(word, isNotWordRune) >= 0 {
+				// A symbol or number interrupts the phrase
+				if len(tokens) > 0 {
+					phrases = append(phrases, tokens)
+					tokens = nil
+				}
+				continue
+			}
+			tokens = append(tokens, word)
+		}
+		if len(tokens) > 0 {
+			phrases = append(phrases, tokens)
+		}
+	}
+
+	return phrases
+}
+
+func isNotWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && r != '-' && r != '\''
+}
+
+// NGrams returns the n-word terms of a phrase. Terms never start or end with a
+// stop word or a word shorter than minTokenLength, so "quality of water" is
+// kept while "of water" is not.
+func NGrams(tokens []string, n int, stop StopWords) []string {
+	ignored := func(token string) bool {
+		return stop[token] || utf8.RuneCountInString(token) < minTokenLength
+	}
+
+	var grams []string
+	for i := 0; i+n <= len(tokens); i++ {
+		if ignored(tokens[i]) || ignored(tokens[i+n-1]) {
+			continue
+		}
+		grams = append(grams, strings.Join(tokens[i:i+n], " "))
+	}
+	return grams
+}
+
+// CountTerms counts the single words and phrases of up to maxNGram words in text
+func CountTerms(text string, stop StopWords) TermFrequencies {
+	result := TermFrequencies{
+		Terms:       make(map[string]int),
+		GeneratedAt: time.Now(),
+	}
+
+	for _, phrase := range Tokenize(text) {
+		for n := 1; n <= maxNGram; n++ {
+			for _, term := range NGrams(phrase, n, stop) {
+				result.Terms[term]++
+				if n == 1 {
+					result.TotalTerms++
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// SaveTermFrequencies stores the term frequencies of a dataset
+func SaveTermFrequencies(st store.Store, dataset string, tf TermFrequencies) error {
+	return saveJSON(st, store.Keywords, dataset, tf)
+}
+
+// LoadTermFrequencies reads the term frequencies stored for a dataset
+func LoadTermFrequencies(st store.Store, dataset string) (*TermFrequencies, error) {
+	var tf TermFrequencies
+	if err := loadJSON(st, store.Keywords, dataset, &tf); err != nil {
+		return nil, err
+	}
+	return &tf, nil
+}
+
+// RankKeywords scores the terms of doc by TF-IDF against corpus, the term
+// frequencies of every stored document (doc included), and returns the best
+// limit of them. Ties are broken alphabetically so the ranking is stable.
+// A limit of 0 returns every term.
+func RankKeywords(doc TermFrequencies, corpus []TermFrequencies, limit int) []Keyword {
+	documentFrequency := make(map[string]int)
+	for _, other := range corpus {
+		for term := range other.Terms {
+			if _, ok := doc.Terms[term]; ok {
+				documentFrequency[term]++
+			}
+		}
+	}
+
+	total := math.Max(float64(doc.TotalTerms), 1)
+	keywords := make([]Keyword, 0, len(doc.Terms))
+	for term, count := range doc.Terms {
+		// Smoothed IDF keeps terms found in every document above zero
+		idf := math.Log(float64(1+len(corpus))/float64(1+documentFrequency[term])) + 1
+		tf := float64(count) / total
+		keywords = append(keywords, Keyword{
+			Term:  term,
+			Count: count,
+			TF:    tf,
+			IDF:   idf,
+			Score: tf * idf,
+		})
+	}
+
+	sort.Slice(keywords, func(i, j int) bool {
+		if keywords[i].Score != keywords[j].Score {
+			return keywords[i].Score > keywords[j].Score
+		}
+		return keywords[i].Term < keywords[j].Term
+	})
+
+	if limit > 0 && len(keywords) > limit {
+		keywords = keywords[:limit]
+	}
+	return keywords
+}
+
+// LoadCorpus reads the term frequencies of every stored dataset
+func LoadCorpus(st store.Store) ([]TermFrequencies, error) {
+	objects, err := st.List(store.Keywords)
+	if err != nil {
+		return nil, err
+	}
+
+	var corpus []TermFrequencies
+	for _, obj := range objects {
+		tf, err := LoadTermFrequencies(st, obj.Name)
+		if err != nil {
+			log.Printf("Skipping term frequencies %s: %v", obj.Name, err)
+			continue
+		}
+		corpus = append(corpus, *tf)
+	}
+	return corpus, nil
+}
@@ -0,0 +1,61 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCountTerms(t *testing.T) {
+	stop := StopWords{"the": true, "of": true, "was": true, "in": true}
+
+	tests := []struct {
+		name  string
+		text  string
+		want  map[string]int
+		total int
+	}{
+		{
+			name:  "Stop words and numbers are dropped",
+			text:  "The PM2.5 level was 40 µg/m³",
+			want:  map[string]int{"level": 1},
+			total: 1,
+		},
+		{
+			name: "Phrases span stop words but never start or end with one",
+			text: "quality of water. Water quality in rivers",
+			want: map[string]int{
+				"quality": 2, "water": 2, "rivers": 1,
+				"quality of water": 1, "water quality": 1, "quality in rivers": 1,
+			},
+			total: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CountTerms(tt.text, stop)
+			if !reflect.DeepEqual(got.Terms, tt.want) {
+				t.Errorf("CountTerms() terms = %v, want %v", got.Terms, tt.want)
+			}
+			if got.TotalTerms != tt.total {
+				t.Errorf("CountTerms() total = %d, want %d", got.TotalTerms, tt.total)
+			}
+		})
+	}
+}
+
+func TestRankKeywords(t *testing.T) {
+	doc := TermFrequencies{Terms: map[string]int{"river": 2, "sample": 2, "wetland": 1}, TotalTerms: 5}
+	other := TermFrequencies{Terms: map[string]int{"sample": 3, "forest": 1}, TotalTerms: 4}
+
+	got := RankKeywords(doc, []TermFrequencies{doc, other}, 2)
+
+	var terms []string
+	for _, k := range got {
+		terms = append(terms, k.Term)
+	}
+	// "sample" is as frequent as "river" but appears in every document
+	if want := []string{"river", "sample"}; !reflect.DeepEqual(terms, want) {
+		t.Errorf("RankKeywords() = %v, want %v", terms, want)
+	}
+}
@@ -0,0 +1,168 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+// SourceName returns the name of the document a dataset was extracted from
+func SourceName(dataset string) string {
+	return strings.TrimSuffix(dataset, ".json")
+}
+
+// DeleteDataset removes a dataset, its source document and every record kept
+// about it. It returns store.ErrNotFound if the dataset does not exist.
+func DeleteDataset(st store.Store, dataset string) error {
+	if _, err := st.Stat(store.Datasets, dataset); err != nil {
+		return err
+	}
+
+	// Drop the content hash first so the document can be uploaded again
+	if meta, err := LoadMetadata(st, dataset); err == nil && meta.SHA256 != "" {
+		if name, err := st.Get(store.Hashes, meta.SHA256); err == nil && string(name) == dataset {
+			if err := st.Delete(store.Hashes, meta.SHA256); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := st.Delete(store.Documents, SourceName(dataset)); err != nil {
+		return err
+	}
+	if err := deleteVersions(st, dataset); err != nil {
+		return err
+	}
+	if err := removeFromProjects(st, dataset); err != nil {
+		return err
+	}
+	if err := RemoveFromIndex(st, dataset); err != nil {
+		return err
+	}
+	if err := RemoveFromSearch(st, dataset); err != nil {
+		return err
+	}
+	for _, kind := range []store.Kind{store.Reports, store.Keywords, store.Audit, store.Meta, store.Datasets} {
+		if err := st.Delete(kind, dataset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenameDataset sets the name a dataset is displayed under. The dataset keeps
+// its ID; an empty name goes back to showing the original file name.
+func RenameDataset(st store.Store, dataset, displayName string) (*Metadata, error) {
+	meta, err := LoadMetadata(st, dataset)
+	if err != nil {
+		return nil, err
+	}
+	meta.DisplayName = strings.TrimSpace(displayName)
+	if err := SaveMetadata(st, dataset, *meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// RetentionPolicy says how long uploads are kept. A zero age keeps them forever.
+type RetentionPolicy struct {
+	// UploadMaxAge is how long the raw uploaded document is kept; the dataset
+	// extracted from it stays
+	UploadMaxAge time.Duration
+	// DatasetMaxAge is how long a dataset is kept before it is deleted with
+	// everything belonging to it
+	DatasetMaxAge time.Duration
+}
+
+// Enabled reports whether the policy removes anything
+func (p RetentionPolicy) Enabled() bool {
+	return p.UploadMaxAge > 0 || p.DatasetMaxAge > 0
+}
+
+// RetentionResult lists what a retention run removed
+type RetentionResult struct {
+	Uploads  []string `json:"uploads"`
+	Datasets []string `json:"datasets"`
+}
+
+// ParseRetentionAge parses an age such as "720h" or "30d"; "" means forever
+func ParseRetentionAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid retention age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid retention age %q", s)
+	}
+	return d, nil
+}
+
+// ApplyRetention removes the uploads and datasets that are older than the
+// policy allows at now, logging every removal. The age of a dataset is taken
+// from its metadata, or from the store when it has none.
+func ApplyRetention(st store.Store, policy RetentionPolicy, now time.Time) (RetentionResult, error) {
+	result := RetentionResult{Uploads: []string{}, Datasets: []string{}}
+	if !policy.Enabled() {
+		return result, nil
+	}
+
+	datasets, err := st.List(store.Datasets)
+	if err != nil {
+		return result, err
+	}
+
+	for _, obj := range datasets {
+		uploadedAt := obj.Modified
+		meta, err := LoadMetadata(st, obj.Name)
+		if err == nil && !meta.UploadedAt.IsZero() {
+			uploadedAt = meta.UploadedAt
+		}
+		if uploadedAt.IsZero() {
+			continue
+		}
+		age := now.Sub(uploadedAt)
+
+		if policy.DatasetMaxAge > 0 && age > policy.DatasetMaxAge {
+			if err := DeleteDataset(st, obj.Name); err != nil {
+				log.Printf("Retention: failed to delete dataset %s: %v", obj.Name, err)
+				continue
+			}
+			log.Printf("Retention: deleted dataset %s uploaded %s ago", obj.Name, age.Round(time.Minute))
+			result.Datasets = append(result.Datasets, obj.Name)
+			continue
+		}
+
+		if policy.UploadMaxAge > 0 && age > policy.UploadMaxAge {
+			source := SourceName(obj.Name)
+			if _, err := st.Stat(store.Documents, source); errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+			if err := st.Delete(store.Documents, source); err != nil {
+				log.Printf("Retention: failed to delete upload %s: %v", source, err)
+				continue
+			}
+			if meta != nil {
+				meta.SourceRemoved = true
+				if err := SaveMetadata(st, obj.Name, *meta); err != nil {
+					log.Printf("Retention: failed to update metadata of %s: %v", obj.Name, err)
+				}
+			}
+			log.Printf("Retention: deleted upload %s uploaded %s ago", source, age.Round(time.Minute))
+			result.Uploads = append(result.Uploads, source)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,111 @@
+package util
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+// storeDataset extracts content into a dataset uploaded at uploadedAt
+func storeDataset(t *testing.T, st store.Store, source, content string, uploadedAt time.Time) string {
+	t.Helper()
+
+	dataset := source + ".json"
+	if err := st.Put(store.Documents, source, []byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseDocument(st, source, dataset); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := NewMetadata(st, source, dataset, source, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta.UploadedAt = uploadedAt
+	if err := SaveMetadata(st, dataset, meta); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordContentHash(st, meta.SHA256, dataset); err != nil {
+		t.Fatal(err)
+	}
+	return dataset
+}
+
+func TestDeleteDataset(t *testing.T) {
+	st := store.NewMemory()
+	dataset := storeDataset(t, st, "report.txt", "Rainfall was 5 mm", time.Now())
+
+	if err := DeleteDataset(st, dataset); err != nil {
+		t.Fatalf("DeleteDataset() error = %v", err)
+	}
+	for _, kind := range store.Kinds {
+		// The listing and search indexes are shared by all datasets
+		if kind == store.Index || kind == store.Search {
+			continue
+		}
+		if objects, _ := st.List(kind); len(objects) != 0 {
+			t.Errorf("%s still holds %v", kind, objects)
+		}
+	}
+	if index, _ := LoadIndex(st); len(index) != 0 {
+		t.Errorf("index still lists %v", index)
+	}
+	if err := DeleteDataset(st, dataset); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("DeleteDataset() twice error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	st := store.NewMemory()
+	fresh := storeDataset(t, st, "fresh.txt", "Rainfall was 5 mm", now.Add(-time.Hour))
+	old := storeDataset(t, st, "old.txt", "Rainfall was 7 mm", now.Add(-10*24*time.Hour))
+	ancient := storeDataset(t, st, "ancient.txt", "Rainfall was 9 mm", now.Add(-100*24*time.Hour))
+
+	policy := RetentionPolicy{UploadMaxAge: 7 * 24 * time.Hour, DatasetMaxAge: 90 * 24 * time.Hour}
+	result, err := ApplyRetention(st, policy, now)
+	if err != nil {
+		t.Fatalf("ApplyRetention() error = %v", err)
+	}
+	if len(result.Uploads) != 1 || result.Uploads[0] != "old.txt" {
+		t.Errorf("removed uploads %v, want old.txt", result.Uploads)
+	}
+	if len(result.Datasets) != 1 || result.Datasets[0] != ancient {
+		t.Errorf("removed datasets %v, want %s", result.Datasets, ancient)
+	}
+
+	if _, err := st.Stat(store.Documents, "fresh.txt"); err != nil {
+		t.Errorf("fresh upload removed: %v", err)
+	}
+	if _, err := st.Stat(store.Datasets, old); err != nil {
+		t.Errorf("dataset of old upload removed: %v", err)
+	}
+	if meta, err := LoadMetadata(st, old); err != nil || !meta.SourceRemoved {
+		t.Errorf("metadata of %s = %+v, %v, want the source marked removed", old, meta, err)
+	}
+	if _, err := LoadMetadata(st, fresh); err != nil {
+		t.Errorf("metadata of %s removed: %v", fresh, err)
+	}
+}
+
+func TestParseRetentionAge(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"36h", 36 * time.Hour, false},
+		{"30d", 30 * 24 * time.Hour, false},
+		{"-1h", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRetentionAge(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRetentionAge(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+// MergeExtractor is the extractor version recorded for merged datasets
+const MergeExtractor = "merge"
+
+// Alignments of merged points
+const (
+	AlignNone    = ""
+	AlignDate    = "date"
+	AlignSection = "section"
+)
+
+// MergeOptions control how datasets are combined
+type MergeOptions struct {
+	// Name is the display name of the merged dataset
+	Name string `json:"name"`
+	// LabelSources prefixes every label with the name of its source dataset
+	LabelSources bool `json:"label_sources"`
+	// Align orders the points by date or by section instead of by source
+	Align string `json:"align"`
+	// Deduplicate keeps only the first of identical points
+	Deduplicate bool `json:"deduplicate"`
+}
+
+// Lineage records a source dataset of a merged dataset
+type Lineage struct {
+	Dataset          string `json:"dataset"`
+	Name             string `json:"name"`
+	Version          int    `json:"version,omitempty"`
+	ExtractorVersion string `json:"extractor_version,omitempty"`
+	PointCount       int    `json:"point_count"`
+}
+
+// ErrNothingToMerge is returned when fewer than two datasets are given
+var ErrNothingToMerge = errors.New("at least two datasets are needed to merge")
+
+// MergeDatasets combines the points of sources into a new dataset named
+// dataset. Point IDs are prefixed with the position of their source ("s1.p3")
+// so they stay unique, and every point records the dataset it came from. The
+// metadata of the result lists the sources it was merged from.
+func MergeDatasets(st store.Store, dataset string, sources []string, opts MergeOptions) (Metadata, error) {
+	if len(sources) < 2 {
+		return Metadata{}, ErrNothingToMerge
+	}
+	if opts.Align != AlignNone && opts.Align != AlignDate && opts.Align != AlignSection {
+		return Metadata{}, fmt.Errorf("invalid alignment: %s", opts.Align)
+	}
+
+	var merged []DataPoint
+	var lineage []Lineage
+	seen := make(map[string]bool)
+	terms := TermFrequencies{Terms: make(map[string]int)}
+
+	for i, source := range sources {
+		points, err := ReadDataset(st, source)
+		if err != nil {
+			return Metadata{}, fmt.Errorf("reading %s: %w", source, err)
+		}
+
+		l := Lineage{Dataset: source, Name: source, PointCount: len(points)}
+		if meta, err := LoadMetadata(st, source); err == nil {
+			l.Name = datasetName(meta)
+			l.Version = meta.Version
+			l.ExtractorVersion = meta.ExtractorVersion
+		}
+		lineage = append(lineage, l)
+
+		prefix := fmt.Sprintf("s%d.", i+1)
+		for _, dp := range points {
+			if opts.Deduplicate {
+				key := pointKey(dp)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+
+			if dp.ID != "" {
+				dp.ID = prefix + dp.ID
+			}
+			if len(dp.Inputs) > 0 {
+				inputs := make([]string, len(dp.Inputs))
+				for j, in := range dp.Inputs {
+					inputs[j] = prefix + in
+				}
+				dp.Inputs = inputs
+			}
+			if dp.Origin == "" {
+				dp.Origin = source
+			}
+			if opts.LabelSources {
+				if dp.Label == "" {
+					dp.Label = l.Name
+				} else {
+					dp.Label = l.Name + ": " + dp.Label
+				}
+			}
+			merged = append(merged, dp)
+		}
+
+		if tf, err := LoadTermFrequencies(st, source); err == nil {
+			for term, n := range tf.Terms {
+				terms.Terms[term] += n
+			}
+			terms.TotalTerms += tf.TotalTerms
+		}
+	}
+
+	alignPoints(merged, opts.Align)
+
+	if err := WriteDataset(st, dataset, merged); err != nil {
+		return Metadata{}, err
+	}
+	terms.GeneratedAt = time.Now()
+	if err := SaveTermFrequencies(st, dataset, terms); err != nil {
+		return Metadata{}, err
+	}
+
+	units, err := GetUnitsFromDataset(st, dataset)
+	if err != nil {
+		return Metadata{}, err
+	}
+	meta := Metadata{
+		Dataset:          dataset,
+		DisplayName:      opts.Name,
+		OriginalName:     opts.Name,
+		MIMEType:         "application/json",
+		UploadedAt:       time.Now(),
+		ExtractorVersion: MergeExtractor,
+		PointCount:       len(merged),
+		Units:            units,
+		MergedFrom:       lineage,
+	}
+	if meta.OriginalName == "" {
+		meta.OriginalName = dataset
+	}
+	if v, err := RecordVersion(st, dataset, meta.ExtractorVersion, nil, meta.UploadedAt); err == nil {
+		meta.Version = v.Version
+	}
+	if err := SaveMetadata(st, dataset, meta); err != nil {
+		return Metadata{}, err
+	}
+	return meta, nil
+}
+
+// datasetName returns the name a dataset is shown under
+func datasetName(meta *Metadata) string {
+	if meta.DisplayName != "" {
+		return meta.DisplayName
+	}
+	if meta.OriginalName != "" {
+		return meta.OriginalName
+	}
+	return meta.Dataset
+}
+
+// pointKey identifies a point by what it measured, ignoring where it was found
+func pointKey(dp DataPoint) string {
+	key := fmt.Sprintf("%g|%s|%s|%s|%s|%s", dp.Value, dp.Unit, dp.Label, dp.Date, dp.Section, dp.Series)
+	if dp.Location != nil {
+		key += fmt.Sprintf("|%g,%g", dp.Location.Lat, dp.Location.Lon)
+	}
+	return key
+}
+
+// alignPoints orders points by date or by section, keeping the order of
+// points with the same key. Points without the key go last.
+func alignPoints(points []DataPoint, align string) {
+	if align == AlignNone {
+		return
+	}
+	key := func(dp DataPoint) string {
+		switch align {
+		case AlignDate:
+			return dp.Date
+		case AlignSection:
+			return dp.Section
+		}
+		return ""
+	}
+
+	// Sections keep the order they first appear in
+	rank := make(map[string]int)
+	if align == AlignSection {
+		for _, dp := range points {
+			if _, ok := rank[dp.Section]; !ok && dp.Section != "" {
+				rank[dp.Section] = len(rank)
+			}
+		}
+	}
+
+	sort.SliceStable(points, func(i, j int) bool {
+		a, b := key(points[i]), key(points[j])
+		if a == "" || b == "" {
+			return a != "" && b == ""
+		}
+		if align == AlignSection {
+			return rank[a] < rank[b]
+		}
+		return a < b
+	})
+}
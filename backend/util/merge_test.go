@@ -0,0 +1,54 @@
+package util
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+func TestMergeDatasets(t *testing.T) {
+	st := store.NewMemory()
+	january := storeDataset(t, st, "january.txt", "On 2024-01-31 rainfall was 5 mm", time.Now())
+	february := storeDataset(t, st, "february.txt", "On 2024-02-29 rainfall was 7 mm\nOn 2024-01-31 rainfall was 5 mm", time.Now())
+
+	meta, err := MergeDatasets(st, "year.json", []string{february, january}, MergeOptions{
+		Name:         "2024",
+		LabelSources: true,
+		Align:        AlignDate,
+		Deduplicate:  true,
+	})
+	if err != nil {
+		t.Fatalf("MergeDatasets() error = %v", err)
+	}
+
+	points, err := ReadDataset(st, "year.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 2 {
+		t.Fatalf("merged %d points, want 2 after deduplication: %+v", len(points), points)
+	}
+	if points[0].Date != "2024-01-31" || points[1].Date != "2024-02-29" {
+		t.Errorf("merged dates %s, %s, want them in order", points[0].Date, points[1].Date)
+	}
+	// The January value was first seen in the February report
+	if points[0].ID != "s1.p2" || points[0].Origin != february || points[0].Label != "february.txt" {
+		t.Errorf("first point = %+v, want s1.p2 from %s", points[0], february)
+	}
+
+	if meta.DisplayName != "2024" || meta.ExtractorVersion != MergeExtractor || meta.PointCount != 2 {
+		t.Errorf("metadata = %+v", meta)
+	}
+	if len(meta.MergedFrom) != 2 || meta.MergedFrom[1].Dataset != january || meta.MergedFrom[1].PointCount != 1 {
+		t.Errorf("lineage = %+v", meta.MergedFrom)
+	}
+
+	if _, _, err := Reextract(st, "year.json", false); !errors.Is(err, ErrNotExtracted) {
+		t.Errorf("Reextract() of merged dataset error = %v, want ErrNotExtracted", err)
+	}
+	if _, err := MergeDatasets(st, "one.json", []string{january}, MergeOptions{}); !errors.Is(err, ErrNothingToMerge) {
+		t.Errorf("MergeDatasets() of one dataset error = %v, want ErrNothingToMerge", err)
+	}
+}
@@ -0,0 +1,112 @@
+package util
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+// ExtractorVersion identifies the extraction pipeline that produced a dataset.
+// It started at 1.0.0 when versions were first recorded. Bump the minor
+// version whenever a change to extraction can change the points of a
+// document, and the major version when the stored points change shape.
+const ExtractorVersion = "1.0.0"
+
+// Metadata describes a dataset and the upload it was extracted from
+type Metadata struct {
+	Dataset          string    `json:"dataset"`
+	DisplayName      string    `json:"display_name,omitempty"`
+	OriginalName     string    `json:"original_name"`
+	MIMEType         string    `json:"mime_type"`
+	Size             int64     `json:"size"`
+	SHA256           string    `json:"sha256"`
+	UploadedAt       time.Time `json:"uploaded_at"`
+	ExtractorVersion string    `json:"extractor_version"`
+	Rules            []string  `json:"rules"`
+	Version          int       `json:"version,omitempty"`
+	PointCount       int       `json:"point_count"`
+	Units            []string  `json:"units"`
+	Aliases          []Alias   `json:"aliases,omitempty"`
+	SourceRemoved    bool      `json:"source_removed,omitempty"`
+	MergedFrom       []Lineage `json:"merged_from,omitempty"`
+}
+
+// Alias records a later upload of the same content that was linked to an
+// existing dataset instead of being extracted again
+type Alias struct {
+	OriginalName string    `json:"original_name"`
+	UploadedAt   time.Time `json:"uploaded_at"`
+}
+
+// ExtractionRules lists the rules the extraction pipeline currently applies,
+// including every registered derivation
+func ExtractionRules() []string {
+	rules := []string{
+		"units",
+		"discard:" + ReasonNoise,
+		"discard:" + ReasonLowConfidence,
+		"discard:" + ReasonUnitless,
+		"discard:" + ReasonCoordinate,
+		"dates",
+		"coordinates",
+		"sections",
+	}
+	for _, name := range DerivationNames() {
+		rules = append(rules, "derive:"+name)
+	}
+	return rules
+}
+
+// NewMetadata describes the dataset extracted from the stored document
+// source. An empty mimeType is detected from the document contents.
+func NewMetadata(st store.Store, source, dataset, originalName, mimeType string) (Metadata, error) {
+	content, err := st.Get(store.Documents, source)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	if mimeType == "" {
+		mimeType = http.DetectContentType(content)
+	}
+
+	points, err := ReadDataset(st, dataset)
+	if err != nil {
+		return Metadata{}, err
+	}
+	units, err := GetUnitsFromDataset(st, dataset)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	return Metadata{
+		Dataset:          dataset,
+		OriginalName:     originalName,
+		MIMEType:         mimeType,
+		Size:             int64(len(content)),
+		SHA256:           ContentHash(content),
+		UploadedAt:       time.Now(),
+		ExtractorVersion: ExtractorVersion,
+		Rules:            ExtractionRules(),
+		PointCount:       len(points),
+		Units:            units,
+	}, nil
+}
+
+// SaveMetadata stores the metadata of a dataset and brings its entry in the
+// listing index up to date
+func SaveMetadata(st store.Store, dataset string, meta Metadata) error {
+	if err := saveJSON(st, store.Meta, dataset, meta); err != nil {
+		return err
+	}
+	return indexDataset(st, dataset, meta)
+}
+
+// LoadMetadata reads the metadata stored for a dataset
+func LoadMetadata(st store.Store, dataset string) (*Metadata, error) {
+	var meta Metadata
+	if err := loadJSON(st, store.Meta, dataset, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
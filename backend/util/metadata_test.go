@@ -0,0 +1,41 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+func TestMetadataRoundTrip(t *testing.T) {
+	st := store.NewMemory()
+	if err := st.Put(store.Documents, "report.txt", []byte("Rainfall was 5 mm and 12 °C")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseDocument(st, "report.txt", "report.txt.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := NewMetadata(st, "report.txt", "report.txt.json", "Report.txt", "")
+	if err != nil {
+		t.Fatalf("NewMetadata() error = %v", err)
+	}
+
+	if meta.Size != 28 || meta.PointCount != 2 || meta.MIMEType != "text/plain; charset=utf-8" {
+		t.Errorf("NewMetadata() = size %d, %d points, %s", meta.Size, meta.PointCount, meta.MIMEType)
+	}
+	if meta.SHA256 != "84ad67f0f3d72596640ac2a2cb439a6ccaac0868243dfe2d29e560d2d1d8f89c" {
+		t.Errorf("NewMetadata() checksum = %q", meta.SHA256)
+	}
+
+	if err := SaveMetadata(st, "report.txt.json", meta); err != nil {
+		t.Fatalf("SaveMetadata() error = %v", err)
+	}
+	loaded, err := LoadMetadata(st, "report.txt.json")
+	if err != nil {
+		t.Fatalf("LoadMetadata() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Units, meta.Units) || loaded.SHA256 != meta.SHA256 || !loaded.UploadedAt.Equal(meta.UploadedAt) {
+		t.Errorf("LoadMetadata() = %+v, want %+v", loaded, meta)
+	}
+}
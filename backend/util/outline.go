@@ -0,0 +1,149 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxHeadingLength is the longest line that is still taken as a numbered or
+// underlined heading
+const maxHeadingLength = 80
+
+var (
+	markdownHeading = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+	numberedHeading = regexp.MustCompile(`^((?:\d+\.)*\d+)\.?\s+(\p{Lu}.*)$`)
+	underline       = regexp.MustCompile(`^(=+|-+)\s*$`)
+)
+
+// Heading is a section heading of a document
+type Heading struct {
+	Level  int    `json:"level"`
+	Title  string `json:"title"`
+	Offset int    `json:"offset"`
+	Line   int    `json:"line"`
+}
+
+/*
+FindHeadings returns the section headings of text in document order.
+
+Three heading styles are recognised:
+
+	# Title, ## Title ...        Markdown ATX headings, level from the number of #
+	Title                        Setext headings, level 1 for "=" and 2 for "-"
+	=====
+	2.1 Title                    Numbered headings, level from the number of parts
+
+Numbered and underlined headings must be short and must not end with a full
+stop, so numbered lists of sentences are not mistaken for an outline.
+*/
+func FindHeadings(text string) []Heading {
+	var headings []Heading
+
+	lines := strings.Split(text, "\n")
+	offset := 0
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
+		start := offset
+		offset += len(raw) + 1
+
+		if line == "" {
+			continue
+		}
+
+		if m := markdownHeading.FindStringSubmatch(line); m != nil {
+			headings = append(headings, Heading{Level: len(m[1]), Title: m[2], Offset: start, Line: i + 1})
+			continue
+		}
+
+		if !looksLikeTitle(line) {
+			continue
+		}
+
+		if m := numberedHeading.FindStringSubmatch(line); m != nil {
+			level := strings.Count(m[1], ".") + 1
+			headings = append(headings, Heading{Level: level, Title: line, Offset: start, Line: i + 1})
+			continue
+		}
+
+		if i+1 < len(lines) {
+			if m := underline.FindStringSubmatch(strings.TrimSpace(lines[i+1])); m != nil {
+				level := 1
+				if m[1][0] == '-' {
+					level = 2
+				}
+				headings = append(headings, Heading{Level: level, Title: line, Offset: start, Line: i + 1})
+			}
+		}
+	}
+
+	return headings
+}
+
+// looksLikeTitle reports whether line is short enough and shaped like a title
+func looksLikeTitle(line string) bool {
+	return len(line) <= maxHeadingLength && !strings.HasSuffix(line, ".") && !underline.MatchString(line)
+}
+
+// sectionAt returns the index of the heading whose section contains offset, or
+// -1 if offset comes before the first heading
+func sectionAt(headings []Heading, offset int) int {
+	section := -1
+	for i, h := range headings {
+		if h.Offset > offset {
+			break
+		}
+		section = i
+	}
+	return section
+}
+
+// OutlineTree builds the heading hierarchy of text under a "Document" root.
+// Every node counts the extracted measurements in its section, including its
+// subsections; derived points are not counted as they have no place of their
+// own in the document. Headings keep their document order.
+func OutlineTree(text string, points []DataPoint) *TreeNode {
+	headings := FindHeadings(text)
+
+	root := &TreeNode{Name: "Document", Rank: "document"}
+	nodes := make([]*TreeNode, len(headings))
+
+	// Attach every heading to the closest preceding heading of a lower level
+	parents := make([]int, len(headings))
+	stack := []int{}
+	for i, h := range headings {
+		for len(stack) > 0 && headings[stack[len(stack)-1]].Level >= h.Level {
+			stack = stack[:len(stack)-1]
+		}
+		nodes[i] = &TreeNode{Name: h.Title, Rank: fmt.Sprintf("h%d", h.Level)}
+		parents[i] = -1
+		parent := root
+		if len(stack) > 0 {
+			parents[i] = stack[len(stack)-1]
+			parent = nodes[parents[i]]
+		}
+		parent.Children = append(parent.Children, nodes[i])
+		stack = append(stack, i)
+	}
+
+	// Count each point in its own section and every enclosing one
+	for _, dp := range points {
+		if dp.Source == nil || len(dp.Inputs) > 0 {
+			continue
+		}
+		root.Count++
+		for s := sectionAt(headings, dp.Source.Offset); s >= 0; s = parents[s] {
+			nodes[s].Count++
+		}
+	}
+
+	return root
+}
+
+// sectionTitle returns the title of the innermost heading enclosing offset
+func sectionTitle(headings []Heading, offset int) string {
+	if s := sectionAt(headings, offset); s >= 0 {
+		return headings[s].Title
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package util
+
+import "testing"
+
+func TestOutlineTree(t *testing.T) {
+	text := "# Survey\n" +
+		"Rainfall was 5 mm.\n" +
+		"## Air\n" +
+		"PM levels reached 40 µg/m³ and 35 µg/m³.\n" +
+		"## Water\n" +
+		"Turbidity was 4 NTU.\n" +
+		"Results\n" +
+		"=======\n" +
+		"Temperature peaked at 31 °C.\n" +
+		"1. Appendix\n" +
+		"1.1 Methods\n" +
+		"2. Counting was done twice.\n"
+
+	extraction := Extract(text)
+	root := OutlineTree(text, append(extraction.Points, extraction.Derived...))
+
+	if root.Count != 5 {
+		t.Errorf("root count = %d, want 5", root.Count)
+	}
+
+	want := []struct {
+		name     string
+		count    int
+		children int
+	}{
+		{"Survey", 4, 2},
+		{"Results", 1, 0},
+		{"1. Appendix", 0, 1},
+	}
+	if len(root.Children) != len(want) {
+		t.Fatalf("got %d top level sections, want %d", len(root.Children), len(want))
+	}
+	for i, w := range want {
+		c := root.Children[i]
+		if c.Name != w.name || c.Count != w.count || len(c.Children) != w.children {
+			t.Errorf("section %d = %s (%d, %d children), want %s (%d, %d children)",
+				i, c.Name, c.Count, len(c.Children), w.name, w.count, w.children)
+		}
+	}
+
+	if air := root.Children[0].Children[0]; air.Name != "Air" || air.Count != 2 {
+		t.Errorf("subsection = %s (%d), want Air (2)", air.Name, air.Count)
+	}
+
+	for _, dp := range extraction.Points {
+		if dp.Unit == "NTU" && dp.Section != "Water" {
+			t.Errorf("NTU point section = %q, want Water", dp.Section)
+		}
+	}
+}
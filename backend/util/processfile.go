@@ -1,87 +1,65 @@
 package util
 
 import (
-	"bufio"
-	"encoding/json"
 	"fmt"
-	"io"
-	"os"
+	"log"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
 )
 
 /*
-ParseDocumentToJSON reads a text document, extracts numeric data with units, and saves the extracted information as a formatted JSON file.
+ParseDocument reads a stored text document, extracts numeric data with units, and saves the extracted information as a dataset.
 
-It reads the input file in 512-byte chunks, processes each chunk with GetData(), and collects all extracted DataPoint entries.
+It reads the whole source document, runs it through Extract() and writes the kept DataPoint
+entries, in document order, to the dataset followed by any derived points.
+Numbers without a unit are discarded. The term frequencies of the document are
+stored alongside for keyword ranking, and its text is added to the full-text index.
 
 Parameters:
 
-	filePath string   - path to the input text file
-	outputPath string - path where the output JSON file should be saved
+	st store.Store  - the store holding the document and receiving the dataset
+	source string   - name of the source document
+	dataset string  - name of the dataset to write
 
 Returns:
 
-	error - if any file operation or JSON encoding fails, the error is returned.
+	QualityReport - the quality report of the extraction
+	error         - if any store operation or JSON encoding fails, the error is returned.
 
 Dependencies:
-  - GetData(text string) []DataPoint: used to extract data from text chunks
+  - Extract(text string) Extraction: used to extract data from the document text
 
 Example:
 
-	err := ParseDocumentToJSON("report.txt", "data.json")
-	data.json will contain:
+	report, err := ParseDocument(st, "report.txt", "report.txt.json")
+	the dataset report.txt.json will contain:
 	[
-	  { "value": 23.5, "unit": "°C" },
-	  { "value": 120.0, "unit": "vehicles/hr" }
+	  { "value": 23.5, "unit": "°C", "source": { "offset": 12, ... } },
+	  { "value": 120.0, "unit": "vehicles/hr", "source": { "offset": 87, ... } }
 	]
 */
-func ParseDocumentToJSON(filePath string, outputPath string) error {
-	const chunkSize = 512
-	var allData []DataPoint
-
-	file, err := os.Open(filePath)
+func ParseDocument(st store.Store, source string, dataset string) (QualityReport, error) {
+	content, err := st.Get(store.Documents, source)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
-
-	reader := bufio.NewReader(file)
-	buffer := make([]byte, chunkSize)
-
-	for {
-		n, err := reader.Read(buffer)
-		if n > 0 {
-			chunkText := string(buffer[:n])
-			data := GetData(chunkText)
-			allData = append(allData, data...)
-		}
-
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("error reading file: %w", err)
-		}
+		return QualityReport{}, fmt.Errorf("failed to read document: %w", err)
 	}
 
-	// Write to JSON file
-	jsonFile, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
-	}
-	defer jsonFile.Close()
+	text := string(content)
+	extraction := Extract(text)
 
-	encoder := json.NewEncoder(jsonFile)
-	encoder.SetIndent("", "  ")
+	if err := WriteDataset(st, dataset, append(extraction.Points, extraction.Derived...)); err != nil {
+		return QualityReport{}, fmt.Errorf("failed to write dataset: %w", err)
+	}
 
-	err = encoder.Encode(allData)
-	if err != nil {
-		return fmt.Errorf("failed to encode JSON: %w", err)
+	// Keyword ranking counts the terms again when they are missing, so a
+	// failure here does not fail the upload
+	if err := SaveTermFrequencies(st, dataset, CountTerms(text, CurrentStopWords())); err != nil {
+		log.Printf("Failed to save term frequencies of %s: %v", dataset, err)
 	}
 
-	err = FilterAndRewriteByUnit(outputPath)
-	if err != nil {
-		return fmt.Errorf("failed to filter and rewrite JSON: %w", err)
+	if err := IndexDocument(st, dataset, text); err != nil {
+		return QualityReport{}, fmt.Errorf("failed to index document: %w", err)
 	}
 
-	return nil
+	return NewQualityReport(text, extraction), nil
 }
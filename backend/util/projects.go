@@ -0,0 +1,233 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+// ErrInvalidProject is returned for projects without a name
+var ErrInvalidProject = errors.New("invalid project")
+
+// projectsMu serialises changes of stored projects so concurrent updates and
+// assignments do not overwrite each other
+var projectsMu sync.Mutex
+
+// Project groups datasets under a name, a description and tags
+type Project struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Tags        []string  `json:"tags"`
+	Datasets    []string  `json:"datasets"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// HasTag reports whether the project is tagged tag, ignoring case
+func (p Project) HasTag(tag string) bool {
+	for _, t := range p.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether dataset is assigned to the project
+func (p Project) Contains(dataset string) bool {
+	for _, d := range p.Datasets {
+		if d == dataset {
+			return true
+		}
+	}
+	return false
+}
+
+// SaveProject validates and stores a project, tidying its tags
+func SaveProject(st store.Store, p *Project) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidProject)
+	}
+	p.Tags = normalizeTags(p.Tags)
+	if p.Datasets == nil {
+		p.Datasets = []string{}
+	}
+	now := time.Now()
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = now
+	}
+	p.UpdatedAt = now
+	return saveJSON(st, store.Projects, p.ID, p)
+}
+
+// LoadProject reads a stored project
+func LoadProject(st store.Store, id string) (*Project, error) {
+	var p Project
+	if err := loadJSON(st, store.Projects, id, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListProjects returns every stored project ordered by name
+func ListProjects(st store.Store) ([]Project, error) {
+	objects, err := st.List(store.Projects)
+	if err != nil {
+		return nil, err
+	}
+
+	projects := []Project{}
+	for _, obj := range objects {
+		p, err := LoadProject(st, obj.Name)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, *p)
+	}
+	sort.SliceStable(projects, func(i, j int) bool {
+		return strings.ToLower(projects[i].Name) < strings.ToLower(projects[j].Name)
+	})
+	return projects, nil
+}
+
+// UpdateProject applies change to a stored project and saves it
+func UpdateProject(st store.Store, id string, change func(*Project)) (*Project, error) {
+	projectsMu.Lock()
+	defer projectsMu.Unlock()
+
+	p, err := LoadProject(st, id)
+	if err != nil {
+		return nil, err
+	}
+	change(p)
+	if err := SaveProject(st, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// DeleteProject removes a project; its datasets are kept
+func DeleteProject(st store.Store, id string) error {
+	projectsMu.Lock()
+	defer projectsMu.Unlock()
+
+	return st.Delete(store.Projects, id)
+}
+
+// CheckDatasets returns ErrInvalidProject if any of datasets does not exist
+func CheckDatasets(st store.Store, datasets []string) error {
+	for _, dataset := range datasets {
+		if _, err := st.Stat(store.Datasets, dataset); errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("%w: dataset %s not found", ErrInvalidProject, dataset)
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssignDatasets adds datasets to a project, skipping those already in it
+func AssignDatasets(st store.Store, id string, datasets []string) (*Project, error) {
+	if err := CheckDatasets(st, datasets); err != nil {
+		return nil, err
+	}
+
+	projectsMu.Lock()
+	defer projectsMu.Unlock()
+
+	p, err := LoadProject(st, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, dataset := range datasets {
+		if !p.Contains(dataset) {
+			p.Datasets = append(p.Datasets, dataset)
+		}
+	}
+	if err := SaveProject(st, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// UnassignDataset removes a dataset from a project
+func UnassignDataset(st store.Store, id, dataset string) (*Project, error) {
+	projectsMu.Lock()
+	defer projectsMu.Unlock()
+
+	p, err := LoadProject(st, id)
+	if err != nil {
+		return nil, err
+	}
+	if !removeDataset(p, dataset) {
+		return p, nil
+	}
+	if err := SaveProject(st, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ProjectsOf maps every dataset to the IDs of the projects it is assigned to
+func ProjectsOf(projects []Project) map[string][]string {
+	membership := make(map[string][]string)
+	for _, p := range projects {
+		for _, dataset := range p.Datasets {
+			membership[dataset] = append(membership[dataset], p.ID)
+		}
+	}
+	return membership
+}
+
+// removeFromProjects drops a deleted dataset from every project
+func removeFromProjects(st store.Store, dataset string) error {
+	projectsMu.Lock()
+	defer projectsMu.Unlock()
+
+	projects, err := ListProjects(st)
+	if err != nil {
+		return err
+	}
+	for i := range projects {
+		if removeDataset(&projects[i], dataset) {
+			if err := SaveProject(st, &projects[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// removeDataset removes dataset from p and reports whether it was there
+func removeDataset(p *Project, dataset string) bool {
+	for i, d := range p.Datasets {
+		if d == dataset {
+			p.Datasets = append(p.Datasets[:i], p.Datasets[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeTags trims tags and drops empty and repeated ones
+func normalizeTags(tags []string) []string {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
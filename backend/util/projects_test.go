@@ -0,0 +1,114 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+func TestProjects(t *testing.T) {
+	st := store.NewMemory()
+	january := storeDataset(t, st, "january.txt", "Rainfall was 5 mm", time.Now())
+	february := storeDataset(t, st, "february.txt", "Rainfall was 7 mm", time.Now())
+
+	project := Project{ID: "rivers", Name: " Rivers ", Tags: []string{"water", " Water", ""}}
+	if err := SaveProject(st, &project); err != nil {
+		t.Fatalf("SaveProject() error = %v", err)
+	}
+	if project.Name != "Rivers" || !reflect.DeepEqual(project.Tags, []string{"water"}) || !project.HasTag("WATER") {
+		t.Errorf("saved project = %+v", project)
+	}
+	if err := SaveProject(st, &Project{ID: "empty"}); !errors.Is(err, ErrInvalidProject) {
+		t.Errorf("SaveProject() without name error = %v, want ErrInvalidProject", err)
+	}
+
+	p, err := AssignDatasets(st, "rivers", []string{january, february, january})
+	if err != nil {
+		t.Fatalf("AssignDatasets() error = %v", err)
+	}
+	if !reflect.DeepEqual(p.Datasets, []string{january, february}) {
+		t.Errorf("assigned %v", p.Datasets)
+	}
+	if _, err := AssignDatasets(st, "rivers", []string{"missing.json"}); !errors.Is(err, ErrInvalidProject) {
+		t.Errorf("AssignDatasets() of missing dataset error = %v, want ErrInvalidProject", err)
+	}
+
+	if p, err = UnassignDataset(st, "rivers", january); err != nil || !reflect.DeepEqual(p.Datasets, []string{february}) {
+		t.Errorf("UnassignDataset() = %v, %v", p, err)
+	}
+
+	if err := DeleteDataset(st, february); err != nil {
+		t.Fatal(err)
+	}
+	projects, err := ListProjects(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 1 || len(projects[0].Datasets) != 0 {
+		t.Errorf("projects after deleting %s = %+v", february, projects)
+	}
+}
+
+func TestAssignDatasetsConcurrently(t *testing.T) {
+	st := store.NewMemory()
+	if err := SaveProject(st, &Project{ID: "survey", Name: "Survey"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		dataset := fmt.Sprintf("d%d.json", i)
+		if err := WriteDataset(st, dataset, nil); err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := AssignDatasets(st, "survey", []string{dataset}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if p, err := LoadProject(st, "survey"); err != nil || len(p.Datasets) != 10 {
+		t.Errorf("project = %+v, %v, want all 10 datasets", p, err)
+	}
+}
+
+func TestUpdateAndDeleteProject(t *testing.T) {
+	st := store.NewMemory()
+	if err := SaveProject(st, &Project{ID: "survey", Name: "Survey"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteDataset(st, "a.json", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// An update keeps the datasets assigned after the project was loaded
+	if _, err := AssignDatasets(st, "survey", []string{"a.json"}); err != nil {
+		t.Fatal(err)
+	}
+	p, err := UpdateProject(st, "survey", func(p *Project) { p.Name = "River survey" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "River survey" || !p.Contains("a.json") {
+		t.Errorf("UpdateProject() = %+v, want renamed with a.json", p)
+	}
+
+	if err := DeleteProject(st, "survey"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AssignDatasets(st, "survey", []string{"a.json"}); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("AssignDatasets() after delete error = %v, want ErrNotFound", err)
+	}
+	if _, err := UpdateProject(st, "survey", func(*Project) {}); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("UpdateProject() after delete error = %v, want ErrNotFound", err)
+	}
+}
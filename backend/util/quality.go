@@ -0,0 +1,104 @@
+package util
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+// QualityReport summarises how well the extraction pipeline did on a document
+type QualityReport struct {
+	Candidates     int              `json:"candidates"`
+	Kept           int              `json:"kept"`
+	Discarded      int              `json:"discarded"`
+	DiscardReasons map[string]int   `json:"discard_reasons"`
+	UnitHistogram  map[string]int   `json:"unit_histogram"`
+	Duplicates     []DuplicateValue `json:"duplicates"`
+	Coverage       float64          `json:"coverage"`
+	GeneratedAt    time.Time        `json:"generated_at"`
+}
+
+// DuplicateValue is a value and unit pair that was extracted more than once
+type DuplicateValue struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+	Count int     `json:"count"`
+}
+
+// NewQualityReport builds the quality report for an extraction of text.
+//
+// Coverage is estimated as the share of non-blank lines of the document that
+// contain at least one kept data point.
+func NewQualityReport(text string, extraction Extraction) QualityReport {
+	report := QualityReport{
+		Candidates:     len(extraction.Points) + len(extraction.Discarded),
+		Kept:           len(extraction.Points),
+		Discarded:      len(extraction.Discarded),
+		DiscardReasons: make(map[string]int),
+		UnitHistogram:  make(map[string]int),
+		Duplicates:     []DuplicateValue{},
+		GeneratedAt:    time.Now(),
+	}
+
+	for _, c := range extraction.Discarded {
+		report.DiscardReasons[c.Reason]++
+	}
+
+	type valueKey struct {
+		value float64
+		unit  string
+	}
+	seen := make(map[valueKey]int)
+	linesWithData := make(map[int]struct{})
+
+	for _, dp := range extraction.Points {
+		report.UnitHistogram[dp.Unit]++
+		seen[valueKey{dp.Value, dp.Unit}]++
+		if dp.Source != nil {
+			linesWithData[dp.Source.Line] = struct{}{}
+		}
+	}
+
+	for k, count := range seen {
+		if count > 1 {
+			report.Duplicates = append(report.Duplicates, DuplicateValue{Value: k.value, Unit: k.unit, Count: count})
+		}
+	}
+	sort.Slice(report.Duplicates, func(i, j int) bool {
+		if report.Duplicates[i].Count != report.Duplicates[j].Count {
+			return report.Duplicates[i].Count > report.Duplicates[j].Count
+		}
+		if report.Duplicates[i].Unit != report.Duplicates[j].Unit {
+			return report.Duplicates[i].Unit < report.Duplicates[j].Unit
+		}
+		return report.Duplicates[i].Value < report.Duplicates[j].Value
+	})
+
+	nonBlank := 0
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" {
+			nonBlank++
+		}
+	}
+	if nonBlank > 0 {
+		report.Coverage = float64(len(linesWithData)) / float64(nonBlank)
+	}
+
+	return report
+}
+
+// SaveQualityReport stores the report of a dataset
+func SaveQualityReport(st store.Store, dataset string, report QualityReport) error {
+	return saveJSON(st, store.Reports, dataset, report)
+}
+
+// LoadQualityReport reads the quality report stored for a dataset
+func LoadQualityReport(st store.Store, dataset string) (*QualityReport, error) {
+	var report QualityReport
+	if err := loadJSON(st, store.Reports, dataset, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,494 @@
+package util
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+// ErrInvalidQuery is returned for filters that cannot be parsed
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Clause is one condition of a point query, such as value>35 or unit:°C
+type Clause struct {
+	Field  string
+	Op     string
+	Values []string
+}
+
+// PointQuery is a parsed filter. A point matches when it satisfies every clause.
+type PointQuery struct {
+	Clauses []Clause
+}
+
+// QueryResult is a point matching a query with the dataset it belongs to.
+// The point keeps its provenance in the source document.
+type QueryResult struct {
+	Dataset  string    `json:"dataset"`
+	Position int       `json:"position"`
+	Point    DataPoint `json:"point"`
+}
+
+// QueryPage is one page of query results
+type QueryPage struct {
+	Results    []QueryResult `json:"results"`
+	NextCursor string        `json:"next_cursor"`
+}
+
+// queryOps are the operators of the filter language, longest first so ">="
+// is not read as ">"
+var queryOps = []string{">=", "<=", "!=", ":", "~", ">", "<", "="}
+
+// queryFields lists the operators each field accepts
+var queryFields = map[string]string{
+	"unit":     ": = !=",
+	"category": ": = !=",
+	"dataset":  ": = !=",
+	"section":  ": = != ~",
+	"label":    ": = != ~",
+	"value":    ": = != > >= < <=",
+	"date":     ": = != > >= < <=",
+	"series":   ": = !=",
+}
+
+/*
+ParseQuery parses the filter language of the query endpoint.
+
+A filter is a list of clauses separated by spaces, all of which must hold.
+A clause is a field, an operator and a value; values with spaces are quoted.
+
+	unit:°C value>35 date:2024
+	unit:ppm section:"Air Quality"
+	category:water label~turbidity value:10..50
+
+Fields are unit, category, dataset, section, label, value, date and series.
+":" and "=" test equality, "!=" its opposite and "~" whether the text
+contains the value; strings are compared ignoring case. value and date also
+take >, >=, < and <=, and a range lo..hi with ":". A date given with ":"
+matches as a prefix, so date:2024 is every day of 2024 and date!=2024 every
+other day. A comma separated list matches any of its values: unit:°C,°F.
+
+Derived points, such as the heat index, share the units of measurements.
+unit and category clauses only match them when a series clause is given;
+series:aqi.us-epa selects every series nested under it.
+*/
+func ParseQuery(filter string) (PointQuery, error) {
+	var q PointQuery
+
+	terms, err := splitQuery(filter)
+	if err != nil {
+		return q, err
+	}
+
+	for _, term := range terms {
+		clause, err := parseClause(term)
+		if err != nil {
+			return q, err
+		}
+		q.Clauses = append(q.Clauses, clause)
+	}
+	return q, nil
+}
+
+// splitQuery splits a filter at spaces outside double quotes and removes the quotes
+func splitQuery(filter string) ([]string, error) {
+	var terms []string
+	var current strings.Builder
+	quoted, inTerm := false, false
+
+	for _, r := range filter {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inTerm = true
+		case (r == ' ' || r == '\t' || r == '\n') && !quoted:
+			if inTerm {
+				terms = append(terms, current.String())
+				current.Reset()
+				inTerm = false
+			}
+		default:
+			current.WriteRune(r)
+			inTerm = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("%w: unterminated quote", ErrInvalidQuery)
+	}
+	if inTerm {
+		terms = append(terms, current.String())
+	}
+	return terms, nil
+}
+
+// parseClause parses a single field, operator and value
+func parseClause(term string) (Clause, error) {
+	var clause Clause
+
+	at := -1
+	for i := range term {
+		for _, op := range queryOps {
+			if strings.HasPrefix(term[i:], op) {
+				at, clause.Op = i, op
+				break
+			}
+		}
+		if at >= 0 {
+			break
+		}
+	}
+	if at <= 0 {
+		return clause, fmt.Errorf("%w: %q is not field, operator and value", ErrInvalidQuery, term)
+	}
+
+	clause.Field = strings.ToLower(term[:at])
+	ops, ok := queryFields[clause.Field]
+	if !ok {
+		return clause, fmt.Errorf("%w: unknown field %q", ErrInvalidQuery, clause.Field)
+	}
+	if !strings.Contains(" "+ops+" ", " "+clause.Op+" ") {
+		return clause, fmt.Errorf("%w: %s does not take %s", ErrInvalidQuery, clause.Field, clause.Op)
+	}
+
+	value := term[at+len(clause.Op):]
+	if value == "" {
+		return clause, fmt.Errorf("%w: %s%s needs a value", ErrInvalidQuery, clause.Field, clause.Op)
+	}
+	clause.Values = strings.Split(value, ",")
+
+	if clause.Field == "category" {
+		for _, c := range clause.Values {
+			if !IsCategory(c) {
+				return clause, fmt.Errorf("%w: unknown category %q", ErrInvalidQuery, c)
+			}
+		}
+	}
+
+	if clause.Field == "value" {
+		for _, v := range clause.Values {
+			bounds := strings.SplitN(v, "..", 2)
+			for _, b := range bounds {
+				if _, err := strconv.ParseFloat(b, 64); err != nil {
+					return clause, fmt.Errorf("%w: %q is not a number", ErrInvalidQuery, b)
+				}
+			}
+			if len(bounds) == 2 && clause.Op != ":" {
+				return clause, fmt.Errorf("%w: ranges are written value:lo..hi", ErrInvalidQuery)
+			}
+		}
+	}
+	return clause, nil
+}
+
+// Matches reports whether a point of dataset satisfies the query
+func (q PointQuery) Matches(dataset string, dp DataPoint) bool {
+	if dp.Series != "" && q.has("unit", "category") && !q.has("series") {
+		return false
+	}
+	for _, c := range q.Clauses {
+		if !c.matches(dataset, dp) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether a point satisfies the clause for any of its values
+func (c Clause) matches(dataset string, dp DataPoint) bool {
+	var field string
+	switch c.Field {
+	case "unit":
+		field = dp.Unit
+	case "category":
+		field = UnitCategory(dp.Unit)
+	case "dataset":
+		field = dataset
+	case "section":
+		field = dp.Section
+	case "label":
+		field = dp.Label
+	case "date":
+		field = dp.Date
+	case "series":
+		field = dp.Series
+	}
+
+	if c.Op == "!=" {
+		// != is the opposite of ":", so date!=2024 is every day outside 2024
+		op := "="
+		if c.Field == "date" {
+			op = ":"
+		}
+		for _, v := range c.Values {
+			if c.matchesValue(field, v, dp.Value, op) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, v := range c.Values {
+		if c.matchesValue(field, v, dp.Value, c.Op) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesValue tests one value of the clause with op
+func (c Clause) matchesValue(field, v string, number float64, op string) bool {
+	if c.Field == "value" {
+		if lo, hi, ok := strings.Cut(v, ".."); ok {
+			low, _ := strconv.ParseFloat(lo, 64)
+			high, _ := strconv.ParseFloat(hi, 64)
+			return number >= low && number <= high
+		}
+		n, _ := strconv.ParseFloat(v, 64)
+		switch op {
+		case ">":
+			return number > n
+		case ">=":
+			return number >= n
+		case "<":
+			return number < n
+		case "<=":
+			return number <= n
+		}
+		return number == n
+	}
+
+	if c.Field == "date" {
+		if field == "" {
+			return false
+		}
+		switch op {
+		case ":":
+			return strings.HasPrefix(field, v)
+		case ">":
+			return field > v && !strings.HasPrefix(field, v)
+		case ">=":
+			return field >= v
+		case "<":
+			return field < v
+		case "<=":
+			return field <= v || strings.HasPrefix(field, v)
+		}
+		return field == v
+	}
+
+	if c.Field == "series" && op == ":" {
+		return strings.EqualFold(field, v) || strings.HasPrefix(strings.ToLower(field), strings.ToLower(v)+".")
+	}
+
+	if op == "~" {
+		return strings.Contains(strings.ToLower(field), strings.ToLower(v))
+	}
+	return strings.EqualFold(field, v)
+}
+
+// has reports whether the query has a clause on any of fields
+func (q PointQuery) has(fields ...string) bool {
+	for _, c := range q.Clauses {
+		if slices.Contains(fields, c.Field) {
+			return true
+		}
+	}
+	return false
+}
+
+// units returns the units a point must have, or nil when the query does not
+// restrict them
+func (q PointQuery) units() []string {
+	for _, c := range q.Clauses {
+		if c.Field == "unit" && (c.Op == ":" || c.Op == "=") {
+			return c.Values
+		}
+	}
+	return nil
+}
+
+// RunQuery returns the points of all datasets matching q, ordered by dataset
+// and by position within it, starting after cursor. Stores that index points
+// by unit answer unit clauses from the index.
+func RunQuery(st store.Store, q PointQuery, cursor string, limit int) (QueryPage, error) {
+	page := QueryPage{Results: []QueryResult{}}
+
+	afterDataset, afterPosition := "", -1
+	if cursor != "" {
+		var err error
+		if afterDataset, afterPosition, err = decodeQueryCursor(cursor); err != nil {
+			return page, err
+		}
+	}
+
+	// The listing index tells which datasets can have the units asked for
+	index, err := LoadIndex(st)
+	if err != nil {
+		return page, err
+	}
+	units := q.units()
+
+	datasets, err := queryDatasets(st, index, units, afterDataset)
+	if err != nil {
+		return page, err
+	}
+
+	for _, dataset := range datasets {
+		if entry, ok := index[dataset]; ok && units != nil && !hasAnyUnit(entry.Units, units) {
+			continue
+		}
+
+		candidates, err := queryCandidates(st, index, dataset, units)
+		if err != nil {
+			return page, fmt.Errorf("reading %s: %w", dataset, err)
+		}
+		for _, c := range candidates {
+			if dataset == afterDataset && c.Position <= afterPosition {
+				continue
+			}
+			if !q.Matches(dataset, c.Point) {
+				continue
+			}
+			if limit > 0 && len(page.Results) == limit {
+				last := page.Results[len(page.Results)-1]
+				page.NextCursor = encodeQueryCursor(last.Dataset, last.Position)
+				return page, nil
+			}
+			page.Results = append(page.Results, c)
+		}
+	}
+	return page, nil
+}
+
+// queryDatasets returns the sorted names of the datasets from afterDataset on
+// that a query for units has to read. Stores that index points by unit only
+// name those with the units; otherwise every dataset is returned.
+func queryDatasets(st store.Store, index map[string]IndexEntry, units []string, afterDataset string) ([]string, error) {
+	var names []string
+	if pi, ok := st.(store.PointIndex); ok && units != nil {
+		seen := make(map[string]bool)
+		for _, unit := range unitSpellings(index, units) {
+			withUnit, err := pi.DatasetsWithUnit(unit)
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range withUnit {
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+			}
+		}
+	} else {
+		objects, err := st.List(store.Datasets)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objects {
+			names = append(names, obj.Name)
+		}
+	}
+
+	datasets := make([]string, 0, len(names))
+	for _, name := range names {
+		if strings.HasSuffix(name, ".json") && name >= afterDataset {
+			datasets = append(datasets, name)
+		}
+	}
+	sort.Strings(datasets)
+	return datasets, nil
+}
+
+// queryCandidates returns the points of a dataset a query for units has to
+// test, in dataset order. Stores that index points by unit only return those
+// with the units.
+func queryCandidates(st store.Store, index map[string]IndexEntry, dataset string, units []string) ([]QueryResult, error) {
+	var candidates []QueryResult
+
+	if pi, ok := st.(store.PointIndex); ok && units != nil {
+		for _, unit := range unitSpellings(index, units) {
+			points, err := pi.PointsByUnit(dataset, unit)
+			if err != nil {
+				return nil, err
+			}
+			for _, point := range points {
+				var dp DataPoint
+				if err := json.Unmarshal(point.Data, &dp); err != nil {
+					return nil, fmt.Errorf("unmarshaling point: %w", err)
+				}
+				candidates = append(candidates, QueryResult{Dataset: dataset, Position: point.Position, Point: dp})
+			}
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].Position < candidates[j].Position
+		})
+		return candidates, nil
+	}
+
+	points, err := ReadDataset(st, dataset)
+	if err != nil {
+		return nil, err
+	}
+	for i, dp := range points {
+		candidates = append(candidates, QueryResult{Dataset: dataset, Position: i, Point: dp})
+	}
+	return candidates, nil
+}
+
+// unitSpellings returns units with the spellings of them, differing only in
+// case, that the listing index has seen. The point index matches units exactly
+// while queries ignore case.
+func unitSpellings(index map[string]IndexEntry, units []string) []string {
+	spellings := append([]string(nil), units...)
+	for _, entry := range index {
+		for _, have := range entry.Units {
+			if slices.Contains(spellings, have) {
+				continue
+			}
+			for _, want := range units {
+				if strings.EqualFold(have, want) {
+					spellings = append(spellings, have)
+					break
+				}
+			}
+		}
+	}
+	return spellings
+}
+
+// hasAnyUnit reports whether have contains any of want, ignoring case
+func hasAnyUnit(have, want []string) bool {
+	for _, h := range have {
+		for _, w := range want {
+			if strings.EqualFold(h, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// encodeQueryCursor returns the cursor pointing after a point of a dataset
+func encodeQueryCursor(dataset string, position int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", position, dataset)))
+}
+
+// decodeQueryCursor reads back the dataset and position a cursor points after
+func decodeQueryCursor(cursor string) (string, int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+	position, dataset, ok := strings.Cut(string(data), ":")
+	n, err := strconv.Atoi(position)
+	if !ok || err != nil || n < 0 || dataset == "" {
+		return "", 0, ErrInvalidCursor
+	}
+	return dataset, n, nil
+}
@@ -0,0 +1,128 @@
+package util
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+func TestParseQuery(t *testing.T) {
+	q, err := ParseQuery(`unit:°C,°F value>=35 section:"Air Quality" label~rain`)
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+	want := []Clause{
+		{Field: "unit", Op: ":", Values: []string{"°C", "°F"}},
+		{Field: "value", Op: ">=", Values: []string{"35"}},
+		{Field: "section", Op: ":", Values: []string{"Air Quality"}},
+		{Field: "label", Op: "~", Values: []string{"rain"}},
+	}
+	if len(q.Clauses) != len(want) {
+		t.Fatalf("ParseQuery() = %+v", q.Clauses)
+	}
+	for i, c := range q.Clauses {
+		if c.Field != want[i].Field || c.Op != want[i].Op || !equalStrings(c.Values, want[i].Values) {
+			t.Errorf("clause %d = %+v, want %+v", i, c, want[i])
+		}
+	}
+
+	for _, bad := range []string{"colour:red", "unit>5", "value:abc", "value>1..2", `section:"Air`, "category:weather", "unit:"} {
+		if _, err := ParseQuery(bad); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("ParseQuery(%q) error = %v, want ErrInvalidQuery", bad, err)
+		}
+	}
+}
+
+func TestRunQuery(t *testing.T) {
+	bolt, err := store.OpenBolt(filepath.Join(t.TempDir(), "biotree.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bolt.Close()
+
+	for name, st := range map[string]store.Store{"memory": store.NewMemory(), "bolt": bolt} {
+		t.Run(name, func(t *testing.T) {
+			testRunQuery(t, st)
+		})
+	}
+}
+
+func testRunQuery(t *testing.T, st store.Store) {
+	storeDataset(t, st, "a.txt", "On 2024-07-02 it was 36 °C\nOn 2023-07-02 it was 38 °C\nRainfall was 5 mm", time.Now())
+	storeDataset(t, st, "b.txt", "On 2024-08-11 it was 31 °C and 40 °C later", time.Now())
+
+	run := func(filter, cursor string, limit int) QueryPage {
+		t.Helper()
+		q, err := ParseQuery(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, err := RunQuery(st, q, cursor, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return page
+	}
+
+	page := run("unit:°C value>35 date:2024", "", 0)
+	if len(page.Results) != 2 || page.Results[0].Point.Value != 36 || page.Results[1].Point.Value != 40 {
+		t.Fatalf("results = %+v, want 36 and 40 °C", page.Results)
+	}
+	if page.Results[0].Point.Source == nil || page.Results[1].Dataset != "b.txt.json" {
+		t.Errorf("results lack provenance: %+v", page.Results)
+	}
+
+	first := run("unit:°C", "", 2)
+	if len(first.Results) != 2 || first.NextCursor == "" {
+		t.Fatalf("first page = %+v", first)
+	}
+	second := run("unit:°C", first.NextCursor, 2)
+	if len(second.Results) != 2 || second.Results[0].Point.Value != 31 || second.NextCursor != "" {
+		t.Errorf("second page = %+v", second)
+	}
+
+	if page := run("value:4..6 dataset:a.txt.json", "", 0); len(page.Results) != 1 || page.Results[0].Point.Unit != "mm" {
+		t.Errorf("range results = %+v", page.Results)
+	}
+	if page := run("unit:°c date!=2024", "", 0); len(page.Results) != 1 || page.Results[0].Point.Value != 38 {
+		t.Errorf("results outside 2024 = %+v, want 38 °C", page.Results)
+	}
+	if page := run("unit:mm,°C value<10", "", 0); len(page.Results) != 1 || page.Results[0].Position != 2 {
+		t.Errorf("results in mm = %+v, want the point at position 2", page.Results)
+	}
+	if _, err := RunQuery(st, PointQuery{}, "bogus", 0); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("RunQuery() with bad cursor error = %v", err)
+	}
+}
+
+func TestRunQueryLeavesOutDerivedPoints(t *testing.T) {
+	st := store.NewMemory()
+	storeDataset(t, st, "c.txt", "It was 34 °C with a relative humidity of 70 %", time.Now())
+
+	run := func(filter string) []QueryResult {
+		t.Helper()
+		q, err := ParseQuery(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, err := RunQuery(st, q, "", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return page.Results
+	}
+
+	if results := run("unit:°C"); len(results) != 1 || results[0].Point.Value != 34 {
+		t.Errorf("unit:°C = %+v, want the measured 34 °C only", results)
+	}
+	results := run("unit:°C series:heat_index")
+	if len(results) != 1 || results[0].Point.Series != "heat_index" || results[0].Point.Value <= 34 {
+		t.Errorf("unit:°C series:heat_index = %+v, want the heat index", results)
+	}
+	if results := run("value>35"); len(results) == 0 {
+		t.Error("value>35 without a unit clause left out the heat index")
+	}
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"log"
+	"os"
+)
+
+// RuleSet is a configuration file the extraction and analysis rules are read from
+type RuleSet struct {
+	Name string `json:"name"`
+	// Env names the environment variable that points at a replacement file
+	Env  string `json:"env"`
+	Data []byte `json:"-"`
+}
+
+// RuleSets returns the rule sets in use: the file named by each environment
+// variable when it is set and readable, the built-in file otherwise
+func RuleSets() []RuleSet {
+	sets := []RuleSet{
+		{Name: "aqi", Env: "BIOTREE_AQI_CONFIG", Data: defaultAQIConfig},
+		{Name: "standards", Env: "BIOTREE_STANDARDS_CONFIG", Data: defaultStandardsCatalogue},
+		{Name: "stopwords", Env: "BIOTREE_STOPWORDS_CONFIG", Data: defaultStopWords},
+		{Name: "taxa", Env: "BIOTREE_TAXA_CHECKLIST", Data: defaultTaxonReference},
+	}
+	for i, set := range sets {
+		path := os.Getenv(set.Env)
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Failed to read rule set %s, using defaults: %v", path, err)
+			continue
+		}
+		sets[i].Data = data
+	}
+	return sets
+}
@@ -0,0 +1,386 @@
+package util
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+const (
+	// searchTermsName is the name of the object mapping every term to the
+	// datasets containing it. Dataset names end in .json, so it cannot clash.
+	searchTermsName = "terms"
+	// searchBackfilledMarker names the object recording that the documents of
+	// datasets stored before the full-text index existed have been indexed
+	searchBackfilledMarker = "backfilled"
+	// passageWindow is how far apart, in bytes, the words of a query may be
+	// and still form one passage
+	passageWindow = 120
+	// snippetRadius is the number of bytes shown on each side of a passage
+	snippetRadius = 60
+	// maxPassages caps the passages returned per dataset
+	maxPassages = 3
+)
+
+// searchMu serialises updates of the term map
+var searchMu sync.Mutex
+
+// searchBackfilled remembers the stores whose documents are known to be indexed
+var searchBackfilled sync.Map
+
+// SearchDocument is the indexed text of one dataset with the byte offsets of
+// every term in it
+type SearchDocument struct {
+	Dataset   string           `json:"dataset"`
+	Text      string           `json:"text"`
+	Positions map[string][]int `json:"positions"`
+}
+
+// Passage is a part of a document matching a query
+type Passage struct {
+	Offset  int         `json:"offset"`
+	Length  int         `json:"length"`
+	Snippet string      `json:"snippet"`
+	Points  []DataPoint `json:"points"`
+}
+
+// SearchResult is a dataset matching a query
+type SearchResult struct {
+	Dataset  string    `json:"dataset"`
+	Name     string    `json:"name"`
+	Score    int       `json:"score"`
+	Passages []Passage `json:"passages"`
+}
+
+// searchToken is a term of a text and where it starts
+type searchToken struct {
+	term   string
+	offset int
+	length int
+}
+
+// searchTokens splits text into lower case runs of letters and digits
+func searchTokens(text string) []searchToken {
+	var tokens []searchToken
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if inWord && start < 0 {
+			start = i
+		}
+		if !inWord && start >= 0 {
+			tokens = append(tokens, searchToken{strings.ToLower(text[start:i]), start, i - start})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, searchToken{strings.ToLower(text[start:]), start, len(text) - start})
+	}
+	return tokens
+}
+
+// IndexDocument adds the text of a dataset's document to the full-text
+// index, replacing what was indexed for it before
+func IndexDocument(st store.Store, dataset, text string) error {
+	doc := SearchDocument{Dataset: dataset, Text: text, Positions: make(map[string][]int)}
+	for _, tok := range searchTokens(text) {
+		doc.Positions[tok.term] = append(doc.Positions[tok.term], tok.offset)
+	}
+	if err := saveJSON(st, store.Search, dataset, doc); err != nil {
+		return err
+	}
+
+	return changeSearchTerms(st, func(terms map[string][]string) {
+		removeSearchTerms(terms, dataset)
+		for term := range doc.Positions {
+			terms[term] = append(terms[term], dataset)
+		}
+	})
+}
+
+// RemoveFromSearch drops a dataset from the full-text index
+func RemoveFromSearch(st store.Store, dataset string) error {
+	if err := st.Delete(store.Search, dataset); err != nil {
+		return err
+	}
+	return changeSearchTerms(st, func(terms map[string][]string) {
+		removeSearchTerms(terms, dataset)
+	})
+}
+
+// backfillSearch indexes the source documents of datasets stored before the
+// full-text index existed, once per store. Documents uploaded since are
+// indexed as they are processed.
+func backfillSearch(st store.Store) error {
+	if _, done := searchBackfilled.Load(st); done {
+		return nil
+	}
+
+	_, err := st.Stat(store.Search, searchBackfilledMarker)
+	if err == nil {
+		searchBackfilled.Store(st, true)
+		return nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+
+	added, err := indexMissingDocuments(st)
+	if err != nil {
+		return err
+	}
+	if added > 0 {
+		log.Printf("Indexed %d documents for search", added)
+	}
+
+	if err := st.Put(store.Search, searchBackfilledMarker, []byte(time.Now().UTC().Format(time.RFC3339))); err != nil {
+		return err
+	}
+	searchBackfilled.Store(st, true)
+	return nil
+}
+
+// indexMissingDocuments indexes the source documents of datasets that are not
+// in the full-text index. Datasets whose source is gone are skipped.
+func indexMissingDocuments(st store.Store) (int, error) {
+	datasets, err := st.List(store.Datasets)
+	if err != nil {
+		return 0, err
+	}
+	indexed, err := st.List(store.Search)
+	if err != nil {
+		return 0, err
+	}
+	have := make(map[string]bool, len(indexed))
+	for _, obj := range indexed {
+		have[obj.Name] = true
+	}
+
+	added := 0
+	for _, dataset := range datasets {
+		if have[dataset.Name] {
+			continue
+		}
+		content, err := st.Get(store.Documents, SourceName(dataset.Name))
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return added, err
+		}
+		if err := IndexDocument(st, dataset.Name, string(content)); err != nil {
+			return added, err
+		}
+		added++
+	}
+	return added, nil
+}
+
+// Search finds the datasets whose documents contain every word of query,
+// best matches first. Words count as one match when they appear close
+// together; the passages around them are returned with the data points
+// extracted from them. The first search of a store without the backfill
+// marker indexes the documents of datasets stored before the index existed.
+func Search(st store.Store, query string, limit int) ([]SearchResult, error) {
+	if err := backfillSearch(st); err != nil {
+		return nil, err
+	}
+
+	var words []string
+	seen := make(map[string]bool)
+	for _, tok := range searchTokens(query) {
+		if !seen[tok.term] {
+			seen[tok.term] = true
+			words = append(words, tok.term)
+		}
+	}
+	results := []SearchResult{}
+	if len(words) == 0 {
+		return results, nil
+	}
+
+	terms, err := loadSearchTerms(st)
+	if err != nil {
+		return nil, err
+	}
+
+	// Candidates contain every word
+	var candidates []string
+	for i, word := range words {
+		datasets := terms[word]
+		if i == 0 {
+			candidates = append(candidates, datasets...)
+			continue
+		}
+		in := make(map[string]bool, len(datasets))
+		for _, d := range datasets {
+			in[d] = true
+		}
+		kept := candidates[:0]
+		for _, d := range candidates {
+			if in[d] {
+				kept = append(kept, d)
+			}
+		}
+		candidates = kept
+	}
+
+	phrase := strings.ToLower(strings.Join(strings.Fields(query), " "))
+	for _, dataset := range candidates {
+		var doc SearchDocument
+		if err := loadJSON(st, store.Search, dataset, &doc); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+
+		spans := matchSpans(doc, words)
+		if len(spans) == 0 {
+			continue
+		}
+
+		result := SearchResult{Dataset: dataset, Name: dataset, Score: len(spans)}
+		if meta, err := LoadMetadata(st, dataset); err == nil {
+			result.Name = datasetName(meta)
+		}
+		if strings.Contains(strings.ToLower(doc.Text), phrase) {
+			result.Score += 10
+		}
+
+		points, _ := ReadDataset(st, dataset)
+		for _, span := range spans {
+			if len(result.Passages) == maxPassages {
+				break
+			}
+			result.Passages = append(result.Passages, newPassage(doc.Text, span, points))
+		}
+		results = append(results, result)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Dataset < results[j].Dataset
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// matchSpans returns the non-overlapping [start, end) byte spans of doc where
+// every word occurs within passageWindow of an occurrence of the first word
+func matchSpans(doc SearchDocument, words []string) [][2]int {
+	var spans [][2]int
+	for _, offset := range doc.Positions[words[0]] {
+		start, end := offset, offset+len(words[0])
+		matched := true
+		for _, word := range words[1:] {
+			best := -1
+			for _, o := range doc.Positions[word] {
+				if o >= offset-passageWindow && o <= offset+passageWindow && (best < 0 || abs(o-offset) < abs(best-offset)) {
+					best = o
+				}
+			}
+			if best < 0 {
+				matched = false
+				break
+			}
+			start = min(start, best)
+			end = max(end, best+len(word))
+		}
+		if !matched {
+			continue
+		}
+		if n := len(spans); n > 0 && start <= spans[n-1][1] {
+			spans[n-1][1] = max(spans[n-1][1], end)
+			continue
+		}
+		spans = append(spans, [2]int{start, end})
+	}
+	return spans
+}
+
+// newPassage cuts the snippet around span out of text and collects the
+// points extracted from it
+func newPassage(text string, span [2]int, points []DataPoint) Passage {
+	start := max(0, span[0]-snippetRadius)
+	end := min(len(text), span[1]+snippetRadius)
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+
+	snippet := strings.Join(strings.Fields(text[start:end]), " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(text) {
+		snippet += "…"
+	}
+
+	passage := Passage{Offset: span[0], Length: span[1] - span[0], Snippet: snippet, Points: []DataPoint{}}
+	for _, dp := range points {
+		if dp.Source != nil && dp.Source.Offset >= start && dp.Source.Offset+dp.Source.Length <= end {
+			passage.Points = append(passage.Points, dp)
+		}
+	}
+	return passage
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// loadSearchTerms reads the map from every term to the datasets containing it
+func loadSearchTerms(st store.Store) (map[string][]string, error) {
+	terms := make(map[string][]string)
+	if err := loadJSON(st, store.Search, searchTermsName, &terms); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+	return terms, nil
+}
+
+// changeSearchTerms applies change to the stored term map
+func changeSearchTerms(st store.Store, change func(map[string][]string)) error {
+	searchMu.Lock()
+	defer searchMu.Unlock()
+
+	terms, err := loadSearchTerms(st)
+	if err != nil {
+		return err
+	}
+	change(terms)
+	return saveJSON(st, store.Search, searchTermsName, terms)
+}
+
+// removeSearchTerms drops dataset from every term of the map
+func removeSearchTerms(terms map[string][]string, dataset string) {
+	for term, datasets := range terms {
+		kept := datasets[:0]
+		for _, d := range datasets {
+			if d != dataset {
+				kept = append(kept, d)
+			}
+		}
+		if len(kept) == 0 {
+			delete(terms, term)
+		} else {
+			terms[term] = kept
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+func TestSearch(t *testing.T) {
+	st := store.NewMemory()
+	rivers := storeDataset(t, st, "rivers.txt", "Sampling report\nNairobi River turbidity reached 40 NTU in March.\n"+
+		"Samples were taken upstream and downstream of the treatment works on three mornings.\nAir was clear.", time.Now())
+	storeDataset(t, st, "roads.txt", "Traffic near the Nairobi bypass was 900 vehicles/hr. The river crossing flooded.", time.Now())
+
+	results, err := Search(st, "Nairobi River turbidity", 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Dataset != rivers {
+		t.Fatalf("Search() = %+v, want only %s", results, rivers)
+	}
+
+	passage := results[0].Passages[0]
+	if !strings.Contains(passage.Snippet, "Nairobi River turbidity reached 40 NTU") {
+		t.Errorf("snippet = %q", passage.Snippet)
+	}
+	if len(passage.Points) != 1 || passage.Points[0].Unit != "NTU" {
+		t.Errorf("passage points = %+v, want the turbidity reading", passage.Points)
+	}
+
+	// Words far apart are not one match
+	if results, _ := Search(st, "sampling clear", 10); len(results) != 0 {
+		t.Errorf("Search() of distant words = %+v, want none", results)
+	}
+
+	if err := DeleteDataset(st, rivers); err != nil {
+		t.Fatal(err)
+	}
+	results, _ = Search(st, "nairobi", 10)
+	if len(results) != 1 || results[0].Dataset == rivers {
+		t.Errorf("Search() after delete = %+v", results)
+	}
+}
+
+func TestSearchBackfillsOnce(t *testing.T) {
+	st := store.NewMemory()
+	storeDataset(t, st, "rivers.txt", "River turbidity reached 40 NTU", time.Now())
+
+	// A dataset stored before the index existed has no search entry
+	unindexed := storeDataset(t, st, "older.txt", "Lake turbidity reached 12 NTU", time.Now())
+	if err := RemoveFromSearch(st, unindexed); err != nil {
+		t.Fatal(err)
+	}
+
+	if results, err := Search(st, "lake", 10); err != nil || len(results) != 1 || results[0].Dataset != unindexed {
+		t.Fatalf("Search() = %+v, %v, want the backfilled %s", results, err, unindexed)
+	}
+	if _, err := st.Stat(store.Search, searchBackfilledMarker); err != nil {
+		t.Fatalf("backfill marker not written: %v", err)
+	}
+
+	// Later searches read the index only
+	if err := RemoveFromSearch(st, unindexed); err != nil {
+		t.Fatal(err)
+	}
+	if results, err := Search(st, "lake", 10); err != nil || len(results) != 0 {
+		t.Errorf("Search() after the backfill = %+v, %v, want no results", results, err)
+	}
+}
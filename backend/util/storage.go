@@ -0,0 +1,29 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+// saveJSON stores v as indented JSON
+func saveJSON(st store.Store, kind store.Kind, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling %s: %w", kind, err)
+	}
+	return st.Put(kind, name, data)
+}
+
+// loadJSON reads a stored JSON object into v
+func loadJSON(st store.Store, kind store.Kind, name string, v interface{}) error {
+	data, err := st.Get(kind, name)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("unmarshaling %s/%s: %w", kind, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package util
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:embed config/taxa.json
+var defaultTaxonReference []byte
+
+// Taxonomic ranks from the root of the tree down to species
+var taxonRanks = []string{"kingdom", "phylum", "class", "order", "family", "genus", "species"}
+
+var (
+	binomialPattern    = regexp.MustCompile(`\b([A-Z][a-z]{2,})\s+([a-z]{3,})\b`)
+	abbreviatedPattern = regexp.MustCompile(`\b([A-Z])\.\s?([a-z]{3,})\b`)
+
+	// Words that follow a genus name without being a specific epithet
+	notEpithets = map[string]bool{
+		"spp": true, "species": true, "trees": true, "tree": true, "plants": true,
+		"population": true, "populations": true, "and": true, "the": true, "was": true,
+		"were": true, "are": true, "for": true, "with": true, "from": true, "has": true,
+		"have": true, "had": true, "individuals": true, "stands": true, "cover": true,
+	}
+)
+
+// Taxon is the classification of a genus or species
+type Taxon struct {
+	Species string `json:"species,omitempty"`
+	Genus   string `json:"genus"`
+	Family  string `json:"family,omitempty"`
+	Order   string `json:"order,omitempty"`
+	Class   string `json:"class,omitempty"`
+	Phylum  string `json:"phylum,omitempty"`
+	Kingdom string `json:"kingdom,omitempty"`
+}
+
+// TaxonReference resolves genus and species names to their higher taxa
+type TaxonReference struct {
+	Taxa []Taxon `json:"taxa"`
+
+	byGenus   map[string]Taxon
+	bySpecies map[string]Taxon
+	epithets  map[string]bool
+}
+
+// SpeciesOccurrence is a species found in a document with its count. Names
+// whose epithet the reference does not know are reported unresolved, with
+// the higher taxa of their genus.
+type SpeciesOccurrence struct {
+	Name     string `json:"name"`
+	Count    int    `json:"count"`
+	Resolved bool   `json:"resolved"`
+	Taxon    Taxon  `json:"taxon"`
+}
+
+var (
+	taxonReferenceOnce sync.Once
+	taxonReference     *TaxonReference
+)
+
+// NewTaxonReference indexes a list of taxa by genus and species
+func NewTaxonReference(taxa []Taxon) *TaxonReference {
+	ref := &TaxonReference{
+		Taxa:      taxa,
+		byGenus:   make(map[string]Taxon),
+		bySpecies: make(map[string]Taxon),
+		epithets:  make(map[string]bool),
+	}
+	for _, t := range taxa {
+		if t.Species != "" {
+			ref.bySpecies[t.Species] = t
+			if words := strings.Fields(t.Species); len(words) > 1 {
+				ref.epithets[words[1]] = true
+			}
+		}
+		if t.Genus != "" {
+			if _, ok := ref.byGenus[t.Genus]; !ok || t.Species == "" {
+				genusOnly := t
+				genusOnly.Species = ""
+				ref.byGenus[t.Genus] = genusOnly
+			}
+		}
+	}
+	return ref
+}
+
+// CurrentTaxonReference returns the built-in reference list, extended with the
+// checklist named by BIOTREE_TAXA_CHECKLIST when it is set
+func CurrentTaxonReference() *TaxonReference {
+	taxonReferenceOnce.Do(func() {
+		var builtIn TaxonReference
+		if err := json.Unmarshal(defaultTaxonReference, &builtIn); err != nil {
+			log.Printf("Failed to parse built-in taxon reference: %v", err)
+		}
+		taxonReference = NewTaxonReference(builtIn.Taxa)
+
+		if path := os.Getenv("BIOTREE_TAXA_CHECKLIST"); path != "" {
+			file, err := os.Open(path)
+			if err != nil {
+				log.Printf("Failed to open taxa checklist %s: %v", path, err)
+				return
+			}
+			defer file.Close()
+
+			checklist, err := ParseChecklist(file)
+			if err != nil {
+				log.Printf("Failed to parse taxa checklist %s: %v", path, err)
+				return
+			}
+			taxonReference = taxonReference.With(checklist)
+		}
+	})
+	return taxonReference
+}
+
+// With returns a reference that also contains taxa, which take precedence
+func (ref *TaxonReference) With(taxa []Taxon) *TaxonReference {
+	combined := append(append([]Taxon{}, ref.Taxa...), taxa...)
+	return NewTaxonReference(combined)
+}
+
+// Resolve looks up a binomial name, first as a species and then by its genus.
+// A name is only resolved through its genus when the epithet is one the
+// reference knows; otherwise the genus's higher taxa are returned unresolved.
+func (ref *TaxonReference) Resolve(genus, epithet string) (Taxon, bool) {
+	name := genus + " " + epithet
+	if t, ok := ref.bySpecies[name]; ok {
+		return t, true
+	}
+	if t, ok := ref.byGenus[genus]; ok {
+		t.Species = name
+		return t, ref.epithets[epithet]
+	}
+	return Taxon{Genus: genus, Species: name}, false
+}
+
+// hasGenus reports whether the reference knows a genus
+func (ref *TaxonReference) hasGenus(genus string) bool {
+	_, ok := ref.byGenus[genus]
+	return ok
+}
+
+// ParseChecklist reads a CSV checklist whose header names some of the columns
+// kingdom, phylum, class, order, family, genus and species. When the genus
+// column is missing it is taken from the first word of the species.
+func ParseChecklist(r io.Reader) ([]Taxon, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading checklist header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["species"]; !ok {
+		if _, ok := columns["genus"]; !ok {
+			return nil, fmt.Errorf("checklist needs a genus or species column")
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var taxa []Taxon
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading checklist: %w", err)
+		}
+
+		t := Taxon{
+			Species: field(record, "species"),
+			Genus:   field(record, "genus"),
+			Family:  field(record, "family"),
+			Order:   field(record, "order"),
+			Class:   field(record, "class"),
+			Phylum:  field(record, "phylum"),
+			Kingdom: field(record, "kingdom"),
+		}
+		if t.Genus == "" && t.Species != "" {
+			t.Genus = strings.Fields(t.Species)[0]
+		}
+		if t.Genus != "" {
+			taxa = append(taxa, t)
+		}
+	}
+
+	return taxa, nil
+}
+
+// FindSpecies returns the binomial species names in text whose genus the
+// reference knows, with their number of occurrences. Names the reference
+// cannot resolve are included with Resolved false; a capitalised word followed
+// by a lower-case one is too common in prose to count without a known genus.
+// Abbreviated names such as "A. tortilis" are expanded using the last genus
+// with the same initial.
+func FindSpecies(text string, ref *TaxonReference) []SpeciesOccurrence {
+	type mention struct {
+		offset  int
+		genus   string
+		epithet string
+	}
+	var mentions []mention
+
+	for _, m := range binomialPattern.FindAllStringSubmatchIndex(text, -1) {
+		mentions = append(mentions, mention{m[0], text[m[2]:m[3]], text[m[4]:m[5]]})
+	}
+	for _, m := range abbreviatedPattern.FindAllStringSubmatchIndex(text, -1) {
+		mentions = append(mentions, mention{m[0], text[m[2]:m[3]], text[m[4]:m[5]]})
+	}
+	sort.Slice(mentions, func(i, j int) bool {
+		return mentions[i].offset < mentions[j].offset
+	})
+
+	occurrences := make(map[string]*SpeciesOccurrence)
+	lastGenus := make(map[byte]string)
+
+	for _, m := range mentions {
+		if notEpithets[m.epithet] {
+			continue
+		}
+
+		genus := m.genus
+		if len(genus) == 1 {
+			if genus = lastGenus[m.genus[0]]; genus == "" {
+				continue
+			}
+		}
+
+		if !ref.hasGenus(genus) {
+			continue
+		}
+		taxon, resolved := ref.Resolve(genus, m.epithet)
+		lastGenus[genus[0]] = genus
+
+		occurrence, seen := occurrences[taxon.Species]
+		if !seen {
+			occurrence = &SpeciesOccurrence{Name: taxon.Species, Resolved: resolved, Taxon: taxon}
+			occurrences[taxon.Species] = occurrence
+		}
+		occurrence.Count++
+	}
+
+	result := make([]SpeciesOccurrence, 0, len(occurrences))
+	for _, o := range occurrences {
+		result = append(result, *o)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// TaxonomyTree builds the taxonomy hierarchy of the occurrences, from kingdom
+// down to species, with the occurrence count summed at every node.
+// Unresolved names are counted at their genus without a species node.
+func TaxonomyTree(occurrences []SpeciesOccurrence) *TreeNode {
+	root := &TreeNode{Name: "Life", Rank: "root"}
+
+	for _, o := range occurrences {
+		names := []string{o.Taxon.Kingdom, o.Taxon.Phylum, o.Taxon.Class, o.Taxon.Order, o.Taxon.Family, o.Taxon.Genus, o.Taxon.Species}
+		if !o.Resolved {
+			names = names[:len(names)-1]
+		}
+
+		root.Count += o.Count
+		node := root
+		for i, name := range names {
+			if name == "" {
+				name = "Unknown " + taxonRanks[i]
+			}
+			node = node.child(name, taxonRanks[i])
+			node.Count += o.Count
+		}
+	}
+
+	root.sortChildren()
+	return root
+}
@@ -0,0 +1,86 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseChecklist(t *testing.T) {
+	checklist := "Species, Family, Kingdom\n" +
+		"Acacia tortilis, Fabaceae, Plantae\n" +
+		"Loxodonta africana, Elephantidae, Animalia\n" +
+		", Unnamed, Plantae\n"
+
+	taxa, err := ParseChecklist(strings.NewReader(checklist))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(taxa) != 2 {
+		t.Fatalf("got %d taxa, want 2: %+v", len(taxa), taxa)
+	}
+	want := Taxon{Species: "Loxodonta africana", Genus: "Loxodonta", Family: "Elephantidae", Kingdom: "Animalia"}
+	if taxa[1] != want {
+		t.Errorf("taxa[1] = %+v, want %+v", taxa[1], want)
+	}
+
+	if _, err := ParseChecklist(strings.NewReader("family,order\nFabaceae,Fabales\n")); err == nil {
+		t.Error("ParseChecklist() accepted a checklist without genus or species")
+	}
+	if _, err := ParseChecklist(strings.NewReader("")); err == nil {
+		t.Error("ParseChecklist() accepted an empty checklist")
+	}
+}
+
+func TestFindSpecies(t *testing.T) {
+	ref := NewTaxonReference([]Taxon{
+		{Genus: "Acacia", Family: "Fabaceae", Kingdom: "Plantae"},
+		{Genus: "Balanites", Family: "Zygophyllaceae", Kingdom: "Plantae"},
+		{Species: "Balanites aegyptiaca", Genus: "Balanites", Family: "Zygophyllaceae", Kingdom: "Plantae"},
+		{Species: "Vachellia tortilis", Genus: "Vachellia", Family: "Fabaceae", Kingdom: "Plantae"},
+	})
+	text := "Balanites aegyptiaca was common. Acacia tortilis and A. tortilis seedlings were counted. " +
+		"Acacia dominated the plots, and Acacia spp. were frequent. Temperature reached 31 °C. " +
+		"B. aegyptiaca was browsed."
+
+	got := make(map[string]SpeciesOccurrence)
+	for _, o := range FindSpecies(text, ref) {
+		got[o.Name] = o
+	}
+
+	tests := []struct {
+		name     string
+		count    int
+		resolved bool
+		family   string
+	}{
+		{"Balanites aegyptiaca", 2, true, "Zygophyllaceae"},
+		// tortilis is a known epithet, so the genus alone resolves it
+		{"Acacia tortilis", 2, true, "Fabaceae"},
+		{"Acacia dominated", 1, false, "Fabaceae"},
+	}
+	for _, tt := range tests {
+		o, ok := got[tt.name]
+		if !ok {
+			t.Errorf("%s not found", tt.name)
+			continue
+		}
+		if o.Count != tt.count || o.Resolved != tt.resolved || o.Taxon.Family != tt.family {
+			t.Errorf("%s = %+v, want count %d, resolved %v, family %s", tt.name, o, tt.count, tt.resolved, tt.family)
+		}
+	}
+	if len(got) != len(tests) {
+		t.Errorf("found %d names, want %d: %+v", len(got), len(tests), got)
+	}
+
+	tree := TaxonomyTree(FindSpecies(text, ref))
+	if tree.Count != 5 {
+		t.Errorf("tree count = %d, want 5", tree.Count)
+	}
+	genus := tree
+	for _, name := range []string{"Plantae", "Unknown phylum", "Unknown class", "Unknown order", "Fabaceae", "Acacia"} {
+		genus = genus.child(name, "")
+	}
+	if genus.Count != 3 || len(genus.Children) != 1 || genus.Children[0].Name != "Acacia tortilis" {
+		t.Errorf("Acacia node = %+v, want count 3 with only Acacia tortilis below it", genus)
+	}
+}
@@ -0,0 +1,34 @@
+package util
+
+import "sort"
+
+// TreeNode is a node of a hierarchy, such as a taxonomy or a document outline,
+// with the count of items at or below it
+type TreeNode struct {
+	Name     string      `json:"name"`
+	Rank     string      `json:"rank,omitempty"`
+	Count    int         `json:"count"`
+	Children []*TreeNode `json:"children,omitempty"`
+}
+
+// child returns the child called name, creating it if needed
+func (n *TreeNode) child(name, rank string) *TreeNode {
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	c := &TreeNode{Name: name, Rank: rank}
+	n.Children = append(n.Children, c)
+	return c
+}
+
+// sortChildren orders every level of the tree by name
+func (n *TreeNode) sortChildren() {
+	sort.Slice(n.Children, func(i, j int) bool {
+		return n.Children[i].Name < n.Children[j].Name
+	})
+	for _, c := range n.Children {
+		c.sortChildren()
+	}
+}
@@ -3,58 +3,91 @@ package util
 import (
 	"encoding/json"
 	"fmt"
-	"os"
-)
-
-// GetUnitsFromFile reads a JSON file and returns a slice of unique units found in the data.
-func GetUnitsFromFile(filePath string) ([]string, error) {
-	dataBytes, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("reading file: %w", err)
-	}
 
-	var data []DataPoint
-	if err := json.Unmarshal(dataBytes, &data); err != nil {
-		return nil, fmt.Errorf("unmarshaling JSON: %w", err)
-	}
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
 
+// GetUnitsFromDataset reads a dataset and returns a slice of unique units found in the data,
+// ordered by category and then by name.
+func GetUnitsFromDataset(st store.Store, dataset string) ([]string, error) {
 	unitSet := make(map[string]struct{})
-	for _, dp := range data {
-		unitSet[dp.Unit] = struct{}{}
+
+	if index, ok := st.(store.PointIndex); ok {
+		counts, err := index.Units(dataset)
+		if err != nil {
+			return nil, err
+		}
+		for unit := range counts {
+			unitSet[unit] = struct{}{}
+		}
+	} else {
+		data, err := ReadDataset(st, dataset)
+		if err != nil {
+			return nil, err
+		}
+		for _, dp := range data {
+			unitSet[dp.Unit] = struct{}{}
+		}
 	}
 
-	// Convert map keys to slice
+	// Convert map keys to slice, ordered by category
 	var units []string
 	for unit := range unitSet {
 		units = append(units, unit)
 	}
+	SortUnits(units)
 
 	return units, nil
 }
 
-// GetDataByUnitFromFile returns a JSON string of all entries from the file with the specified unit.
-func GetDataByUnitFromFile(filePath string, unit string) (string, error) {
-	dataBytes, err := os.ReadFile(filePath)
-	if err != nil {
-		return "", fmt.Errorf("reading file: %w", err)
-	}
-
+// ReadDataset reads a stored dataset and returns its data points
+func ReadDataset(st store.Store, dataset string) ([]DataPoint, error) {
 	var data []DataPoint
-	if err := json.Unmarshal(dataBytes, &data); err != nil {
-		return "", fmt.Errorf("unmarshaling JSON: %w", err)
+	if err := loadJSON(st, store.Datasets, dataset, &data); err != nil {
+		return nil, err
 	}
+	return data, nil
+}
 
+// ReadDatasetByUnit returns the points of a dataset with the specified unit.
+// Stores that index points by unit are read through the index; otherwise the
+// whole dataset is read and filtered.
+func ReadDatasetByUnit(st store.Store, dataset string, unit string) ([]DataPoint, error) {
 	var filtered []DataPoint
-	for _, dp := range data {
-		if dp.Unit == unit {
+
+	if index, ok := st.(store.PointIndex); ok {
+		points, err := index.PointsByUnit(dataset, unit)
+		if err != nil {
+			return nil, err
+		}
+		for _, point := range points {
+			var dp DataPoint
+			if err := json.Unmarshal(point.Data, &dp); err != nil {
+				return nil, fmt.Errorf("unmarshaling point: %w", err)
+			}
 			filtered = append(filtered, dp)
 		}
+		return filtered, nil
 	}
 
-	result, err := json.MarshalIndent(filtered, "", "  ")
+	data, err := ReadDataset(st, dataset)
 	if err != nil {
-		return "", fmt.Errorf("marshaling result: %w", err)
+		return nil, err
 	}
+	for _, dp := range data {
+		if dp.Unit == unit {
+			filtered = append(filtered, dp)
+		}
+	}
+	return filtered, nil
+}
 
-	return string(result), nil
+// WriteDataset stores the data points of a dataset, replacing any previous
+// ones, and brings its entry in the listing index up to date. A dataset
+// without metadata yet is indexed once its metadata is saved.
+func WriteDataset(st store.Store, dataset string, points []DataPoint) error {
+	if err := saveJSON(st, store.Datasets, dataset, points); err != nil {
+		return err
+	}
+	return IndexDataset(st, dataset)
 }
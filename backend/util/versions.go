@@ -0,0 +1,244 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+// DatasetVersion is one immutable extraction of a dataset
+type DatasetVersion struct {
+	Version          int         `json:"version"`
+	ExtractorVersion string      `json:"extractor_version"`
+	Rules            []string    `json:"rules"`
+	ExtractedAt      time.Time   `json:"extracted_at"`
+	PointCount       int         `json:"point_count"`
+	Points           []DataPoint `json:"points,omitempty"`
+}
+
+var (
+	// ErrSourceRemoved is returned when a dataset can no longer be re-extracted
+	// because its source document was removed
+	ErrSourceRemoved = errors.New("source document was removed")
+	// ErrNotExtracted is returned when re-extracting a dataset that was not
+	// extracted from a document, such as a merged one
+	ErrNotExtracted = errors.New("dataset was not extracted from a document")
+)
+
+// versionsMu serialises updates of the version lists
+var versionsMu sync.Mutex
+
+// versionName returns the object name of a version of a dataset. The list of
+// versions, without their points, is kept under the dataset's own name.
+func versionName(dataset string, version int) string {
+	return fmt.Sprintf("%s@%d", dataset, version)
+}
+
+// RecordVersion stores the current points of a dataset as its next version.
+// Versions are never changed once recorded.
+func RecordVersion(st store.Store, dataset, extractorVersion string, rules []string, extractedAt time.Time) (DatasetVersion, error) {
+	points, err := ReadDataset(st, dataset)
+	if err != nil {
+		return DatasetVersion{}, err
+	}
+
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+
+	versions, err := listVersions(st, dataset)
+	if err != nil {
+		return DatasetVersion{}, err
+	}
+
+	v := DatasetVersion{
+		Version:          1,
+		ExtractorVersion: extractorVersion,
+		Rules:            rules,
+		ExtractedAt:      extractedAt,
+		PointCount:       len(points),
+		Points:           points,
+	}
+	if len(versions) > 0 {
+		v.Version = versions[len(versions)-1].Version + 1
+	}
+	if err := saveJSON(st, store.Versions, versionName(dataset, v.Version), v); err != nil {
+		return DatasetVersion{}, err
+	}
+
+	summary := v
+	summary.Points = nil
+	if err := saveJSON(st, store.Versions, dataset, append(versions, summary)); err != nil {
+		return DatasetVersion{}, err
+	}
+	return v, nil
+}
+
+// ListVersions returns the versions of a dataset, oldest first, without their points
+func ListVersions(st store.Store, dataset string) ([]DatasetVersion, error) {
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+	return listVersions(st, dataset)
+}
+
+// listVersions reads the version list of a dataset. Datasets versioned before
+// the list was kept get it built once from their stored versions.
+func listVersions(st store.Store, dataset string) ([]DatasetVersion, error) {
+	versions := []DatasetVersion{}
+	err := loadJSON(st, store.Versions, dataset, &versions)
+	if err == nil {
+		return versions, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+
+	objects, err := st.List(store.Versions)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objects {
+		n, ok := strings.CutPrefix(obj.Name, dataset+"@")
+		if !ok {
+			continue
+		}
+		number, err := strconv.Atoi(n)
+		if err != nil {
+			continue
+		}
+		v, err := LoadVersion(st, dataset, number)
+		if err != nil {
+			return nil, err
+		}
+		v.Points = nil
+		versions = append(versions, *v)
+	}
+	if len(versions) == 0 {
+		return versions, nil
+	}
+
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	if err := saveJSON(st, store.Versions, dataset, versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// LoadVersion reads a version of a dataset with its points
+func LoadVersion(st store.Store, dataset string, version int) (*DatasetVersion, error) {
+	var v DatasetVersion
+	if err := loadJSON(st, store.Versions, versionName(dataset, version), &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ReadDatasetVersion returns the points of a version of a dataset. Version 0
+// is the latest extraction.
+func ReadDatasetVersion(st store.Store, dataset string, version int) ([]DataPoint, error) {
+	if version == 0 {
+		return ReadDataset(st, dataset)
+	}
+	v, err := LoadVersion(st, dataset, version)
+	if err != nil {
+		return nil, err
+	}
+	return v.Points, nil
+}
+
+// deleteVersions removes every version of a dataset and its version list
+func deleteVersions(st store.Store, dataset string) error {
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+
+	versions, err := listVersions(st, dataset)
+	if err != nil {
+		return err
+	}
+	for _, v := range versions {
+		if err := st.Delete(store.Versions, versionName(dataset, v.Version)); err != nil {
+			return err
+		}
+	}
+	return st.Delete(store.Versions, dataset)
+}
+
+// Reextract runs the current extraction rules over the source document of a
+// dataset again and records the result as a new version. The previous
+// extractions stay available as earlier versions; a dataset extracted before
+// versions were kept first gets its current points recorded as version 1.
+// Manual corrections are not carried over; they stay in the audit trail.
+// Corrections made since the latest extraction are only discarded when force
+// is set, otherwise ErrHasCorrections is returned.
+func Reextract(st store.Store, dataset string, force bool) (DatasetVersion, QualityReport, error) {
+	meta, err := LoadMetadata(st, dataset)
+	if err != nil {
+		return DatasetVersion{}, QualityReport{}, err
+	}
+	if len(meta.MergedFrom) > 0 {
+		return DatasetVersion{}, QualityReport{}, ErrNotExtracted
+	}
+	source := SourceName(dataset)
+	if _, err := st.Stat(store.Documents, source); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return DatasetVersion{}, QualityReport{}, ErrSourceRemoved
+		}
+		return DatasetVersion{}, QualityReport{}, err
+	}
+
+	correctionsMu.Lock()
+	defer correctionsMu.Unlock()
+
+	versions, err := ListVersions(st, dataset)
+	if err != nil {
+		return DatasetVersion{}, QualityReport{}, err
+	}
+	if !force {
+		pending, err := pendingCorrections(st, dataset, versions)
+		if err != nil {
+			return DatasetVersion{}, QualityReport{}, err
+		}
+		if pending {
+			return DatasetVersion{}, QualityReport{}, ErrHasCorrections
+		}
+	}
+	if len(versions) == 0 {
+		if _, err := RecordVersion(st, dataset, meta.ExtractorVersion, meta.Rules, meta.UploadedAt); err != nil {
+			return DatasetVersion{}, QualityReport{}, err
+		}
+	}
+
+	report, err := ParseDocument(st, source, dataset)
+	if err != nil {
+		return DatasetVersion{}, QualityReport{}, err
+	}
+	if err := SaveQualityReport(st, dataset, report); err != nil {
+		return DatasetVersion{}, QualityReport{}, err
+	}
+
+	v, err := RecordVersion(st, dataset, ExtractorVersion, ExtractionRules(), time.Now())
+	if err != nil {
+		return DatasetVersion{}, QualityReport{}, err
+	}
+
+	units, err := GetUnitsFromDataset(st, dataset)
+	if err != nil {
+		return DatasetVersion{}, QualityReport{}, err
+	}
+	meta.ExtractorVersion = v.ExtractorVersion
+	meta.Rules = v.Rules
+	meta.Version = v.Version
+	meta.PointCount = v.PointCount
+	meta.Units = units
+	if err := SaveMetadata(st, dataset, *meta); err != nil {
+		return DatasetVersion{}, QualityReport{}, err
+	}
+
+	v.Points = nil
+	return v, report, nil
+}
@@ -0,0 +1,127 @@
+package util
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+)
+
+func TestReextractKeepsVersions(t *testing.T) {
+	st := store.NewMemory()
+	dataset := storeDataset(t, st, "report.txt", "Rainfall was 5 mm", time.Now())
+	if _, err := RecordVersion(st, dataset, "1.0.0", []string{"old"}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The source changes under the same name, as an improved rule would
+	// find more in it
+	if err := st.Put(store.Documents, "report.txt", []byte("Rainfall was 5 mm and 12 °C")); err != nil {
+		t.Fatal(err)
+	}
+	v, _, err := Reextract(st, dataset, false)
+	if err != nil {
+		t.Fatalf("Reextract() error = %v", err)
+	}
+	if v.Version != 2 || v.PointCount != 2 || v.ExtractorVersion != ExtractorVersion {
+		t.Errorf("Reextract() = %+v, want version 2 with 2 points", v)
+	}
+
+	versions, err := ListVersions(st, dataset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[0].ExtractorVersion != "1.0.0" || versions[0].Points != nil {
+		t.Errorf("ListVersions() = %+v", versions)
+	}
+
+	pinned, err := ReadDatasetVersion(st, dataset, 1)
+	if err != nil || len(pinned) != 1 {
+		t.Errorf("version 1 = %v, %v, want the single original point", pinned, err)
+	}
+	latest, err := ReadDatasetVersion(st, dataset, 0)
+	if err != nil || len(latest) != 2 {
+		t.Errorf("latest = %v, %v, want 2 points", latest, err)
+	}
+	if meta, _ := LoadMetadata(st, dataset); meta.Version != 2 || meta.PointCount != 2 {
+		t.Errorf("metadata = %+v, want version 2", meta)
+	}
+}
+
+func TestReextractRecordsLegacyExtraction(t *testing.T) {
+	st := store.NewMemory()
+	dataset := storeDataset(t, st, "report.txt", "Rainfall was 5 mm", time.Now())
+
+	v, _, err := Reextract(st, dataset, false)
+	if err != nil {
+		t.Fatalf("Reextract() error = %v", err)
+	}
+	if v.Version != 2 {
+		t.Errorf("Reextract() version = %d, want 2 after recording the original", v.Version)
+	}
+
+	if err := st.Delete(store.Documents, "report.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Reextract(st, dataset, false); err != ErrSourceRemoved {
+		t.Errorf("Reextract() without source error = %v, want ErrSourceRemoved", err)
+	}
+}
+
+func TestReextractKeepsCorrectionsUnlessForced(t *testing.T) {
+	st := store.NewMemory()
+	dataset := storeDataset(t, st, "report.txt", "Rainfall was 5 mm", time.Now())
+	value := 6.0
+	if _, err := EditPoint(st, dataset, "p1", PointEdit{Value: &value}, "ana"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := Reextract(st, dataset, false); !errors.Is(err, ErrHasCorrections) {
+		t.Fatalf("Reextract() of corrected dataset error = %v, want ErrHasCorrections", err)
+	}
+	if points, _ := ReadDataset(st, dataset); len(points) != 1 || points[0].Value != 6 {
+		t.Errorf("points = %+v, want the correction kept", points)
+	}
+
+	if _, _, err := Reextract(st, dataset, true); err != nil {
+		t.Fatalf("Reextract() with force error = %v", err)
+	}
+	if points, _ := ReadDataset(st, dataset); len(points) != 1 || points[0].Value != 5 {
+		t.Errorf("points = %+v, want the extracted value", points)
+	}
+
+	// The discarded corrections no longer stand in the way
+	if _, _, err := Reextract(st, dataset, false); err != nil {
+		t.Errorf("Reextract() after forced re-extraction error = %v", err)
+	}
+}
+
+func TestListVersionsKeepsList(t *testing.T) {
+	st := store.NewMemory()
+	dataset := storeDataset(t, st, "report.txt", "Rainfall was 5 mm", time.Now())
+	for _, extractor := range []string{"1.0.0", "1.1.0"} {
+		if _, err := RecordVersion(st, dataset, extractor, nil, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Versions recorded before the list was kept only have their own objects
+	if err := st.Delete(store.Versions, dataset); err != nil {
+		t.Fatal(err)
+	}
+	versions, err := ListVersions(st, dataset)
+	if err != nil || len(versions) != 2 || versions[1].ExtractorVersion != "1.1.0" {
+		t.Fatalf("ListVersions() = %+v, %v, want both versions", versions, err)
+	}
+	if _, err := st.Stat(store.Versions, dataset); err != nil {
+		t.Errorf("version list not rebuilt: %v", err)
+	}
+
+	if v, err := RecordVersion(st, dataset, "1.2.0", nil, time.Now()); err != nil || v.Version != 3 {
+		t.Fatalf("RecordVersion() = %+v, %v, want version 3", v, err)
+	}
+	if versions, _ := ListVersions(st, dataset); len(versions) != 3 {
+		t.Errorf("ListVersions() after recording = %+v, want 3 versions", versions)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Vinolia-E/BioTree/backend/store"
+	"github.com/Vinolia-E/BioTree/backend/util"
+)
+
+// runCommand runs a command line subcommand against st
+func runCommand(st store.Store, args []string) error {
+	switch args[0] {
+	case "backup":
+		return backupCommand(st, args[1:])
+	case "restore":
+		return restoreCommand(st, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q, use backup or restore", args[0])
+	}
+}
+
+// backupCommand writes a backup archive of the workspace to a file
+func backupCommand(st store.Store, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: biotree backup <archive.zip>")
+	}
+
+	file, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	manifest, err := util.Backup(st, file)
+	if err != nil {
+		file.Close()
+		os.Remove(args[0])
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d objects and %d rule sets to %s\n", len(manifest.Objects), len(manifest.Rules), args[0])
+	return nil
+}
+
+// restoreCommand restores a backup archive into the workspace. Rule sets that
+// differ from the ones in use are written to the -rules directory when given.
+func restoreCommand(st store.Store, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	conflict := flags.String("conflict", util.ConflictSkip, "what to do with objects that exist with other contents: skip, overwrite or fail")
+	rulesDir := flags.String("rules", "", "directory to write the archived rule sets to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: biotree restore [-conflict skip|overwrite|fail] [-rules dir] <archive.zip>")
+	}
+
+	archive, err := zip.OpenReader(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("%w: %v", util.ErrInvalidBackup, err)
+	}
+	defer archive.Close()
+
+	result, err := util.Restore(st, &archive.Reader, *conflict)
+	for _, name := range result.Conflicts {
+		fmt.Printf("conflict: %s\n", name)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored %d objects, %d unchanged, %d conflicts skipped\n", result.Restored, result.Unchanged, result.Skipped)
+
+	for _, rule := range result.Rules {
+		if *rulesDir == "" {
+			fmt.Printf("Rule set %s differs from the one in use; restore with -rules to keep it\n", rule.Name)
+			continue
+		}
+		if err := os.MkdirAll(*rulesDir, 0755); err != nil {
+			return err
+		}
+		path := filepath.Join(*rulesDir, rule.Name+".json")
+		if err := os.WriteFile(path, rule.Data, 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote rule set %s; set %s=%s to use it\n", rule.Name, rule.Env, path)
+	}
+	return nil
+}
@@ -1,19 +1,28 @@
 // Function to fetch user documents
 export async function fetchUserDocuments() {
   try {
-    const response = await fetch('/api/data-files');
-    
-    if (!response.ok) {
-      throw new Error(`HTTP error! status: ${response.status}`);
-    }
-    
-    const data = await response.json();
-    
-    if (data.status === 'error') {
-      throw new Error(data.message || 'Failed to fetch documents');
-    }
+    const documents = [];
+    let cursor = '';
+    
+    // The listing is paginated, follow the cursors to the last page
+    do {
+      const response = await fetch(`/api/data-files?cursor=${encodeURIComponent(cursor)}`);
+      
+      if (!response.ok) {
+        throw new Error(`HTTP error! status: ${response.status}`);
+      }
+      
+      const data = await response.json();
+      
+      if (data.status === 'error') {
+        throw new Error(data.message || 'Failed to fetch documents');
+      }
+      
+      documents.push(...(data.files || []));
+      cursor = data.next_cursor || '';
+    } while (cursor);
     
-    return data.files || [];
+    return documents;
   } catch (error) {
     console.error('Error fetching documents:', error);
     return [];
@@ -36,19 +45,23 @@ export function displayUserDocuments(documents) {
   
   documents.forEach(doc => {
     const fileName = doc.name;
+    const displayName = (doc.metadata && doc.metadata.display_name) || fileName;
     const fileSize = formatFileSize(doc.size);
     const modified = new Date(doc.modified).toLocaleString();
     const units = doc.units && doc.units.length > 0 
       ? `<span class="units-count">${doc.units.length} units</span>` 
       : '<span class="units-count">No units</span>';
+    const quality = doc.quality
+      ? `<span class="quality-badge ${qualityClass(doc.quality)}" title="${formatQualityTitle(doc.quality)}">${doc.quality.kept}/${doc.quality.candidates} kept • ${Math.round(doc.quality.coverage * 100)}% coverage</span>`
+      : '';
     
     html += `
       <div class="document-item">
         <div class="document-info">
-          <h4 class="document-name">${fileName}</h4>
+          <h4 class="document-name" title="${fileName}">${displayName}</h4>
           <p class="document-meta">
             ${fileSize} • ${modified}<br>
-            ${units}
+            ${units} ${quality}
           </p>
         </div>
         <div class="document-actions">
@@ -67,6 +80,23 @@ export function displayUserDocuments(documents) {
   addDocumentButtonListeners();
 }
 
+// Function to pick a badge style from an extraction quality report
+function qualityClass(quality) {
+  if (quality.candidates === 0 || quality.kept / quality.candidates < 0.2) {
+    return 'quality-poor';
+  }
+  return quality.kept / quality.candidates < 0.5 ? 'quality-fair' : 'quality-good';
+}
+
+// Function to describe the discard reasons of a quality report
+function formatQualityTitle(quality) {
+  const reasons = Object.entries(quality.discard_reasons || {})
+    .map(([reason, count]) => `${reason}: ${count}`)
+    .join(', ');
+  const duplicates = (quality.duplicates || []).length;
+  return `Discarded ${quality.discarded}${reasons ? ` (${reasons})` : ''}, ${duplicates} duplicate values`;
+}
+
 // Function to add event listeners to document buttons
 function addDocumentButtonListeners() {
   // View content buttons
@@ -178,6 +208,11 @@ function generateChartForDocument(fileName) {
           <option value="line">Line Chart</option>
           <option value="bar">Bar Chart</option>
           <option value="pie">Pie Chart</option>
+          <option value="map">Map</option>
+          <option value="tree:taxonomy">Taxonomy Tree</option>
+          <option value="tree:outline">Outline Tree</option>
+          <option value="treemap:outline">Outline Treemap</option>
+          <option value="wordcloud">Keyword Cloud</option>
         </select>
       </div>
       <button id="update-chart-btn" class="primary-button">Generate Chart</button>
@@ -213,7 +248,7 @@ function generateChartForDocument(fileName) {
 // Function to load units for a document
 async function loadUnitsForDocument(fileName) {
   try {
-    const response = await fetch('/api/data-files');
+    const response = await fetch(`/api/data-files?dataset=${encodeURIComponent(fileName)}`);
     
     if (!response.ok) {
       throw new Error(`HTTP error! status: ${response.status}`);
@@ -240,12 +275,27 @@ async function loadUnitsForDocument(fileName) {
     allOption.textContent = 'All Units';
     unitSelect.appendChild(allOption);
     
-    // Add each unit as an option
-    fileData.units.forEach(unit => {
-      const option = document.createElement('option');
-      option.value = unit;
-      option.textContent = unit;
-      unitSelect.appendChild(option);
+    // Add units grouped by category, with an option to chart the whole category
+    const groups = fileData.categories || [{ category: 'units', units: fileData.units }];
+    groups.forEach(group => {
+      const optgroup = document.createElement('optgroup');
+      optgroup.label = group.category.charAt(0).toUpperCase() + group.category.slice(1);
+
+      if (fileData.categories) {
+        const categoryOption = document.createElement('option');
+        categoryOption.value = `category:${group.category}`;
+        categoryOption.textContent = `All ${group.category} units`;
+        optgroup.appendChild(categoryOption);
+      }
+
+      group.units.forEach(unit => {
+        const option = document.createElement('option');
+        option.value = unit;
+        option.textContent = unit;
+        optgroup.appendChild(option);
+      });
+
+      unitSelect.appendChild(optgroup);
     });
     
   } catch (error) {
@@ -262,10 +312,16 @@ async function updateChart(fileName) {
   updateChartBtn.disabled = true;
   
   try {
+    const selected = document.getElementById('unit-select').value;
+    const isCategory = selected.startsWith('category:');
+    // Hierarchical chart types carry the hierarchy to draw, e.g. "tree:outline"
+    const [chartType, hierarchy = ''] = document.getElementById('chart-type').value.split(':');
     const chartRequest = {
       dataFile: fileName,
-      chartType: document.getElementById('chart-type').value,
-      unit: document.getElementById('unit-select').value,
+      chartType: chartType,
+      hierarchy: hierarchy,
+      unit: isCategory ? '' : selected,
+      category: isCategory ? selected.slice('category:'.length) : '',
       title: document.getElementById('chart-title').value,
       xLabel: document.getElementById('chart-x-label').value,
       yLabel: document.getElementById('chart-y-label').value,
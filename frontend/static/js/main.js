@@ -60,7 +60,11 @@ document.addEventListener("DOMContentLoaded", () => {
                 initUserDocuments();
                 
                 // Show success message
-                showNotification("Document processed successfully!");
+                if (data.duplicate) {
+                    showNotification("This document was already uploaded, showing the existing dataset.");
+                } else {
+                    showNotification("Document processed successfully!");
+                }
             }
             
             // Reset button state
@@ -49,6 +49,7 @@ export const process = async (form) => {
     return {
       units: data.units,
       dataFile: data.data_file || `${Date.now()}.json`, // Fallback if data_file is missing
+      duplicate: Boolean(data.duplicate),
     };
   } catch (error) {
     console.error('Network or parsing error:', error);
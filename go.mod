@@ -4,5 +4,8 @@ go 1.23.4
 
 require (
 	github.com/google/uuid v1.6.0
+	go.etcd.io/bbolt v1.3.11
 	golang.org/x/time v0.11.0
 )
+
+require golang.org/x/sys v0.4.0 // indirect
@@ -1,11 +1,17 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/Vinolia-E/BioTree/backend/handler"
 	"github.com/Vinolia-E/BioTree/backend/route"
+	"github.com/Vinolia-E/BioTree/backend/store"
+	"github.com/Vinolia-E/BioTree/backend/util"
 )
 
 func main() {
@@ -14,6 +20,41 @@ func main() {
 		port = "8080"
 	}
 
+	// Uploads and datasets live below BIOTREE_DATA_DIR, by default the
+	// working directory
+	dataDir := os.Getenv("BIOTREE_DATA_DIR")
+	if dataDir == "" {
+		dataDir = "."
+	}
+
+	st, closeStore, err := openStore(dataDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeStore()
+
+	// "biotree backup" and "biotree restore" work on the store and exit
+	if len(os.Args) > 1 {
+		if err := runCommand(st, os.Args[1:]); err != nil {
+			closeStore()
+			log.Fatal(err)
+		}
+		return
+	}
+	handler.SetStore(st)
+
+	// BIOTREE_ADMIN_TOKEN is required as a bearer token by the backup and
+	// restore endpoints, which are disabled when it is not set
+	handler.SetAdminToken(os.Getenv("BIOTREE_ADMIN_TOKEN"))
+
+	// BIOTREE_RETAIN_UPLOADS and BIOTREE_RETAIN_DATASETS set how long raw
+	// uploads and whole datasets are kept, e.g. 720h or 30d
+	policy, err := retentionPolicy()
+	if err != nil {
+		log.Fatalf("Invalid retention policy: %v", err)
+	}
+	handler.StartRetention(policy, time.Hour)
+
 	router := route.InitRoutes()
 
 	server := &http.Server{
@@ -26,3 +67,70 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// openStore opens the store named by BIOTREE_STORE. The local store keeps
+// files below dataDir; BIOTREE_STORE=bolt keeps them in an embedded database
+// instead. The returned function closes the store.
+func openStore(dataDir string) (store.Store, func(), error) {
+	switch os.Getenv("BIOTREE_STORE") {
+	case "", "local":
+		return store.NewLocal(dataDir), func() {}, nil
+	case "bolt":
+		db, err := openDatabase(dataDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open database: %w", err)
+		}
+		return db, func() { db.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown BIOTREE_STORE %q, use local or bolt", os.Getenv("BIOTREE_STORE"))
+	}
+}
+
+// retentionPolicy reads the retention policy from the environment
+func retentionPolicy() (util.RetentionPolicy, error) {
+	uploads, err := util.ParseRetentionAge(os.Getenv("BIOTREE_RETAIN_UPLOADS"))
+	if err != nil {
+		return util.RetentionPolicy{}, err
+	}
+	datasets, err := util.ParseRetentionAge(os.Getenv("BIOTREE_RETAIN_DATASETS"))
+	if err != nil {
+		return util.RetentionPolicy{}, err
+	}
+	return util.RetentionPolicy{UploadMaxAge: uploads, DatasetMaxAge: datasets}, nil
+}
+
+// openDatabase opens the embedded database named by BIOTREE_DB, by default
+// biotree.db in dataDir. A database without datasets is first filled from the
+// files/ and data/ directories below dataDir.
+func openDatabase(dataDir string) (*store.Bolt, error) {
+	path := os.Getenv("BIOTREE_DB")
+	if path == "" {
+		path = filepath.Join(dataDir, "biotree.db")
+	}
+
+	db, err := store.OpenBolt(path)
+	if err != nil {
+		return nil, err
+	}
+
+	datasets, err := db.List(store.Datasets)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if len(datasets) > 0 {
+		return db, nil
+	}
+
+	copied, err := store.Migrate(store.NewLocal(dataDir), db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrating %s: %w", dataDir, err)
+	}
+	for _, kind := range store.Kinds {
+		if copied[kind] > 0 {
+			log.Printf("Migrated %d objects from %s/%s into %s", copied[kind], dataDir, kind, path)
+		}
+	}
+	return db, nil
+}